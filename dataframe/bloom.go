@@ -0,0 +1,183 @@
+package dataframe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+const (
+	bloomBitsPerValue = 10 // About 1% false positives with bloomHashes hashes
+	bloomHashes       = 7
+	maxBloomProbes    = 4096 // Join keys beyond which probing the filters costs more than it saves
+)
+
+// WithBloomFilters builds a Bloom filter over the values of each of the given
+// columns in every chunk file. Equality filters and inner join probes on those
+// columns skip chunks whose filters rule out every value looked for, without
+// reading them. Durable frames remember the columns.
+func WithBloomFilters(columns ...string) FrameOption {
+	return func(df *DataFrame) {
+		df.bloomColumns = append([]string(nil), columns...)
+	}
+}
+
+// bloomFilter is a Bloom filter over the values of a column in a chunk.
+type bloomFilter struct {
+	bits []uint64
+	k    int
+}
+
+func newBloomFilter(n int) *bloomFilter {
+	words := (n*bloomBitsPerValue + 63) / 64
+	if words == 0 {
+		words = 1
+	}
+	return &bloomFilter{bits: make([]uint64, words), k: bloomHashes}
+}
+
+// positions derives the k bit positions of a hash by double hashing.
+func (f *bloomFilter) positions(h uint64, fn func(pos uint64) bool) bool {
+	m := uint64(len(f.bits)) * 64
+	h2 := h>>32 | h<<32 | 1
+	for i := 0; i < f.k; i++ {
+		if !fn((h + uint64(i)*h2) % m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(h uint64) {
+	f.positions(h, func(pos uint64) bool {
+		f.bits[pos/64] |= 1 << (pos % 64)
+		return true
+	})
+}
+
+// mayContain reports whether a value with hash h may have been added.
+func (f *bloomFilter) mayContain(h uint64) bool {
+	return f.positions(h, func(pos uint64) bool {
+		return f.bits[pos/64]&(1<<(pos%64)) != 0
+	})
+}
+
+// bloomHash hashes a value so that values equal under compareValues, such as
+// numbers of different types or times in different locations, hash alike.
+// It reports false for nulls, which equal nothing.
+func bloomHash(val interface{}) (uint64, bool) {
+	val, ok := statValue(val)
+	if !ok {
+		return 0, false
+	}
+
+	h := fnv.New64a()
+	var buf [9]byte
+	if f, ok := ToFloat64(val); ok {
+		if f == 0 {
+			f = 0 // Fold -0 into 0
+		}
+		buf[0] = classNumber
+		binary.LittleEndian.PutUint64(buf[1:], math.Float64bits(f))
+		h.Write(buf[:])
+		return h.Sum64(), true
+	}
+	switch v := val.(type) {
+	case string:
+		h.Write([]byte{classString})
+		h.Write([]byte(v))
+		return h.Sum64(), true
+	case time.Time:
+		buf[0] = classTime
+		binary.LittleEndian.PutUint64(buf[1:], uint64(v.UnixNano()))
+		h.Write(buf[:])
+		return h.Sum64(), true
+	}
+	return hashValue(val), true
+}
+
+// mayContainAny reports whether the Bloom filter of a column may contain any
+// of the given hashes. It is true if the column has no filter.
+func (s *ChunkStats) mayContainAny(col string, hashes []uint64) bool {
+	f, ok := s.blooms[col]
+	if !ok {
+		return true
+	}
+	for _, h := range hashes {
+		if f.mayContain(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeChunks drops the ids of chunks whose Bloom filters hold none of the
+// hashes of a key column, for every key column. The caller must hold df.mutex.
+func (df *DataFrame) probeChunks(ids []int, keys map[string][]uint64) []int {
+	if len(keys) == 0 {
+		return ids
+	}
+	return df.skipChunks(ids, func(stats *ChunkStats) bool {
+		for col, hashes := range keys {
+			if !stats.mayContainAny(col, hashes) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// appendBloomFilters appends the Bloom filters of a chunk to its encoded statistics.
+func appendBloomFilters(buf []byte, blooms map[string]*bloomFilter, columns []string) []byte {
+	var names []string
+	for _, col := range columns {
+		if _, ok := blooms[col]; ok {
+			names = append(names, col)
+		}
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, col := range names {
+		f := blooms[col]
+		buf = appendBinaryString(buf, col)
+		buf = append(buf, byte(f.k))
+		buf = binary.AppendUvarint(buf, uint64(len(f.bits)))
+		for _, word := range f.bits {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+	}
+	return buf
+}
+
+// readBloomFilters reads the Bloom filters written by appendBloomFilters.
+func readBloomFilters(r byteReader, size int) (map[string]*bloomFilter, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	blooms := make(map[string]*bloomFilter, n)
+	for i := uint64(0); i < n; i++ {
+		col, err := readBinaryString(r)
+		if err != nil {
+			return nil, err
+		}
+		k, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		words, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if k == 0 || words == 0 || words > uint64(size)/8 {
+			return nil, fmt.Errorf("invalid Bloom filter for column %s", col)
+		}
+		f := &bloomFilter{bits: make([]uint64, words), k: int(k)}
+		if err := binary.Read(r, binary.LittleEndian, f.bits); err != nil {
+			return nil, err
+		}
+		blooms[col] = f
+	}
+	return blooms, nil
+}
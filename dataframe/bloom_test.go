@@ -0,0 +1,49 @@
+package dataframe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBloomChunkSkipping(t *testing.T) {
+	df := statItems(t, 4000, WithBloomFilters("Name", "Price", "At"))
+	if err := df.UpdateRow(3999, map[string]interface{}{"Price": 5.0}); err != nil {
+		t.Fatal(err) // Left unflushed, so chunk 3 is never skipped
+	}
+
+	hour := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(1500 * time.Hour)
+	tests := []struct {
+		name   string
+		pred   Expr
+		chunks []int
+	}{
+		{"string", Col("Name").Eq("item1234"), []int{1, 3}},
+		{"absent inside range", Col("Price").Eq(617.25), []int{3}},
+		{"integer against floats", Col("Price").Eq(617), []int{1, 3}},
+		{"negative zero", Col("Price").Eq(math.Copysign(0, -1)), []int{0, 3}},
+		{"time in another zone", Col("At").Eq(hour.In(time.FixedZone("X", 3600))), []int{1, 3}},
+		{"in list", Col("Name").In("item5", "item2500", "none"), []int{0, 2, 3}},
+		{"disjunction", Col("Name").Eq("item10").Or(Col("Name").Eq("item2100")), []int{0, 2, 3}},
+		{"null", Col("Name").Eq(nil), []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkPruned(t, df, tt.pred, tt.chunks)
+		})
+	}
+
+	hashes := func(values ...interface{}) []uint64 {
+		var out []uint64
+		for _, val := range values {
+			h, _ := bloomHash(val)
+			out = append(out, h)
+		}
+		return out
+	}
+	probe := map[string][]uint64{"Name": hashes("item5", "item2999"), "Price": hashes(2.5, 1499.5, 7)}
+	if got := keptChunks(df.probeChunks(df.rowIDs(), probe)); !reflect.DeepEqual(got, []int{0, 2, 3}) {
+		t.Errorf("join probe kept chunks %v, want [0 2 3]", got)
+	}
+}
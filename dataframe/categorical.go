@@ -0,0 +1,401 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// categoriesFile holds the dictionaries of the categorical columns in the store.
+const categoriesFile = "categories"
+
+// WithCategorical dictionary-encodes the given string columns. Each distinct
+// value is kept once per frame, shared by every row holding it, and chunk files
+// store the values as integer codes into that dictionary. Filters
+// on values absent from a column skip every chunk, and the values group and
+// join as plain strings. It suits low-cardinality columns such as cities or
+// categories. Durable frames remember the columns.
+func WithCategorical(columns ...string) FrameOption {
+	return func(df *DataFrame) {
+		for _, col := range columns {
+			if df.categories[col] == nil {
+				df.categories[col] = newCategoryDict()
+			}
+		}
+	}
+}
+
+// categoryDict holds the distinct values of a categorical column.
+type categoryDict struct {
+	mutex  sync.RWMutex // Ingest workers intern values concurrently
+	codes  map[string]int
+	values []string
+	other  bool // The column held values other than strings, which are not interned
+}
+
+func newCategoryDict() *categoryDict {
+	return &categoryDict{codes: make(map[string]int)}
+}
+
+// clone returns a copy of the dictionary giving every value the same code, so
+// that chunk files coded with the dictionary can be read with the copy.
+func (d *categoryDict) clone() *categoryDict {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	c := &categoryDict{codes: make(map[string]int, len(d.codes)), values: append([]string(nil), d.values...), other: d.other}
+	for s, code := range d.codes {
+		c.codes[s] = code
+	}
+	return c
+}
+
+// intern returns the copy of s held by the dictionary, adding it if it is new.
+func (d *categoryDict) intern(s string) string {
+	d.mutex.RLock()
+	if code, ok := d.codes[s]; ok {
+		interned := d.values[code] // Read before unlocking, as appends may move values
+		d.mutex.RUnlock()
+		return interned
+	}
+	d.mutex.RUnlock()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if code, ok := d.codes[s]; ok {
+		return d.values[code]
+	}
+	s = strings.Clone(s) // Do not keep alive a larger string s may point into
+	d.codes[s] = len(d.values)
+	d.values = append(d.values, s)
+	return s
+}
+
+// mayContain reports whether a row of the column may hold s.
+func (d *categoryDict) mayContain(s string) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	_, ok := d.codes[s]
+	return ok || d.other
+}
+
+// Categories returns the distinct values of a categorical column in ascending
+// order. Values whose rows were all deleted or updated may still be listed.
+func (df *DataFrame) Categories(column string) ([]string, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	dict, ok := df.categories[column]
+	if !ok {
+		return nil, fmt.Errorf("column %s is not categorical", column)
+	}
+	dict.mutex.RLock()
+	values := append([]string(nil), dict.values...)
+	dict.mutex.RUnlock()
+	sort.Strings(values)
+	return values, nil
+}
+
+// categoricalColumns returns the names of the categorical columns in order.
+func (df *DataFrame) categoricalColumns() []string {
+	columns := make([]string, 0, len(df.categories))
+	for col := range df.categories {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// internCategories replaces the strings of the categorical columns of a row by
+// the copies held in their dictionaries, adding new values.
+func (df *DataFrame) internCategories(row interface{}) {
+	if len(df.categories) == 0 {
+		return
+	}
+	values, ok := row.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for col, dict := range df.categories {
+		switch v := values[col].(type) {
+		case nil:
+		case string:
+			values[col] = dict.intern(v)
+		default:
+			dict.mutex.Lock()
+			dict.other = true
+			dict.mutex.Unlock()
+		}
+	}
+}
+
+// pruneCategories drops every id if one of preds compares a categorical column
+// with strings that none of its rows hold.
+func (df *DataFrame) pruneCategories(ids []int, preds []Expr) []int {
+	for _, pred := range preds {
+		if !df.categoriesMayMatch(pred) {
+			return nil
+		}
+	}
+	return ids
+}
+
+// categoriesMayMatch reports whether a row might satisfy pred as far as the
+// dictionaries of the categorical columns tell. It is conservative.
+func (df *DataFrame) categoriesMayMatch(pred Expr) bool {
+	switch pred.op {
+	case "and":
+		for _, arg := range pred.args {
+			if !df.categoriesMayMatch(arg) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, arg := range pred.args {
+			if df.categoriesMayMatch(arg) {
+				return true
+			}
+		}
+		return false
+	case "==":
+		left, right := pred.args[0], pred.args[1]
+		if left.op == "lit" {
+			left, right = right, left
+		}
+		return df.categoryMayContain(left, right)
+	case "in":
+		for _, arg := range pred.args[1:] {
+			if df.categoryMayContain(pred.args[0], arg) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// categoryMayContain reports whether a column expression might equal a literal.
+func (df *DataFrame) categoryMayContain(col, lit Expr) bool {
+	if col.op != "col" || lit.op != "lit" {
+		return true
+	}
+	dict, ok := df.categories[col.name]
+	if !ok {
+		return true
+	}
+	s, ok := lit.value.(string)
+	return !ok || dict.mayContain(s)
+}
+
+// encodeCategoryCodes replaces the values of the categorical columns of a
+// chunk by their codes in the frame dictionaries, and returns the names of the
+// columns encoded. Columns holding anything but strings and nulls in the chunk
+// are left as they are. The chunk itself is not modified, as its rows are
+// shared with readers.
+func encodeCategoryCodes(chunk map[int]interface{}, categories map[string]*categoryDict) (map[int]interface{}, []string) {
+	for _, dict := range categories {
+		dict.mutex.RLock()
+		defer dict.mutex.RUnlock()
+	}
+
+	var coded []string
+columns:
+	for col, dict := range categories {
+		for _, row := range chunk {
+			values, ok := row.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch v := values[col].(type) {
+			case nil:
+			case string:
+				if _, ok := dict.codes[v]; !ok {
+					continue columns
+				}
+			default:
+				continue columns
+			}
+		}
+		coded = append(coded, col)
+	}
+	if len(coded) == 0 {
+		return chunk, nil
+	}
+	sort.Strings(coded)
+
+	encoded := make(map[int]interface{}, len(chunk))
+	for id, row := range chunk {
+		values, ok := row.(map[string]interface{})
+		if !ok {
+			encoded[id] = row
+			continue
+		}
+		copied := make(map[string]interface{}, len(values))
+		for col, val := range values {
+			copied[col] = val
+		}
+		for _, col := range coded {
+			if s, ok := values[col].(string); ok {
+				copied[col] = categories[col].codes[s]
+			}
+		}
+		encoded[id] = copied
+	}
+	return encoded, coded
+}
+
+// decodeCategoryCodes replaces the codes of the given columns of a decoded row
+// by their values in the frame dictionaries, which all rows holding a value share.
+func decodeCategoryCodes(row interface{}, coded []string, categories map[string]*categoryDict) error {
+	values, ok := row.(map[string]interface{})
+	if !ok || len(coded) == 0 {
+		return nil
+	}
+	for _, col := range coded {
+		val, present := values[col]
+		if !present || val == nil {
+			continue
+		}
+		dict, ok := categories[col]
+		if !ok {
+			return fmt.Errorf("no dictionary for categorical column %s", col)
+		}
+		code, ok := codeValue(val)
+		if !ok {
+			return fmt.Errorf("invalid code %v in categorical column %s", val, col)
+		}
+		dict.mutex.RLock()
+		if code >= len(dict.values) {
+			dict.mutex.RUnlock()
+			return fmt.Errorf("invalid code %d in categorical column %s", code, col)
+		}
+		values[col] = dict.values[code]
+		dict.mutex.RUnlock()
+	}
+	return nil
+}
+
+// codeValue returns the code held by a value of any integer type, as codecs
+// may decode integers to a different type than they were encoded from.
+func codeValue(val interface{}) (int, bool) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= 0 {
+			return int(v.Int()), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() <= uint64(^uint(0)>>1) {
+			return int(v.Uint()), true
+		}
+	}
+	return 0, false
+}
+
+// appendCodedColumns appends the names of the columns a chunk stores as codes.
+func appendCodedColumns(buf []byte, coded []string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(coded)))
+	for _, col := range coded {
+		buf = appendBinaryString(buf, col)
+	}
+	return buf
+}
+
+// readCodedColumns reads the names written by appendCodedColumns.
+func readCodedColumns(r byteReader, size int) ([]string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(size) {
+		return nil, fmt.Errorf("chunk header is truncated")
+	}
+	var coded []string
+	for i := uint64(0); i < n; i++ {
+		col, err := readBinaryString(r)
+		if err != nil {
+			return nil, fmt.Errorf("chunk header is truncated")
+		}
+		coded = append(coded, col)
+	}
+	return coded, nil
+}
+
+// saveCategories puts the frame dictionaries in the store if they grew since
+// they were last saved. The dictionaries only grow, so chunk files written
+// before stay readable; they are saved before the chunk files using new codes.
+// The caller must hold df.flushMutex.
+func (df *DataFrame) saveCategories() error {
+	if len(df.categories) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	sizes := make(map[string]int, len(df.categories))
+	changed := false
+	columns := df.categoricalColumns()
+	buf.Write(binary.AppendUvarint(nil, uint64(len(columns))))
+	for _, col := range columns {
+		dict := df.categories[col]
+		dict.mutex.RLock()
+		sizes[col] = len(dict.values)
+		b := appendBinaryString(nil, col)
+		b = binary.AppendUvarint(b, uint64(len(dict.values)))
+		for _, s := range dict.values {
+			b = appendBinaryString(b, s)
+		}
+		dict.mutex.RUnlock()
+		buf.Write(b)
+		if sizes[col] != df.savedCategories[col] {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := df.store.Put(categoriesFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing categories: %v", err)
+	}
+	df.savedCategories = sizes
+	return nil
+}
+
+// loadCategories reads the frame dictionaries saved by saveCategories, if any.
+func (df *DataFrame) loadCategories() error {
+	data, err := df.store.Get(categoriesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading categories: %v", err)
+	}
+	r := bytes.NewReader(data)
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(len(data)) {
+		return fmt.Errorf("invalid categories file")
+	}
+	df.savedCategories = make(map[string]int, n)
+	for i := uint64(0); i < n; i++ {
+		col, err := readBinaryString(r)
+		if err != nil {
+			return fmt.Errorf("invalid categories file")
+		}
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size > uint64(len(data)) {
+			return fmt.Errorf("invalid categories file")
+		}
+		dict := newCategoryDict()
+		for code := 0; code < int(size); code++ {
+			s, err := readBinaryString(r)
+			if err != nil {
+				return fmt.Errorf("invalid categories file")
+			}
+			dict.codes[s] = code
+			dict.values = append(dict.values, s)
+		}
+		df.categories[col] = dict
+		df.savedCategories[col] = len(dict.values)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package dataframe
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestCategoricalRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []FrameOption
+	}{
+		{"indexed", nil},
+		{"binary zstd", []FrameOption{WithChunkCodec(BinaryCodec{}, Zstd)}},
+		{"msgpack snappy", []FrameOption{WithChunkCodec(MsgpackCodec{}, Snappy)}},
+		{"gob", []FrameOption{WithChunkCodec(GobCodec{}, NoCompression)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "items")
+			df, want := durableItems(t, dir, 2500, append(tt.opts, WithCategorical("Name"))...)
+			df.InsertRow(2600, durableItem{"mug", 1}) // A value new to the saved dictionary
+			want[2600] = map[string]interface{}{"Name": "mug", "Qty": 1}
+			df.InsertRow(2700, map[string]interface{}{"Name": 5, "Qty": 2}) // Leaves chunk 2 uncoded
+			want[2700] = map[string]interface{}{"Name": 5, "Qty": 2}
+			df.Close()
+
+			df, err := OpenDataFrame(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer df.Close()
+			checkItems(t, df, want)
+			if values, err := df.Categories("Name"); err != nil || !reflect.DeepEqual(values, []string{"cap", "ink", "mug", "pen"}) {
+				t.Errorf("Categories = %v, %v, want [cap ink mug pen]", values, err)
+			}
+			if _, err := df.Categories("Qty"); err == nil {
+				t.Error("Categories of a column that is not categorical succeeded")
+			}
+
+			for chunkID, want := range map[int][]string{0: {"Name"}, 2: nil} {
+				data, err := df.chunkBytes(chunkID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				header, _, err := parseChunkHeader(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(header.coded, want) {
+					t.Errorf("chunk %d stores %v as codes, want %v", chunkID, header.coded, want)
+				}
+			}
+
+			// Rows holding a value share the dictionary's copy of it
+			first, err := df.ReadRow(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := df.ReadRow(1500)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, b := toRowMap(first)["Name"].(string), toRowMap(second)["Name"].(string)
+			if a != "pen" || b != "pen" || unsafe.StringData(a) != unsafe.StringData(b) {
+				t.Errorf("rows 0 and 1500 hold %q and %q in separate copies", a, b)
+			}
+
+			for value, n := range map[interface{}]int{"mug": 1, "cup": 0, 5: 1} {
+				out, err := df.Lazy().Filter(Col("Name").Eq(value)).Collect()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := len(out.rowIDs()); got != n {
+					t.Errorf("filter on %v kept %d rows, want %d", value, got, n)
+				}
+				out.Close()
+			}
+		})
+	}
+}
+
+func TestCategoricalPruning(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "items")
+	df, _ := durableItems(t, dir, 2500, WithCategorical("Name"))
+	df.Close()
+	df, err := OpenDataFrame(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	tests := []struct {
+		pred Expr
+		kept bool
+	}{
+		{Col("Name").Eq("cup"), false},
+		{Lit("cup").Eq(Col("Name")), false},
+		{Col("Name").In("cup", "bowl"), false},
+		{Col("Name").Eq("cup").Or(Col("Name").Eq("pen")), true},
+		{Col("Name").Eq("pen").And(Col("Name").Eq("cup")), false},
+		{Col("Name").Ne("cup"), true},
+		{Col("Name").Eq(5), true},
+		{Col("Qty").Eq("cup"), true},
+	}
+	ids := df.rowIDs()
+	for _, tt := range tests {
+		if kept := df.pruneCategories(ids, []Expr{tt.pred}) != nil; kept != tt.kept {
+			t.Errorf("%v kept rows = %v, want %v", tt.pred, kept, tt.kept)
+		}
+	}
+
+	df.InsertRow(2600, map[string]interface{}{"Name": 5, "Qty": 1})
+	if df.pruneCategories(ids, []Expr{Col("Name").Eq("cup")}) == nil {
+		t.Error("filter on a column holding values other than strings skipped every row")
+	}
+}
@@ -0,0 +1,444 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// ChunkCodec encodes the rows of a chunk file. Rows are keyed by id and are
+// either column maps or plain values.
+type ChunkCodec interface {
+	// Name identifies the codec in chunk headers. It must be unique and at most 255 bytes.
+	Name() string
+	Encode(w io.Writer, chunk map[int]interface{}) error
+	Decode(r io.Reader) (map[int]interface{}, error)
+}
+
+// Compression is the compression applied to a chunk file after encoding.
+type Compression uint8
+
+const (
+	NoCompression Compression = iota
+	Zstd
+	Snappy
+	LZ4
+)
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Zstd:
+		return "zstd"
+	case Snappy:
+		return "snappy"
+	case LZ4:
+		return "lz4"
+	}
+	return fmt.Sprintf("Compression(%d)", uint8(c))
+}
+
+// Chunk files start with a header naming the codec and compression of the rest
+// of the file. Version 2 headers also hold the statistics of the chunk,
+// version 3 headers the columns it stores as category codes, and version 4
+// headers an offset added to the ids of the rows, so that a chunk file can be
+// moved to other ids by rewriting only its header. Files without a header are
+// plain gob, as written before codecs existed.
+const (
+	chunkMagic   = "BJCK"
+	chunkVersion = 4
+)
+
+var (
+	chunkCodecsMutex sync.RWMutex
+	chunkCodecs      = make(map[string]ChunkCodec)
+)
+
+func init() {
+	RegisterChunkCodec(GobCodec{})
+	RegisterChunkCodec(BinaryCodec{})
+	RegisterChunkCodec(MsgpackCodec{})
+	RegisterChunkCodec(IndexedCodec{})
+
+	// Durations result from subtracting times, and are read back as such in
+	// processes that have not written any
+	registerNamedType(reflect.TypeOf(time.Duration(0)))
+}
+
+// RegisterChunkCodec makes a codec available for reading chunk files whose
+// header names it. The built-in codecs are registered already.
+func RegisterChunkCodec(codec ChunkCodec) {
+	chunkCodecsMutex.Lock()
+	defer chunkCodecsMutex.Unlock()
+	chunkCodecs[codec.Name()] = codec
+}
+
+// lookupChunkCodec returns the registered codec with the given name.
+func lookupChunkCodec(name string) (ChunkCodec, error) {
+	chunkCodecsMutex.RLock()
+	defer chunkCodecsMutex.RUnlock()
+	codec, ok := chunkCodecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown chunk codec %q", name)
+	}
+	return codec, nil
+}
+
+// FrameOption configures a DataFrame when it is created or opened.
+type FrameOption func(*DataFrame)
+
+// WithChunkCodec sets the codec and compression of the chunk files the DataFrame
+// writes. Files written with other codecs stay readable. The default is
+// IndexedCodec without compression, whose rows are read one at a time from
+// memory-mapped chunk files.
+func WithChunkCodec(codec ChunkCodec, compression Compression) FrameOption {
+	return func(df *DataFrame) {
+		df.codec = codec
+		df.compression = compression
+	}
+}
+
+// encodeChunk writes a chunk header with the encoded statistics of the chunk
+// and the names of its columns stored as category codes, followed by the chunk
+// encoded with codec.
+func encodeChunk(w io.Writer, codec ChunkCodec, compression Compression, chunk map[int]interface{}, stats []byte, coded []string) error {
+	name := codec.Name()
+	if len(name) > 255 {
+		return fmt.Errorf("chunk codec name %q is too long", name)
+	}
+	header := append([]byte(chunkMagic), chunkVersion, byte(compression), byte(len(name)))
+	header = append(header, name...)
+	header = appendBinaryBytes(header, stats)
+	header = appendCodedColumns(header, coded)
+	header = binary.AppendVarint(header, 0)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	var body io.WriteCloser
+	switch compression {
+	case NoCompression:
+		return codec.Encode(w, chunk)
+	case Zstd:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		body = encoder
+	case Snappy:
+		body = snappy.NewBufferedWriter(w)
+	case LZ4:
+		body = lz4.NewWriter(w)
+	default:
+		return fmt.Errorf("unsupported compression %v", compression)
+	}
+	if err := codec.Encode(body, chunk); err != nil {
+		body.Close()
+		return err
+	}
+	return body.Close()
+}
+
+// chunkHeader is the parsed header of a chunk file.
+type chunkHeader struct {
+	codec       ChunkCodec
+	compression Compression
+	stats       []byte   // Encoded statistics, nil before version 2
+	coded       []string // Columns stored as category codes, nil before version 3
+	idOffset    int      // Added to the ids of the encoded rows, 0 before version 4
+	size        int      // Offset of the encoded chunk
+}
+
+// parseChunkHeader parses the header of a chunk file. ok is false for plain gob files.
+func parseChunkHeader(data []byte) (header chunkHeader, ok bool, err error) {
+	fixed := len(chunkMagic) + 3
+	if len(data) < fixed || string(data[:len(chunkMagic)]) != chunkMagic {
+		return header, false, nil
+	}
+	version := data[4]
+	if version < 1 || version > chunkVersion {
+		return header, false, fmt.Errorf("unsupported chunk version %d", version)
+	}
+	header.compression = Compression(data[5])
+	header.size = fixed + int(data[6])
+	if header.size > len(data) {
+		return header, false, fmt.Errorf("chunk header is truncated")
+	}
+	if header.codec, err = lookupChunkCodec(string(data[fixed:header.size])); err != nil {
+		return header, false, err
+	}
+
+	if version >= 2 {
+		r := bytes.NewReader(data[header.size:])
+		if header.stats, err = readBinaryBytes(r); err != nil {
+			return header, false, fmt.Errorf("chunk header is truncated")
+		}
+		if len(header.stats) == 0 {
+			header.stats = nil
+		}
+		if version >= 3 {
+			if header.coded, err = readCodedColumns(r, len(data)); err != nil {
+				return header, false, err
+			}
+		}
+		if version >= 4 {
+			offset, err := binary.ReadVarint(r)
+			if err != nil {
+				return header, false, fmt.Errorf("chunk header is truncated")
+			}
+			header.idOffset = int(offset)
+		}
+		header.size = len(data) - r.Len()
+	}
+	return header, true, nil
+}
+
+// decodeChunk decodes a chunk file written by encodeChunk, or a plain gob chunk,
+// looking up category codes in the given frame dictionaries.
+func decodeChunk(data []byte, categories map[string]*categoryDict) (map[int]interface{}, error) {
+	header, ok, err := parseChunkHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return GobCodec{}.Decode(bytes.NewReader(data))
+	}
+	chunk, err := decodeChunkBody(header, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range chunk {
+		if err := decodeCategoryCodes(row, header.coded, categories); err != nil {
+			return nil, err
+		}
+	}
+	if header.idOffset != 0 {
+		moved := make(map[int]interface{}, len(chunk))
+		for id, row := range chunk {
+			moved[id+header.idOffset] = row
+		}
+		chunk = moved
+	}
+	return chunk, nil
+}
+
+// moveChunk returns a chunk file with the ids of its rows moved by delta. Only
+// the header is rewritten. ok is false for plain gob files, which have none.
+func moveChunk(data []byte, delta int) (moved []byte, ok bool, err error) {
+	header, ok, err := parseChunkHeader(data)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	name := header.codec.Name()
+	moved = append([]byte(chunkMagic), chunkVersion, byte(header.compression), byte(len(name)))
+	moved = append(moved, name...)
+	moved = appendBinaryBytes(moved, header.stats)
+	moved = appendCodedColumns(moved, header.coded)
+	moved = binary.AppendVarint(moved, int64(header.idOffset+delta))
+	return append(moved, data[header.size:]...), true, nil
+}
+
+// decodeChunkBody decodes the rows following a chunk header.
+func decodeChunkBody(header chunkHeader, data []byte) (map[int]interface{}, error) {
+	body := bytes.NewReader(data[header.size:])
+	switch header.compression {
+	case NoCompression:
+		return header.codec.Decode(body)
+	case Zstd:
+		decoder, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return header.codec.Decode(decoder)
+	case Snappy:
+		return header.codec.Decode(snappy.NewReader(body))
+	case LZ4:
+		return header.codec.Decode(lz4.NewReader(body))
+	}
+	return nil, fmt.Errorf("unsupported compression %v", header.compression)
+}
+
+// decodeChunkRow decodes the row with the given id from an encoded chunk. Only
+// that row is decoded if the chunk is uncompressed and its codec is a RowDecoder;
+// otherwise the whole chunk is. ok is false if the chunk has no such row.
+func decodeChunkRow(data []byte, id int, categories map[string]*categoryDict) (row interface{}, ok bool, err error) {
+	header, hasHeader, err := parseChunkHeader(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if decoder, isRowDecoder := header.codec.(RowDecoder); hasHeader && isRowDecoder && header.compression == NoCompression {
+		row, ok, err := decoder.DecodeRow(data[header.size:], id-header.idOffset)
+		if err == nil && ok {
+			err = decodeCategoryCodes(row, header.coded, categories)
+		}
+		return row, ok, err
+	}
+
+	chunk, err := decodeChunk(data, categories)
+	if err != nil {
+		return nil, false, err
+	}
+	row, ok = chunk[id]
+	return row, ok, nil
+}
+
+// GobCodec encodes chunks with encoding/gob. Concrete types stored in rows
+// other than basic types and time.Time must be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Name() string { return "gob" }
+
+func (GobCodec) Encode(w io.Writer, chunk map[int]interface{}) error {
+	return gob.NewEncoder(w).Encode(chunk)
+}
+
+func (GobCodec) Decode(r io.Reader) (map[int]interface{}, error) {
+	var chunk map[int]interface{}
+	if err := gob.NewDecoder(r).Decode(&chunk); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// chunkColumns returns the sorted column names used by the map rows of a chunk.
+func chunkColumns(chunk map[int]interface{}) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range chunk {
+		values, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for col := range values {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// sortedIDs returns the ids of a chunk in ascending order, so that encodings are deterministic.
+func sortedIDs(chunk map[int]interface{}) []int {
+	ids := make([]int, 0, len(chunk))
+	for id := range chunk {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// basicTypes are the unnamed types of the kinds codecs store natively.
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// namedTypes maps the names of named types of basic kinds, such as
+// time.Duration or a type Celsius float64, to the types. Codecs store values
+// of such types as their basic kind with the name of the type, and decode them
+// back to the type if it is here, or to the basic kind otherwise. Types are
+// added as values of them are encoded and as structs with fields of them are
+// read.
+var namedTypes sync.Map
+
+// basicType returns the unnamed type of the kind of t if t is a named type
+// of a basic kind or of []byte, and nil otherwise.
+func basicType(t reflect.Type) reflect.Type {
+	basic := basicTypes[t.Kind()]
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		basic = reflect.TypeOf([]byte(nil))
+	}
+	if basic == nil || t == basic {
+		return nil
+	}
+	return basic
+}
+
+// typeName names a type by its package path and name, as gob.Register does.
+func typeName(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
+
+// registerNamedType adds a named type of a basic kind to namedTypes, and
+// registers it with gob so that rows holding it can be logged.
+func registerNamedType(t reflect.Type) {
+	if _, loaded := namedTypes.LoadOrStore(typeName(t), t); loaded {
+		return
+	}
+	defer func() {
+		recover() // Registered with gob under another name, which serves as well
+	}()
+	gob.Register(reflect.Zero(t).Interface())
+}
+
+// registerValueType registers the type of val, or of what it points to, if it
+// is a named type of a basic kind.
+func registerValueType(val interface{}) {
+	switch val.(type) {
+	case nil, bool, int, int64, float64, string:
+		return
+	}
+	t := reflect.TypeOf(val)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if basicType(t) != nil {
+		registerNamedType(t)
+	}
+}
+
+// namedBasic returns a value of a named type of a basic kind as that kind,
+// with the name of its type. ok is false for values of other types.
+func namedBasic(val interface{}) (basic interface{}, name string, ok bool) {
+	v := reflect.ValueOf(val)
+	if !v.IsValid() {
+		return nil, "", false
+	}
+	t := basicType(v.Type())
+	if t == nil {
+		return nil, "", false
+	}
+	registerNamedType(v.Type())
+	return v.Convert(t).Interface(), typeName(v.Type()), true
+}
+
+// namedValue converts a value decoded as its basic kind back to the named
+// type it was encoded from, if that type is known.
+func namedValue(name string, basic interface{}) interface{} {
+	t, ok := namedTypes.Load(name)
+	if !ok {
+		return basic
+	}
+	v := reflect.ValueOf(basic)
+	if !v.IsValid() || !v.Type().ConvertibleTo(t.(reflect.Type)) {
+		return basic
+	}
+	return v.Convert(t.(reflect.Type)).Interface()
+}
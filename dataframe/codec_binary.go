@@ -0,0 +1,355 @@
+package dataframe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Type tags of values in the binary codec.
+const (
+	tagAbsent byte = iota // Column missing from a map row
+	tagNil
+	tagFalse
+	tagTrue
+	tagInt
+	tagInt8
+	tagInt16
+	tagInt32
+	tagInt64
+	tagUint
+	tagUint8
+	tagUint16
+	tagUint32
+	tagUint64
+	tagFloat32
+	tagFloat64
+	tagString
+	tagBytes
+	tagTime
+	tagGob   // Any other type, gob encoded
+	tagNamed // Named type of a basic kind: type name and the value as that kind
+)
+
+// Row kinds in the binary codec.
+const (
+	rowMap   byte = iota // Column map, one tagged value per chunk column
+	rowValue             // Plain value
+)
+
+// byteReader is what values are decoded from: a bufio.Reader over a stream or a
+// bytes.Reader over a chunk in memory.
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// BinaryCodec is a compact typed binary encoding. Column names are written once
+// per chunk, and every value is a type tag followed by a varint or fixed-width
+// encoding, so Go types survive a round trip without gob's per-type overhead.
+type BinaryCodec struct{}
+
+func (BinaryCodec) Name() string { return "binary" }
+
+func (BinaryCodec) Encode(w io.Writer, chunk map[int]interface{}) error {
+	bw := bufio.NewWriter(w)
+	columns := chunkColumns(chunk)
+
+	buf := binary.AppendUvarint(nil, uint64(len(columns)))
+	for _, col := range columns {
+		buf = appendBinaryString(buf, col)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(chunk)))
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+
+	for _, id := range sortedIDs(chunk) {
+		buf = binary.AppendVarint(buf[:0], int64(id))
+		var err error
+		if buf, err = appendBinaryRow(buf, columns, chunk[id]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (BinaryCodec) Decode(r io.Reader) (map[int]interface{}, error) {
+	br := bufio.NewReader(r)
+	numColumns, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, numColumns)
+	for i := range columns {
+		if columns[i], err = readBinaryString(br); err != nil {
+			return nil, err
+		}
+	}
+	numRows, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := make(map[int]interface{}, numRows)
+	for i := uint64(0); i < numRows; i++ {
+		id, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if chunk[int(id)], err = readBinaryRow(br, columns); err != nil {
+			return nil, err
+		}
+	}
+	return chunk, nil
+}
+
+// appendBinaryRow appends the kind and values of a row. Map rows hold one
+// tagged value per chunk column.
+func appendBinaryRow(buf []byte, columns []string, row interface{}) ([]byte, error) {
+	values, ok := row.(map[string]interface{})
+	if !ok {
+		return appendBinaryValue(append(buf, rowValue), row)
+	}
+
+	buf = append(buf, rowMap)
+	for _, col := range columns {
+		val, ok := values[col]
+		if !ok {
+			buf = append(buf, tagAbsent)
+			continue
+		}
+		var err error
+		if buf, err = appendBinaryValue(buf, val); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// readBinaryRow reads a row written by appendBinaryRow.
+func readBinaryRow(br byteReader, columns []string) (interface{}, error) {
+	kind, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case rowMap:
+		values := make(map[string]interface{}, len(columns))
+		for _, col := range columns {
+			val, present, err := readBinaryValue(br)
+			if err != nil {
+				return nil, err
+			}
+			if present {
+				values[col] = val
+			}
+		}
+		return values, nil
+	case rowValue:
+		val, _, err := readBinaryValue(br)
+		return val, err
+	}
+	return nil, fmt.Errorf("invalid row kind %d", kind)
+}
+
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendBinaryBytes(buf []byte, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// appendBinaryValue appends the tag and encoding of a value.
+func appendBinaryValue(buf []byte, val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case nil:
+		return append(buf, tagNil), nil
+	case bool:
+		if v {
+			return append(buf, tagTrue), nil
+		}
+		return append(buf, tagFalse), nil
+	case int:
+		return binary.AppendVarint(append(buf, tagInt), int64(v)), nil
+	case int8:
+		return binary.AppendVarint(append(buf, tagInt8), int64(v)), nil
+	case int16:
+		return binary.AppendVarint(append(buf, tagInt16), int64(v)), nil
+	case int32:
+		return binary.AppendVarint(append(buf, tagInt32), int64(v)), nil
+	case int64:
+		return binary.AppendVarint(append(buf, tagInt64), v), nil
+	case uint:
+		return binary.AppendUvarint(append(buf, tagUint), uint64(v)), nil
+	case uint8:
+		return binary.AppendUvarint(append(buf, tagUint8), uint64(v)), nil
+	case uint16:
+		return binary.AppendUvarint(append(buf, tagUint16), uint64(v)), nil
+	case uint32:
+		return binary.AppendUvarint(append(buf, tagUint32), uint64(v)), nil
+	case uint64:
+		return binary.AppendUvarint(append(buf, tagUint64), v), nil
+	case float32:
+		return binary.LittleEndian.AppendUint32(append(buf, tagFloat32), math.Float32bits(v)), nil
+	case float64:
+		return binary.LittleEndian.AppendUint64(append(buf, tagFloat64), math.Float64bits(v)), nil
+	case string:
+		return appendBinaryString(append(buf, tagString), v), nil
+	case []byte:
+		return appendBinaryBytes(append(buf, tagBytes), v), nil
+	case time.Time:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return appendBinaryBytes(append(buf, tagTime), data), nil
+	}
+
+	// Pointers are stored as the values they point to, as gob does
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return append(buf, tagNil), nil
+		}
+		return appendBinaryValue(buf, v.Elem().Interface())
+	}
+	if basic, name, ok := namedBasic(val); ok {
+		return appendBinaryValue(appendBinaryString(append(buf, tagNamed), name), basic)
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(&val); err != nil {
+		return nil, fmt.Errorf("error encoding %T: %v", val, err)
+	}
+	return appendBinaryBytes(append(buf, tagGob), data.Bytes()), nil
+}
+
+func readBinaryString(br byteReader) (string, error) {
+	b, err := readBinaryBytes(br)
+	return string(b), err
+}
+
+func readBinaryBytes(br byteReader) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(math.MaxInt32) {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(br, b)
+	return b, err
+}
+
+// readBinaryValue reads a tagged value. present is false for tagAbsent.
+func readBinaryValue(br byteReader) (val interface{}, present bool, err error) {
+	tag, err := br.ReadByte()
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch tag {
+	case tagAbsent:
+		return nil, false, nil
+	case tagNil:
+		return nil, true, nil
+	case tagFalse:
+		return false, true, nil
+	case tagTrue:
+		return true, true, nil
+	case tagInt, tagInt8, tagInt16, tagInt32, tagInt64:
+		v, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, false, err
+		}
+		switch tag {
+		case tagInt:
+			return int(v), true, nil
+		case tagInt8:
+			return int8(v), true, nil
+		case tagInt16:
+			return int16(v), true, nil
+		case tagInt32:
+			return int32(v), true, nil
+		}
+		return v, true, nil
+	case tagUint, tagUint8, tagUint16, tagUint32, tagUint64:
+		v, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, false, err
+		}
+		switch tag {
+		case tagUint:
+			return uint(v), true, nil
+		case tagUint8:
+			return uint8(v), true, nil
+		case tagUint16:
+			return uint16(v), true, nil
+		case tagUint32:
+			return uint32(v), true, nil
+		}
+		return v, true, nil
+	case tagFloat32:
+		var b [4]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			return nil, false, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b[:])), true, nil
+	case tagFloat64:
+		var b [8]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			return nil, false, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), true, nil
+	case tagString:
+		s, err := readBinaryString(br)
+		return s, err == nil, err
+	case tagBytes:
+		b, err := readBinaryBytes(br)
+		return b, err == nil, err
+	case tagTime:
+		b, err := readBinaryBytes(br)
+		if err != nil {
+			return nil, false, err
+		}
+		var t time.Time
+		if err := t.UnmarshalBinary(b); err != nil {
+			return nil, false, err
+		}
+		return t, true, nil
+	case tagGob:
+		b, err := readBinaryBytes(br)
+		if err != nil {
+			return nil, false, err
+		}
+		var v interface{}
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+			return nil, false, err
+		}
+		return v, true, nil
+	case tagNamed:
+		name, err := readBinaryString(br)
+		if err != nil {
+			return nil, false, err
+		}
+		v, _, err := readBinaryValue(br)
+		if err != nil {
+			return nil, false, err
+		}
+		return namedValue(name, v), true, nil
+	}
+	return nil, false, fmt.Errorf("invalid value tag %d", tag)
+}
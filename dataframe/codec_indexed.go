@@ -0,0 +1,178 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// RowDecoder is implemented by codecs that can decode a single row of an
+// uncompressed chunk without decoding the others. body is the encoded chunk
+// after its header, possibly memory-mapped; decoded values must not refer to it.
+type RowDecoder interface {
+	DecodeRow(body []byte, id int) (row interface{}, ok bool, err error)
+}
+
+// indexEntrySize is the size of an entry of the row table: an id and an offset.
+const indexEntrySize = 16
+
+// IndexedCodec stores rows like BinaryCodec but at fixed offsets. The chunk starts
+// with the row count, the column dictionary and a table of ids and row offsets
+// sorted by id, so a single row is found by binary search and decoded on its own.
+// It is the default codec.
+//
+// Layout, little-endian:
+//
+//	uint32 rows, uint32 dictionary size, dictionary,
+//	rows × (int64 id, uint64 offset from the start of the row data), row data
+type IndexedCodec struct{}
+
+func (IndexedCodec) Name() string { return "indexed" }
+
+func (IndexedCodec) Encode(w io.Writer, chunk map[int]interface{}) error {
+	columns := chunkColumns(chunk)
+	dict := binary.AppendUvarint(nil, uint64(len(columns)))
+	for _, col := range columns {
+		dict = appendBinaryString(dict, col)
+	}
+
+	ids := sortedIDs(chunk)
+	table := make([]byte, 0, len(ids)*indexEntrySize)
+	var data []byte
+	for _, id := range ids {
+		table = binary.LittleEndian.AppendUint64(table, uint64(int64(id)))
+		table = binary.LittleEndian.AppendUint64(table, uint64(len(data)))
+		var err error
+		if data, err = appendBinaryRow(data, columns, chunk[id]); err != nil {
+			return err
+		}
+	}
+
+	header := binary.LittleEndian.AppendUint32(nil, uint32(len(ids)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(dict)))
+	for _, part := range [][]byte{header, dict, table, data} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c IndexedCodec) Decode(r io.Reader) (map[int]interface{}, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := parseIndexedChunk(body)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := make(map[int]interface{}, layout.rows)
+	for i := 0; i < layout.rows; i++ {
+		row, err := layout.row(body, i)
+		if err != nil {
+			return nil, err
+		}
+		chunk[layout.id(body, i)] = row
+	}
+	return chunk, nil
+}
+
+// DecodeRow finds a row in the row table by binary search and decodes only that row.
+func (IndexedCodec) DecodeRow(body []byte, id int) (interface{}, bool, error) {
+	layout, err := parseIndexedChunk(body)
+	if err != nil {
+		return nil, false, err
+	}
+	i := sort.Search(layout.rows, func(i int) bool { return layout.id(body, i) >= id })
+	if i == layout.rows || layout.id(body, i) != id {
+		return nil, false, nil
+	}
+	row, err := layout.row(body, i)
+	return row, err == nil, err
+}
+
+// indexedLayout locates the parts of an indexed chunk.
+type indexedLayout struct {
+	rows    int
+	columns []string
+	table   int // Offset of the row table
+	data    int // Offset of the row data
+}
+
+func parseIndexedChunk(body []byte) (indexedLayout, error) {
+	var layout indexedLayout
+	if len(body) < 8 {
+		return layout, fmt.Errorf("indexed chunk is truncated")
+	}
+	layout.rows = int(binary.LittleEndian.Uint32(body[0:]))
+	dictSize := int(binary.LittleEndian.Uint32(body[4:]))
+	layout.table = 8 + dictSize
+	layout.data = layout.table + layout.rows*indexEntrySize
+	if layout.data > len(body) {
+		return layout, fmt.Errorf("indexed chunk is truncated")
+	}
+
+	var err error
+	layout.columns, err = columnDictionary(body[8:layout.table])
+	return layout, err
+}
+
+// id returns the id of the i-th row.
+func (l indexedLayout) id(body []byte, i int) int {
+	return int(int64(binary.LittleEndian.Uint64(body[l.table+i*indexEntrySize:])))
+}
+
+// row decodes the i-th row.
+func (l indexedLayout) row(body []byte, i int) (interface{}, error) {
+	offset := l.data + int(binary.LittleEndian.Uint64(body[l.table+i*indexEntrySize+8:]))
+	if offset >= len(body) {
+		return nil, fmt.Errorf("indexed chunk is truncated")
+	}
+	return readBinaryRow(bytes.NewReader(body[offset:]), l.columns)
+}
+
+// Chunks of a frame nearly always share their columns, so decoded column
+// dictionaries are cached by their encoding instead of being decoded per row read.
+var (
+	columnDictsMutex sync.RWMutex
+	columnDicts      = make(map[string][]string)
+)
+
+const maxColumnDicts = 1024
+
+func columnDictionary(dict []byte) ([]string, error) {
+	columnDictsMutex.RLock()
+	columns, ok := columnDicts[string(dict)]
+	columnDictsMutex.RUnlock()
+	if ok {
+		return columns, nil
+	}
+
+	r := bytes.NewReader(dict)
+	numColumns, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if numColumns > uint64(len(dict)) {
+		return nil, fmt.Errorf("invalid column dictionary")
+	}
+	columns = make([]string, numColumns)
+	for i := range columns {
+		if columns[i], err = readBinaryString(r); err != nil {
+			return nil, err
+		}
+	}
+
+	columnDictsMutex.Lock()
+	if len(columnDicts) >= maxColumnDicts {
+		columnDicts = make(map[string][]string)
+	}
+	columnDicts[string(dict)] = columns
+	columnDictsMutex.Unlock()
+	return columns, nil
+}
@@ -0,0 +1,448 @@
+package dataframe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Extension types used by the msgpack codec for values msgpack has no type for.
+const (
+	msgpackExtNumber int8 = 1 // Go integer kind byte followed by 8 bytes
+	msgpackExtGob    int8 = 2 // Gob encoded value
+	msgpackExtTime   int8 = 3 // time.Time.MarshalBinary, keeping the location offset
+	msgpackExtNamed  int8 = 4 // Type name string and the value of a named type as its basic kind
+)
+
+// MsgpackCodec encodes a chunk as a MessagePack map from id to row, with every
+// row a self-describing map from column name to value. It is larger than
+// BinaryCodec but readable by any MessagePack decoder. Go ints, float64,
+// strings, bools, []byte and nil use the native MessagePack types.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+func (MsgpackCodec) Encode(w io.Writer, chunk map[int]interface{}) error {
+	bw := bufio.NewWriter(w)
+	buf := appendMsgpackMapHeader(nil, len(chunk))
+	for _, id := range sortedIDs(chunk) {
+		buf = appendMsgpackInt(buf, int64(id))
+		var err error
+		if values, ok := chunk[id].(map[string]interface{}); ok {
+			buf = appendMsgpackMapHeader(buf, len(values))
+			for _, col := range sortedKeys(values) {
+				buf = appendMsgpackString(buf, col)
+				if buf, err = appendMsgpackValue(buf, values[col]); err != nil {
+					return err
+				}
+			}
+		} else if buf, err = appendMsgpackValue(buf, chunk[id]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		buf = buf[:0]
+	}
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (MsgpackCodec) Decode(r io.Reader) (map[int]interface{}, error) {
+	br := bufio.NewReader(r)
+	v, err := readMsgpackValue(br)
+	if err != nil {
+		return nil, err
+	}
+	if empty, ok := v.(map[string]interface{}); ok && len(empty) == 0 {
+		return make(map[int]interface{}), nil
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("msgpack chunk is not a map")
+	}
+
+	chunk := make(map[int]interface{}, len(m))
+	for key, row := range m {
+		id, ok := key.(int)
+		if !ok {
+			return nil, fmt.Errorf("invalid row id %v", key)
+		}
+		chunk[id] = row
+	}
+	return chunk, nil
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendMsgpackMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xde), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(buf, 0xdf), uint32(n))
+}
+
+func appendMsgpackInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		return append(buf, byte(v))
+	case v < 0 && v >= -32:
+		return append(buf, byte(int8(v)))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(v))
+}
+
+func appendMsgpackString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+func appendMsgpackBin(buf []byte, b []byte) []byte {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xc5), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xc6), uint32(n))
+	}
+	return append(buf, b...)
+}
+
+func appendMsgpackExt(buf []byte, extType int8, data []byte) []byte {
+	switch n := len(data); {
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xc7, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xc8), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xc9), uint32(n))
+	}
+	return append(append(buf, byte(extType)), data...)
+}
+
+// appendMsgpackNumber appends a Go integer kind other than int as an extension,
+// so that it decodes to the same type.
+func appendMsgpackNumber(buf []byte, tag byte, bits uint64) []byte {
+	data := binary.BigEndian.AppendUint64([]byte{tag}, bits)
+	return appendMsgpackExt(buf, msgpackExtNumber, data)
+}
+
+func appendMsgpackValue(buf []byte, val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int:
+		return appendMsgpackInt(buf, int64(v)), nil
+	case int8:
+		return appendMsgpackNumber(buf, tagInt8, uint64(v)), nil
+	case int16:
+		return appendMsgpackNumber(buf, tagInt16, uint64(v)), nil
+	case int32:
+		return appendMsgpackNumber(buf, tagInt32, uint64(v)), nil
+	case int64:
+		return appendMsgpackNumber(buf, tagInt64, uint64(v)), nil
+	case uint:
+		return appendMsgpackNumber(buf, tagUint, uint64(v)), nil
+	case uint8:
+		return appendMsgpackNumber(buf, tagUint8, uint64(v)), nil
+	case uint16:
+		return appendMsgpackNumber(buf, tagUint16, uint64(v)), nil
+	case uint32:
+		return appendMsgpackNumber(buf, tagUint32, uint64(v)), nil
+	case uint64:
+		return appendMsgpackNumber(buf, tagUint64, v), nil
+	case float32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xca), math.Float32bits(v)), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(v)), nil
+	case string:
+		return appendMsgpackString(buf, v), nil
+	case []byte:
+		return appendMsgpackBin(buf, v), nil
+	case time.Time:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgpackExt(buf, msgpackExtTime, data), nil
+	case map[string]interface{}:
+		buf = appendMsgpackMapHeader(buf, len(v))
+		for _, key := range sortedKeys(v) {
+			buf = appendMsgpackString(buf, key)
+			var err error
+			if buf, err = appendMsgpackValue(buf, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	// Pointers are stored as the values they point to, as gob does
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return append(buf, 0xc0), nil
+		}
+		return appendMsgpackValue(buf, v.Elem().Interface())
+	}
+	if basic, name, ok := namedBasic(val); ok {
+		data, err := appendMsgpackValue(appendMsgpackString(nil, name), basic)
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgpackExt(buf, msgpackExtNamed, data), nil
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(&val); err != nil {
+		return nil, fmt.Errorf("error encoding %T: %v", val, err)
+	}
+	return appendMsgpackExt(buf, msgpackExtGob, data.Bytes()), nil
+}
+
+func readMsgpackN(br *bufio.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(br, b)
+	return b, err
+}
+
+// readMsgpackLength reads a big-endian length of size bytes.
+func readMsgpackLength(br *bufio.Reader, size int) (int, error) {
+	b, err := readMsgpackN(br, size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	}
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+// readMsgpackValue reads one value. Maps with string keys decode to column
+// maps, other maps to map[interface{}]interface{}, and integers to int.
+func readMsgpackValue(br *bufio.Reader) (interface{}, error) {
+	b, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int(b), nil
+	case b >= 0xe0:
+		return int(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return readMsgpackMap(br, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		s, err := readMsgpackN(br, int(b&0x1f))
+		return string(s), err
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		data, err := readMsgpackN(br, 4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(data)), nil
+	case 0xcb:
+		data, err := readMsgpackN(br, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		data, err := readMsgpackN(br, size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 1:
+			return int(int8(data[0])), nil
+		case 2:
+			return int(int16(binary.BigEndian.Uint16(data))), nil
+		case 4:
+			return int(int32(binary.BigEndian.Uint32(data))), nil
+		}
+		return int(int64(binary.BigEndian.Uint64(data))), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		size := 1 << (b - 0xcc)
+		data, err := readMsgpackN(br, size)
+		if err != nil {
+			return nil, err
+		}
+		var v uint64
+		for _, d := range data {
+			v = v<<8 | uint64(d)
+		}
+		return int(v), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(br, 1<<(b-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := readMsgpackN(br, n)
+		return string(s), err
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLength(br, 1<<(b-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackN(br, n)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(br, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(br, n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackLength(br, 1<<(b-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackExt(br, n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readMsgpackExt(br, 1<<(b-0xd4))
+	}
+	return nil, fmt.Errorf("unsupported msgpack type 0x%02x", b)
+}
+
+func readMsgpackMap(br *bufio.Reader, n int) (interface{}, error) {
+	keys := make([]interface{}, n)
+	values := make([]interface{}, n)
+	stringKeys := true
+	for i := 0; i < n; i++ {
+		var err error
+		if keys[i], err = readMsgpackValue(br); err != nil {
+			return nil, err
+		}
+		if values[i], err = readMsgpackValue(br); err != nil {
+			return nil, err
+		}
+		if _, ok := keys[i].(string); !ok {
+			stringKeys = false
+		}
+	}
+
+	if stringKeys {
+		m := make(map[string]interface{}, n)
+		for i, key := range keys {
+			m[key.(string)] = values[i]
+		}
+		return m, nil
+	}
+	m := make(map[interface{}]interface{}, n)
+	for i, key := range keys {
+		m[key] = values[i]
+	}
+	return m, nil
+}
+
+func readMsgpackExt(br *bufio.Reader, n int) (interface{}, error) {
+	extType, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := readMsgpackN(br, n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch int8(extType) {
+	case msgpackExtNumber:
+		if len(data) != 9 {
+			return nil, fmt.Errorf("invalid number extension")
+		}
+		bits := binary.BigEndian.Uint64(data[1:])
+		switch data[0] {
+		case tagInt8:
+			return int8(bits), nil
+		case tagInt16:
+			return int16(bits), nil
+		case tagInt32:
+			return int32(bits), nil
+		case tagInt64:
+			return int64(bits), nil
+		case tagUint:
+			return uint(bits), nil
+		case tagUint8:
+			return uint8(bits), nil
+		case tagUint16:
+			return uint16(bits), nil
+		case tagUint32:
+			return uint32(bits), nil
+		case tagUint64:
+			return bits, nil
+		}
+		return nil, fmt.Errorf("invalid number kind %d", data[0])
+	case msgpackExtTime:
+		var t time.Time
+		if err := t.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return t, nil
+	case msgpackExtGob:
+		var v interface{}
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case msgpackExtNamed:
+		r := bufio.NewReader(bytes.NewReader(data))
+		name, err := readMsgpackValue(r)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := name.(string); !ok {
+			return nil, fmt.Errorf("invalid named type extension")
+		}
+		v, err := readMsgpackValue(r)
+		if err != nil {
+			return nil, err
+		}
+		return namedValue(name.(string), v), nil
+	}
+	return nil, fmt.Errorf("unsupported msgpack extension %d", int8(extType))
+}
@@ -0,0 +1,205 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/gob"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type (
+	codecCelsius float64
+	codecLevel   int8
+	codecCount   uint16
+	codecCode    string
+	codecFlag    bool
+	codecBlob    []byte
+)
+
+type codecPoint struct{ X, Y int }
+
+var (
+	codecTime = time.Date(2024, 3, 1, 12, 30, 0, 5, time.FixedZone("CET", 3600))
+	codecInt  = 42
+)
+
+// basicChunk holds a row of every type every codec stores, gob included.
+func basicChunk() map[int]interface{} {
+	return map[int]interface{}{
+		0: map[string]interface{}{"I": 1, "F": 2.5, "S": "pen", "B": true, "T": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		1: map[string]interface{}{"I": -7, "F": -0.25, "S": "", "B": false, "T": time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)},
+		7: map[string]interface{}{"I": 1 << 40, "S": "ink"}, // Absent columns
+	}
+}
+
+// taggedChunk holds a row of every value tag of the binary and msgpack codecs.
+func taggedChunk() map[int]interface{} {
+	return map[int]interface{}{
+		0: map[string]interface{}{
+			"nil": nil, "false": false, "true": true,
+			"int": -1 << 40, "int8": int8(-8), "int16": int16(-16), "int32": int32(-32), "int64": int64(-1 << 62),
+			"uint": uint(1 << 40), "uint8": uint8(8), "uint16": uint16(16), "uint32": uint32(32), "uint64": uint64(1 << 63),
+			"float32": float32(1.5), "float64": -2.25, "string": "pen", "bytes": []byte{0, 1, 2},
+			"time": codecTime, "gob": codecPoint{1, 2},
+			"celsius": codecCelsius(21.5), "level": codecLevel(-3), "count": codecCount(9), "code": codecCode("x1"),
+			"flag": codecFlag(true), "blob": codecBlob{9, 8}, "duration": 90 * time.Second,
+		},
+		1: map[string]interface{}{"int": 0, "string": ""}, // Absent columns
+		2: "plain value",
+		3: &codecInt,
+		4: codecCelsius(-40),
+	}
+}
+
+// wantDecoded returns the chunk a round trip should give: pointers are stored
+// as the values they point to.
+func wantDecoded(chunk map[int]interface{}) map[int]interface{} {
+	want := make(map[int]interface{}, len(chunk))
+	for id, row := range chunk {
+		if p, ok := row.(*int); ok {
+			row = *p
+		}
+		want[id] = row
+	}
+	return want
+}
+
+// sameChunk compares chunks, comparing times by instant and offset.
+func sameChunk(t *testing.T, got, want map[int]interface{}) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("decoded %d rows, want %d", len(got), len(want))
+	}
+	for id, row := range want {
+		wantValues, isMap := row.(map[string]interface{})
+		if !isMap {
+			if !sameValue(got[id], row) {
+				t.Errorf("row %d = %#v, want %#v", id, got[id], row)
+			}
+			continue
+		}
+		gotValues, ok := got[id].(map[string]interface{})
+		if !ok || len(gotValues) != len(wantValues) {
+			t.Fatalf("row %d = %#v, want %#v", id, got[id], row)
+		}
+		for col, val := range wantValues {
+			if !sameValue(gotValues[col], val) {
+				t.Errorf("row %d column %s = %#v (%T), want %#v (%T)", id, col, gotValues[col], gotValues[col], val, val)
+			}
+		}
+	}
+}
+
+func sameValue(got, want interface{}) bool {
+	if wantTime, ok := want.(time.Time); ok {
+		gotTime, ok := got.(time.Time)
+		_, wantOffset := wantTime.Zone()
+		_, gotOffset := gotTime.Zone()
+		return ok && gotTime.Equal(wantTime) && gotOffset == wantOffset
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func TestChunkCodecRoundTrip(t *testing.T) {
+	gob.Register(codecPoint{})
+	compressions := []Compression{NoCompression, Zstd, Snappy, LZ4}
+	tests := []struct {
+		name  string
+		codec ChunkCodec
+		chunk func() map[int]interface{}
+	}{
+		{"basic", GobCodec{}, basicChunk},
+		{"basic", BinaryCodec{}, basicChunk},
+		{"tagged", BinaryCodec{}, taggedChunk},
+		{"basic", MsgpackCodec{}, basicChunk},
+		{"tagged", MsgpackCodec{}, taggedChunk},
+		{"basic", IndexedCodec{}, basicChunk},
+		{"tagged", IndexedCodec{}, taggedChunk},
+	}
+	for _, tt := range tests {
+		for _, compression := range compressions {
+			t.Run(tt.codec.Name()+"/"+tt.name+"/"+compression.String(), func(t *testing.T) {
+				chunk := tt.chunk()
+				var buf bytes.Buffer
+				if err := encodeChunk(&buf, tt.codec, compression, chunk, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+				decoded, err := decodeChunk(buf.Bytes(), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := wantDecoded(chunk)
+				sameChunk(t, decoded, want)
+
+				for id, row := range want {
+					got, ok, err := decodeChunkRow(buf.Bytes(), id, nil)
+					if err != nil || !ok {
+						t.Fatalf("decodeChunkRow(%d) = %v, %v", id, ok, err)
+					}
+					sameChunk(t, map[int]interface{}{id: got}, map[int]interface{}{id: row})
+				}
+				if _, ok, err := decodeChunkRow(buf.Bytes(), 100, nil); ok || err != nil {
+					t.Errorf("decodeChunkRow of a missing row = %v, %v", ok, err)
+				}
+			})
+		}
+	}
+}
+
+func TestChunkCodecUnknownNamedType(t *testing.T) {
+	type unknown int16
+	for _, codec := range []ChunkCodec{BinaryCodec{}, MsgpackCodec{}, IndexedCodec{}} {
+		var buf bytes.Buffer
+		chunk := map[int]interface{}{0: map[string]interface{}{"v": unknown(5)}}
+		if err := encodeChunk(&buf, codec, NoCompression, chunk, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		namedTypes.Delete(typeName(reflect.TypeOf(unknown(0))))
+
+		decoded, err := decodeChunk(buf.Bytes(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decoded[0].(map[string]interface{})["v"]; got != int16(5) {
+			t.Errorf("%s decoded an unknown named type as %#v, want int16(5)", codec.Name(), got)
+		}
+	}
+}
+
+func TestNamedTypeColumns(t *testing.T) {
+	type reading struct {
+		Temp  codecCelsius
+		Level *codecLevel
+	}
+	level := codecLevel(2)
+	for _, codec := range []ChunkCodec{BinaryCodec{}, MsgpackCodec{}, IndexedCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "readings")
+			df, err := CreateDataFrame(dir, []reading{{21.5, &level}, {-3, nil}}, WithChunkCodec(codec, Zstd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := df.UpdateRow(1, map[string]interface{}{"Temp": codecCelsius(4)}); err != nil {
+				t.Fatal(err)
+			}
+			df.Close()
+
+			reopened, err := OpenDataFrame(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			for id, want := range []map[string]interface{}{
+				{"Temp": codecCelsius(21.5), "Level": codecLevel(2)},
+				{"Temp": codecCelsius(4), "Level": nil},
+			} {
+				row, err := reopened.ReadRow(id)
+				if err != nil || !reflect.DeepEqual(row, want) {
+					t.Errorf("row %d = %#v, %v, want %#v", id, row, err, want)
+				}
+			}
+		})
+	}
+}
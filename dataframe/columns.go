@@ -0,0 +1,203 @@
+package dataframe
+
+import "fmt"
+
+// Select returns a copy of the DataFrame with only the given columns, in the
+// given order. Use Lazy().Select to project the result of a query instead.
+func (df *DataFrame) Select(columns ...string) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	seen := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		if !hasField(df.fields, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+		if seen[col] {
+			return nil, fmt.Errorf("column %s is selected twice", col)
+		}
+		seen[col] = true
+	}
+	return df.project(pickFields(df.fields, columns), columns)
+}
+
+// Drop returns a copy of the DataFrame without the given columns.
+func (df *DataFrame) Drop(columns ...string) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	for _, col := range columns {
+		if !hasField(df.fields, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+	}
+	var fields []Field
+	var source []string
+	for _, field := range df.fields {
+		if !contains(columns, field.Name) {
+			fields = append(fields, field)
+			source = append(source, field.Name)
+		}
+	}
+	return df.project(fields, source)
+}
+
+// Rename returns a copy of the DataFrame with columns renamed from the keys of
+// names to their values. Columns not in names keep their name.
+func (df *DataFrame) Rename(names map[string]string) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	for old := range names {
+		if !hasField(df.fields, old) {
+			return nil, fmt.Errorf("column %s not found", old)
+		}
+	}
+	fields := make([]Field, len(df.fields))
+	source := make([]string, len(df.fields))
+	taken := make(map[string]bool, len(df.fields))
+	for i, field := range df.fields {
+		source[i] = field.Name
+		if name, ok := names[field.Name]; ok {
+			field.Name = name
+		}
+		if taken[field.Name] {
+			return nil, fmt.Errorf("column %s already exists", field.Name)
+		}
+		taken[field.Name] = true
+		fields[i] = field
+	}
+	return df.project(fields, source)
+}
+
+// Reorder returns a copy of the DataFrame with the given columns first, in the
+// given order, followed by the other columns in their current order.
+func (df *DataFrame) Reorder(columns ...string) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	for i, col := range columns {
+		if !hasField(df.fields, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+		if contains(columns[:i], col) {
+			return nil, fmt.Errorf("column %s is listed twice", col)
+		}
+	}
+	fields := pickFields(df.fields, columns)
+	for _, field := range df.fields {
+		if !contains(columns, field.Name) {
+			fields = append(fields, field)
+		}
+	}
+	source := make([]string, len(fields))
+	for i, field := range fields {
+		source[i] = field.Name
+	}
+	return df.project(fields, source)
+}
+
+// project returns a copy of the DataFrame with the given fields, the values of
+// fields[i] taken from column source[i]. When every column is kept under its
+// name, the chunk files of the chunks without unflushed changes are copied
+// whole rather than decoded, and the rows of the other chunks are shared
+// rather than copied, as stored rows are never modified. The codec, the Bloom
+// filters and the dictionaries of the columns kept carry over, as does the
+// time index if its column is kept. The caller must hold df.mutex.
+func (df *DataFrame) project(fields []Field, source []string) (*DataFrame, error) {
+	shared := len(fields) == len(df.fields)
+	for i, field := range fields {
+		shared = shared && field.Name == source[i]
+	}
+
+	ids := df.rowIDs()
+	out, err := df.derivedFrame(len(ids), fields, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if shared {
+		var copied []int
+		if copied, ids, err = df.copyCleanChunks(out, ids); err != nil {
+			out.Close()
+			return nil, err
+		}
+		out.indexIDs(groupByChunk(copied))
+	}
+
+	parts, err := mapChunks(df, ids, func(chunk Chunk) (derivedChunk, error) {
+		if shared {
+			return derivedChunk{ids: chunk.IDs, rows: chunk.Rows}, nil
+		}
+		rows := make([]Row, len(chunk.Rows))
+		for i, row := range chunk.Rows {
+			projected := make(Row, len(fields))
+			for j, field := range fields {
+				if val, ok := row[source[j]]; ok {
+					projected[field.Name] = val
+				}
+			}
+			rows[i] = projected
+		}
+		return derivedChunk{ids: chunk.IDs, rows: rows}, nil
+	})
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+	rows := make(map[int]interface{})
+	for _, part := range parts {
+		for i, id := range part.ids {
+			rows[id] = map[string]interface{}(part.rows[i])
+		}
+	}
+	if len(rows) > 0 {
+		out.InsertRows(rows)
+	}
+
+	if df.timeIndex != nil {
+		for i, col := range source {
+			if col != df.timeIndex.column {
+				continue
+			}
+			if err := out.SetTimeIndex(fields[i].Name); err != nil {
+				out.Close()
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// copyCleanChunks copies to out the chunk files of the chunks of ids without
+// unflushed changes, returning the ids they hold and the ids of the other
+// chunks. The caller must hold df.mutex.
+func (df *DataFrame) copyCleanChunks(out *DataFrame, ids []int) (copied, rest []int, err error) {
+	for _, task := range groupByChunk(ids) {
+		if !df.chunkChanged(task.chunkID) {
+			stats, ok, err := df.copyChunkFile(out, task.chunkID, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				if stats != nil {
+					out.stats[stats.ID] = stats
+				}
+				copied = append(copied, task.ids...)
+				continue
+			}
+		}
+		rest = append(rest, task.ids...)
+	}
+	return copied, rest, nil
+}
+
+// chunkChanged reports whether a chunk has changes not yet written to its
+// chunk file. The caller must hold df.mutex.
+func (df *DataFrame) chunkChanged(chunkID int) bool {
+	_, cached := df.cache[chunkID]
+	_, deleted := df.deleted[chunkID]
+	_, flushing := df.flushing[chunkID]
+	_, flushDeleted := df.flushDeleted[chunkID]
+	return cached || deleted || flushing || flushDeleted
+}
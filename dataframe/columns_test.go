@@ -0,0 +1,113 @@
+package dataframe
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDerivedFrameEncodings(t *testing.T) {
+	items := make([]durableItem, 2500)
+	for i := range items {
+		items[i] = durableItem{[]string{"pen", "ink", "cap"}[i%3], i}
+	}
+	df, err := NewDataFrame(items, WithCategorical("Name"), WithBloomFilters("Name", "Qty"), WithChunkCodec(BinaryCodec{}, Zstd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	if err := df.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := df.UpdateRow(1, map[string]interface{}{"Name": "pad"}); err != nil {
+		t.Fatal(err) // Leaves the first chunk unflushed
+	}
+
+	tests := []struct {
+		name       string
+		derive     func() (*DataFrame, error)
+		renamed    map[string]string // Column of df each column comes from, if renamed
+		columns    []string
+		bloom      []string
+		categories []string
+	}{
+		{"select all", func() (*DataFrame, error) { return df.Select("Name", "Qty") }, nil,
+			[]string{"Name", "Qty"}, []string{"Name", "Qty"}, []string{"Name"}},
+		{"drop", func() (*DataFrame, error) { return df.Drop("Qty") }, nil,
+			[]string{"Name"}, []string{"Name"}, []string{"Name"}},
+		{"rename", func() (*DataFrame, error) { return df.Rename(map[string]string{"Name": "Product"}) }, map[string]string{"Product": "Name"},
+			[]string{"Product", "Qty"}, []string{"Product", "Qty"}, []string{"Product"}},
+		{"add column", func() (*DataFrame, error) { return df.WithColumn("Double", Col("Qty").Mul(2)) }, nil,
+			[]string{"Name", "Qty", "Double"}, []string{"Name", "Qty"}, []string{"Name"}},
+		{"replace column", func() (*DataFrame, error) { return df.WithColumn("Name", Col("Qty").Mul(2)) }, nil,
+			[]string{"Name", "Qty"}, []string{"Qty"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.derive()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if err := out.flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(out.Columns(), tt.columns) {
+				t.Errorf("columns = %v, want %v", out.Columns(), tt.columns)
+			}
+			if out.codec != df.codec || out.compression != df.compression {
+				t.Errorf("codec = %s %v, want %s %v", out.codec.Name(), out.compression, df.codec.Name(), df.compression)
+			}
+			bloom := append([]string(nil), out.bloomColumns...)
+			sort.Strings(bloom)
+			if len(bloom) > 0 || len(tt.bloom) > 0 {
+				if !reflect.DeepEqual(bloom, tt.bloom) {
+					t.Errorf("Bloom columns = %v, want %v", bloom, tt.bloom)
+				}
+			}
+			if got := out.categoricalColumns(); len(got) > 0 || len(tt.categories) > 0 {
+				if !reflect.DeepEqual(got, tt.categories) {
+					t.Errorf("categorical columns = %v, want %v", got, tt.categories)
+				}
+			}
+			for _, col := range tt.categories {
+				if values, err := out.Categories(col); err != nil || !reflect.DeepEqual(values, []string{"cap", "ink", "pad", "pen"}) {
+					t.Errorf("Categories(%s) = %v, %v", col, values, err)
+				}
+			}
+
+			rows := 0
+			err = out.scanRows(func(id int, row map[string]interface{}) error {
+				rows++
+				source, err := df.ReadRow(id)
+				if err != nil {
+					return err
+				}
+				for _, col := range tt.columns {
+					from := col
+					if name, ok := tt.renamed[col]; ok {
+						from = name
+					}
+					want, ok := source.(map[string]interface{})[from]
+					if !ok {
+						continue // Computed
+					}
+					if tt.name == "replace column" && col == "Name" {
+						want = 2 * id
+					}
+					if !reflect.DeepEqual(row[col], want) {
+						t.Fatalf("row %d column %s = %#v, want %#v", id, col, row[col], want)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != len(items) {
+				t.Errorf("derived frame has %d rows, want %d", rows, len(items))
+			}
+		})
+	}
+}
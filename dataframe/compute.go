@@ -0,0 +1,141 @@
+package dataframe
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WithColumn returns a copy of the DataFrame with a column computed from expr
+// for every row, for example Col("Price").Mul(Col("Quantity")). A column with
+// the same name is replaced in place; otherwise the column is added last. The
+// other columns keep their Bloom filters and categorical dictionaries.
+func (df *DataFrame) WithColumn(name string, expr Expr) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	for _, col := range expr.Columns() {
+		if !hasField(df.fields, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+	}
+	return df.deriveColumn(name, func(id int, row Row) (interface{}, error) {
+		return expr.Eval(id, row)
+	})
+}
+
+// Apply returns a copy of the DataFrame with a column holding the result of fn
+// for every row. fn receives a copy of the row and is called concurrently from
+// the worker pool, so it must be safe for that.
+func (df *DataFrame) Apply(name string, fn func(Row) interface{}) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	return df.deriveColumn(name, func(_ int, row Row) (interface{}, error) {
+		return fn(row), nil
+	})
+}
+
+// MapColumn returns a copy of the DataFrame with every value of a column
+// replaced by the result of fn. fn is called concurrently from the worker
+// pool, so it must be safe for that.
+func (df *DataFrame) MapColumn(column string, fn func(interface{}) interface{}) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if !hasField(df.fields, column) {
+		return nil, fmt.Errorf("column %s not found", column)
+	}
+	return df.deriveColumn(column, func(_ int, row Row) (interface{}, error) {
+		return fn(row[column]), nil
+	})
+}
+
+// derivedChunk is the part of a derived DataFrame computed from one chunk.
+type derivedChunk struct {
+	ids   []int
+	rows  []Row
+	typ   reflect.Type // Type of the non-nil values computed, nil if none
+	mixed bool         // Values of several types were computed
+}
+
+// deriveColumn computes a column with value for every row, one chunk per
+// worker, and returns a copy of the DataFrame holding it. The type of the
+// column is the type of its values, or interface{} if they differ. The time
+// index is kept unless the column replaces it with values other than times.
+// The caller must hold df.mutex.
+func (df *DataFrame) deriveColumn(name string, value func(id int, row Row) (interface{}, error)) (*DataFrame, error) {
+	parts, err := mapChunks(df, df.rowIDs(), func(chunk Chunk) (derivedChunk, error) {
+		part := derivedChunk{ids: chunk.IDs, rows: make([]Row, len(chunk.Rows))}
+		for i, row := range chunk.Rows {
+			copied := make(Row, len(row)+1)
+			for col, val := range row {
+				copied[col] = val
+			}
+			val, err := value(chunk.IDs[i], copied)
+			if err != nil {
+				return part, fmt.Errorf("error computing column %s for row %d: %v", name, chunk.IDs[i], err)
+			}
+			copied[name] = val
+			part.rows[i] = copied
+			if val != nil {
+				part.addType(reflect.TypeOf(val))
+			}
+		}
+		return part, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	var rows []Row
+	var total derivedChunk
+	for _, part := range parts {
+		ids = append(ids, part.ids...)
+		rows = append(rows, part.rows...)
+		total.mixed = total.mixed || part.mixed
+		if part.typ != nil {
+			total.addType(part.typ)
+		}
+	}
+	field := Field{Name: name, Type: total.typ}
+	if total.typ == nil || total.mixed {
+		field.Type = reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+
+	fields := append([]Field(nil), df.fields...)
+	source := fieldNames(fields)
+	replaced := false
+	for i := range fields {
+		if fields[i].Name == name {
+			fields[i], replaced = field, true
+			source[i] = "" // The computed values replace the column
+		}
+	}
+	if !replaced {
+		fields = append(fields, field)
+		source = append(source, "")
+	}
+
+	out, err := df.derivedFrameFromRows(fields, source, ids, rows)
+	if err != nil {
+		return nil, err
+	}
+	if ix := df.timeIndex; ix != nil && (ix.column != name || field.Type == reflect.TypeOf(time.Time{})) {
+		if err := out.SetTimeIndex(ix.column); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// addType records the type of computed values.
+func (d *derivedChunk) addType(t reflect.Type) {
+	if d.typ == nil {
+		d.typ = t
+	} else if d.typ != t {
+		d.mixed = true
+	}
+}
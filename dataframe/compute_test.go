@@ -0,0 +1,96 @@
+package dataframe
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type computeSpan struct {
+	Name       string
+	Start, End time.Time
+	Wait       time.Duration
+}
+
+func computeSpans() []computeSpan {
+	base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	return []computeSpan{
+		{"a", base, base.Add(time.Hour), 0},
+		{"b", base, base.Add(90 * time.Second), time.Minute},
+	}
+}
+
+func TestWithColumnTimeArithmetic(t *testing.T) {
+	df, err := NewDataFrame(computeSpans())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	tests := []struct {
+		expr Expr
+		want []interface{}
+	}{
+		{Col("End").Sub(Col("Start")), []interface{}{time.Hour, 90 * time.Second}},
+		{Col("End").Sub(Col("Start")).Add(Col("Wait")), []interface{}{time.Hour, 150 * time.Second}},
+		{Col("Start").Add(Col("Wait")), []interface{}{computeSpans()[0].Start, computeSpans()[1].Start.Add(time.Minute)}},
+		{Col("End").Sub(Col("Wait")), []interface{}{computeSpans()[0].End, computeSpans()[1].End.Add(-time.Minute)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr.String(), func(t *testing.T) {
+			out, err := df.WithColumn("Took", tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			// Concatenating writes the chunk files of out
+			both, err := Concat([]*DataFrame{out, out})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer both.Close()
+
+			for i, want := range tt.want {
+				for _, id := range []int{i, chunkSize + i} {
+					row, err := both.ReadRow(id)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := row.(map[string]interface{})["Took"]; got != want {
+						t.Errorf("row %d = %#v (%T), want %#v (%T)", id, got, got, want, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDurableDurations(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spans")
+	df, err := CreateDataFrame(dir, computeSpans())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := df.UpdateRow(0, map[string]interface{}{"Wait": 5 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	df.Close()
+
+	reopened, err := OpenDataFrame(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	var waits []interface{}
+	for _, id := range []int{0, 1} {
+		row, err := reopened.ReadRow(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waits = append(waits, row.(map[string]interface{})["Wait"])
+	}
+	if want := []interface{}{5 * time.Second, time.Minute}; !reflect.DeepEqual(waits, want) {
+		t.Errorf("waits = %#v, want %#v", waits, want)
+	}
+}
@@ -0,0 +1,431 @@
+package dataframe
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// concatMutex is held by Concat while it takes the read locks of its frames.
+var concatMutex sync.Mutex
+
+// concatConfig holds the options of Concat and HConcat.
+type concatConfig struct {
+	preserveIDs bool
+	intersect   bool
+}
+
+// ConcatOption configures Concat and HConcat.
+type ConcatOption func(*concatConfig)
+
+// WithPreservedIDs makes Concat keep the ids of the rows of every frame instead
+// of moving the ids of each frame past those of the frames before it. The ids
+// must then be distinct.
+func WithPreservedIDs() ConcatOption {
+	return func(c *concatConfig) {
+		c.preserveIDs = true
+	}
+}
+
+// WithSchemaIntersection makes Concat keep only the columns found in every
+// frame, and HConcat only the ids found in every frame. By default all of them
+// are kept, with nil for the values a frame does not have.
+func WithSchemaIntersection() ConcatOption {
+	return func(c *concatConfig) {
+		c.intersect = true
+	}
+}
+
+func newConcatConfig(opts []ConcatOption) concatConfig {
+	var cfg concatConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Concat stacks the rows of frames into a new DataFrame. A column found in
+// several frames with different numeric types is promoted to int or float64,
+// and to interface{} for other types. Unless WithPreservedIDs is given, the ids
+// of every frame are moved by a multiple of the chunk size past the ids of the
+// frames before it, keeping their order and gaps, so that the first frame keeps
+// its ids and every chunk holds the rows of one frame. The chunk files of a
+// frame whose schema is unchanged are then copied whole, only their headers
+// being rewritten, for the chunks without unflushed changes; with
+// WithPreservedIDs only chunks no other frame has ids in are. The frames are
+// not flushed. Frames with categorical columns, chunk files written before
+// chunk headers existed and frames whose schema changes are still decoded and
+// their rows inserted one by one. The time index is kept if every frame has it
+// on the same column. Every frame is read under its read lock for the whole
+// call, so the result holds the rows of all frames at one point in time.
+func Concat(frames []*DataFrame, opts ...ConcatOption) (*DataFrame, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to concatenate")
+	}
+	cfg := newConcatConfig(opts)
+
+	// A frame given twice is locked once, as read locks do not nest while a
+	// writer waits. Concats take their locks one at a time, so that two of them
+	// locking the same frames in another order never wait on each other.
+	locked := make(map[*DataFrame]bool, len(frames))
+	concatMutex.Lock()
+	for _, f := range frames {
+		if !locked[f] {
+			locked[f] = true
+			f.mutex.RLock()
+			defer f.mutex.RUnlock()
+		}
+	}
+	concatMutex.Unlock()
+
+	schemas := make([][]Field, len(frames))
+	deltas := make([]int, len(frames)) // Added to the ids of each frame
+	timeColumn := ""
+	total := 0
+	nextChunk := 0
+	owners := make(map[int]int) // Number of frames with ids in each chunk
+	seen := make(map[int]bool)
+	for i, f := range frames {
+		schemas[i] = f.fields
+		ids := f.rowIDs()
+		column := ""
+		if f.timeIndex != nil {
+			column = f.timeIndex.column
+		}
+
+		if i == 0 || column == timeColumn {
+			timeColumn = column
+		} else {
+			timeColumn = ""
+		}
+		total += len(ids)
+		if !cfg.preserveIDs {
+			if len(ids) > 0 {
+				if i > 0 {
+					deltas[i] = (nextChunk - ids[0]/chunkSize) * chunkSize
+				}
+				nextChunk = (ids[len(ids)-1]+deltas[i])/chunkSize + 1
+			}
+			continue
+		}
+		for _, task := range groupByChunk(ids) {
+			owners[task.chunkID]++
+		}
+		for _, id := range ids {
+			if seen[id] {
+				return nil, fmt.Errorf("row id %d is in several frames", id)
+			}
+			seen[id] = true
+		}
+	}
+	fields := concatFields(schemas, cfg.intersect)
+
+	out, err := newDataFrame(total, "")
+	if err != nil {
+		return nil, err
+	}
+	out.Name = frames[0].Name
+	out.fields = fields
+
+	var copiedIDs []int
+	rows := make(map[int]interface{})
+	for i, f := range frames {
+		var ids []int
+		var err error
+		if sameFields(schemas[i], fields) {
+			ids, err = f.copyChunkFiles(out, deltas[i], func(chunkID int) bool {
+				return !cfg.preserveIDs || owners[chunkID] == 1
+			}, rows)
+		} else {
+			err = f.conformRows(fields, deltas[i], rows)
+		}
+		if err != nil {
+			out.Close()
+			return nil, err
+		}
+		copiedIDs = append(copiedIDs, ids...)
+	}
+
+	sort.Ints(copiedIDs)
+	out.indexIDs(groupByChunk(copiedIDs))
+	if len(rows) > 0 {
+		out.InsertRows(rows)
+	}
+	if timeColumn != "" && hasField(fields, timeColumn) {
+		if err := out.SetTimeIndex(timeColumn); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// copyChunkFiles copies the chunk files of df to out with the ids of their rows
+// moved by delta, a multiple of chunkSize, returning the moved ids they hold.
+// The rows of the chunks owns rejects are added to rows instead, as are those
+// of chunks with unflushed changes and of chunk files that cannot be copied:
+// chunk files with category codes cannot be read without the dictionaries of
+// df, and plain gob files cannot be moved. The caller must hold df.mutex.
+func (df *DataFrame) copyChunkFiles(out *DataFrame, delta int, owns func(chunkID int) bool, rows map[int]interface{}) ([]int, error) {
+	var copied []int
+	for _, task := range groupByChunk(df.rowIDs()) {
+		if owns(task.chunkID) && len(df.categories) == 0 && !df.chunkChanged(task.chunkID) {
+			stats, ok, err := df.copyChunkFile(out, task.chunkID, delta)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				if stats != nil {
+					out.stats[stats.ID] = stats
+				}
+				for _, id := range task.ids {
+					copied = append(copied, id+delta)
+				}
+				continue
+			}
+		}
+
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range chunk.IDs {
+			rows[id+delta] = map[string]interface{}(chunk.Rows[i])
+		}
+	}
+	return copied, nil
+}
+
+// copyChunkFile copies the chunk file of a chunk to out with the ids of its rows
+// moved by delta, a multiple of chunkSize, returning the statistics of the copy
+// if df has them. The file is not decoded; only its header is rewritten when
+// delta is not 0. ok is false for plain gob files, which cannot be moved. The
+// caller must hold df.mutex, and the chunk must have no unflushed changes.
+func (df *DataFrame) copyChunkFile(out *DataFrame, chunkID, delta int) (stats *ChunkStats, ok bool, err error) {
+	name := chunkName(chunkID)
+	data, err := df.store.Get(name)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading chunk file %s: %v", name, err)
+	}
+	if delta != 0 {
+		if data, ok, err = moveChunk(data, delta); err != nil || !ok {
+			return nil, false, err
+		}
+	}
+	movedID := chunkID + delta/chunkSize
+	moved := chunkName(movedID)
+	if err := out.store.Put(moved, data); err != nil {
+		return nil, false, fmt.Errorf("error writing chunk file %s: %v", moved, err)
+	}
+	if s, found := df.stats[chunkID]; found {
+		copied := *s
+		copied.ID = movedID
+		stats = &copied
+	}
+	return stats, true, nil
+}
+
+// conformRows adds the rows of df to rows with the given fields, converting
+// values to the promoted types and filling missing columns with nil. The ids of
+// the rows are moved by delta. The caller must hold df.mutex.
+func (df *DataFrame) conformRows(fields []Field, delta int, rows map[int]interface{}) error {
+	types := make(map[string]reflect.Type, len(df.fields))
+	for _, field := range df.fields {
+		types[field.Name] = field.Type
+	}
+	parts, err := mapChunks(df, df.rowIDs(), func(chunk Chunk) (derivedChunk, error) {
+		part := derivedChunk{ids: chunk.IDs, rows: make([]Row, len(chunk.Rows))}
+		for i, row := range chunk.Rows {
+			conformed := make(Row, len(fields))
+			for _, field := range fields {
+				val := row[field.Name]
+				if types[field.Name] != field.Type {
+					val = promoteValue(val, field.Type)
+				}
+				conformed[field.Name] = val
+			}
+			part.rows[i] = conformed
+		}
+		return part, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		for i, id := range part.ids {
+			rows[id+delta] = map[string]interface{}(part.rows[i])
+		}
+	}
+	return nil
+}
+
+// HConcat places the columns of frames side by side in a new DataFrame, joining
+// rows on their id. By default every id of any frame is kept, with nil for the
+// columns of frames without it; WithSchemaIntersection keeps only the ids of
+// every frame. Frames must not share column names.
+func HConcat(frames []*DataFrame, opts ...ConcatOption) (*DataFrame, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to concatenate")
+	}
+	cfg := newConcatConfig(opts)
+
+	var fields []Field
+	merged := make(map[int]Row)
+	counts := make(map[int]int)
+	timeColumn := ""
+	for i, f := range frames {
+		f.mutex.RLock()
+		for _, field := range f.fields {
+			if hasField(fields, field.Name) {
+				f.mutex.RUnlock()
+				return nil, fmt.Errorf("column %s is in several frames", field.Name)
+			}
+			fields = append(fields, field)
+		}
+		if i == 0 && f.timeIndex != nil {
+			timeColumn = f.timeIndex.column
+		}
+		parts, err := mapChunks(f, f.rowIDs(), func(chunk Chunk) (Chunk, error) {
+			return chunk, nil
+		})
+		f.mutex.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range parts {
+			for j, id := range chunk.IDs {
+				row := merged[id]
+				if row == nil {
+					row = make(Row)
+					merged[id] = row
+				}
+				for col, val := range chunk.Rows[j] {
+					row[col] = val
+				}
+				counts[id]++
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(merged))
+	for id := range merged {
+		if !cfg.intersect || counts[id] == len(frames) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	rows := make([]Row, len(ids))
+	for i, id := range ids {
+		rows[i] = merged[id]
+		for _, field := range fields {
+			if _, ok := rows[i][field.Name]; !ok {
+				rows[i][field.Name] = nil
+			}
+		}
+	}
+
+	out, err := newDataFrameFromRows(frames[0].Name, fields, ids, rows)
+	if err != nil {
+		return nil, err
+	}
+	if timeColumn != "" {
+		if err := out.SetTimeIndex(timeColumn); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// concatFields returns the columns of the stacked schemas in order of first
+// appearance, only those of every schema if intersect is set, with the types
+// promoted by promoteType.
+func concatFields(schemas [][]Field, intersect bool) []Field {
+	var fields []Field
+	counts := make(map[string]int)
+	for _, schema := range schemas {
+		for _, field := range schema {
+			counts[field.Name]++
+			found := false
+			for i := range fields {
+				if fields[i].Name == field.Name {
+					fields[i].Type = promoteType(fields[i].Type, field.Type)
+					found = true
+				}
+			}
+			if !found {
+				fields = append(fields, field)
+			}
+		}
+	}
+	if !intersect {
+		return fields
+	}
+	kept := fields[:0]
+	for _, field := range fields {
+		if counts[field.Name] == len(schemas) {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// promoteType returns a type able to hold values of both types: int for
+// integers, float64 for numbers with a float among them, and interface{}
+// otherwise.
+func promoteType(a, b reflect.Type) reflect.Type {
+	if a == b {
+		return a
+	}
+	if a != nil && b != nil && isNumericKind(a.Kind()) && isNumericKind(b.Kind()) {
+		if isFloatKind(a.Kind()) || isFloatKind(b.Kind()) {
+			return reflect.TypeOf(0.0)
+		}
+		return reflect.TypeOf(0)
+	}
+	return reflect.TypeOf((*interface{})(nil)).Elem()
+}
+
+// promoteValue converts a value to a type returned by promoteType.
+func promoteValue(val interface{}, t reflect.Type) interface{} {
+	if _, ok := deref(val); !ok || t == nil {
+		return val
+	}
+	switch t.Kind() {
+	case reflect.Float64:
+		if f, ok := ToFloat64(val); ok {
+			return f
+		}
+	case reflect.Int:
+		if i, ok := integerValue(val); ok {
+			return i
+		}
+	}
+	return val
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64 && k != reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// sameFields reports whether two schemas hold the same columns with the same
+// types, in any order.
+func sameFields(a, b []Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, field := range a {
+		found := pickFields(b, []string{field.Name})
+		if len(found) != 1 || found[0].Type != field.Type {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package dataframe
+
+import (
+	"reflect"
+	"testing"
+)
+
+// concatRows returns the rows of df by id.
+func concatRows(t *testing.T, df *DataFrame) map[int]map[string]interface{} {
+	t.Helper()
+	rows := make(map[int]map[string]interface{})
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		rows[id] = row
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestConcat(t *testing.T) {
+	type floatItem struct {
+		Name string
+		Qty  float64
+	}
+	items, err := NewDataFrame([]durableItem{{"pen", 1}, {"ink", 2}, {"cap", 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer items.Close()
+	if err := items.flush(); err != nil {
+		t.Fatal(err)
+	}
+	stock, err := NewDataFrame([]durableItem{{"pad", 7}, {"cup", 8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stock.Close()
+	if err := stock.flush(); err != nil {
+		t.Fatal(err)
+	}
+	floats, err := NewDataFrame([]floatItem{{"pad", 1.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer floats.Close()
+	late, err := newDataFrameFromRows("late", items.fields, []int{5000, 5007}, []Row{{"Name": "cup", "Qty": 4}, {"Name": "mug", "Qty": 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer late.Close()
+	if err := items.UpdateRow(1, map[string]interface{}{"Qty": 20}); err != nil {
+		t.Fatal(err) // Left unflushed
+	}
+
+	tests := []struct {
+		name   string
+		frames []*DataFrame
+		opts   []ConcatOption
+		want   map[int]map[string]interface{}
+		copied []int // Chunks whose files are copied rather than inserted
+	}{
+		{"moved ids", []*DataFrame{late, items, stock}, nil, map[int]map[string]interface{}{
+			5000: {"Name": "cup", "Qty": 4}, 5007: {"Name": "mug", "Qty": 5},
+			6000: {"Name": "pen", "Qty": 1}, 6001: {"Name": "ink", "Qty": 20}, 6002: {"Name": "cap", "Qty": 3},
+			7000: {"Name": "pad", "Qty": 7}, 7001: {"Name": "cup", "Qty": 8},
+		}, []int{7}},
+		{"same frame twice", []*DataFrame{items, items}, nil, map[int]map[string]interface{}{
+			0: {"Name": "pen", "Qty": 1}, 1: {"Name": "ink", "Qty": 20}, 2: {"Name": "cap", "Qty": 3},
+			1000: {"Name": "pen", "Qty": 1}, 1001: {"Name": "ink", "Qty": 20}, 1002: {"Name": "cap", "Qty": 3},
+		}, nil},
+		{"promoted", []*DataFrame{items, floats}, nil, map[int]map[string]interface{}{
+			0: {"Name": "pen", "Qty": 1.0}, 1: {"Name": "ink", "Qty": 20.0}, 2: {"Name": "cap", "Qty": 3.0},
+			1000: {"Name": "pad", "Qty": 1.5},
+		}, nil},
+		{"preserved ids", []*DataFrame{items, late}, []ConcatOption{WithPreservedIDs()}, map[int]map[string]interface{}{
+			0: {"Name": "pen", "Qty": 1}, 1: {"Name": "ink", "Qty": 20}, 2: {"Name": "cap", "Qty": 3},
+			5000: {"Name": "cup", "Qty": 4}, 5007: {"Name": "mug", "Qty": 5},
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Concat(tt.frames, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if got := concatRows(t, out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Concat = %v, want %v", got, tt.want)
+			}
+			for _, chunkID := range tt.copied {
+				if _, cached := out.cache[chunkID]; cached {
+					t.Errorf("chunk %d was inserted rather than copied", chunkID)
+				}
+			}
+		})
+	}
+
+	if _, ok := items.cache[0]; !ok {
+		t.Error("Concat flushed the unflushed rows of a frame")
+	}
+	if _, err := Concat([]*DataFrame{items, items}, WithPreservedIDs()); err == nil {
+		t.Error("Concat with preserved ids accepted an id in two frames")
+	}
+}
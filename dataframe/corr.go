@@ -0,0 +1,515 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// corrConfig holds the settings of Corr and Cov.
+type corrConfig struct {
+	minPeriods int
+}
+
+// CorrOption configures Corr and Cov.
+type CorrOption func(*corrConfig)
+
+// WithMinPeriods sets the number of rows in which both columns of a pair must
+// be non-null for the pair to get a value. Pairs with fewer rows are null.
+// The default is 1.
+func WithMinPeriods(n int) CorrOption {
+	return func(c *corrConfig) {
+		c.minPeriods = n
+	}
+}
+
+func newCorrConfig(opts []CorrOption) *corrConfig {
+	c := &corrConfig{minPeriods: 1}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// pairMoments accumulates the means and co-moments of two columns over the
+// rows where both are non-null. Moments of separate chunks merge into the
+// moments of all of them.
+type pairMoments struct {
+	n             float64
+	meanX, meanY  float64
+	cxy, m2x, m2y float64 // Sums of products of deviations from the means
+}
+
+func (p *pairMoments) add(x, y float64) {
+	p.n++
+	dx := x - p.meanX
+	p.meanX += dx / p.n
+	dy := y - p.meanY
+	p.meanY += dy / p.n
+	p.cxy += dx * (y - p.meanY)
+	p.m2x += dx * (x - p.meanX)
+	p.m2y += dy * (y - p.meanY)
+}
+
+func (p *pairMoments) merge(o *pairMoments) {
+	if o.n == 0 {
+		return
+	}
+	n := p.n + o.n
+	dx := o.meanX - p.meanX
+	dy := o.meanY - p.meanY
+	w := p.n * o.n / n
+	p.cxy += o.cxy + dx*dy*w
+	p.m2x += o.m2x + dx*dx*w
+	p.m2y += o.m2y + dy*dy*w
+	p.meanX += dx * o.n / n
+	p.meanY += dy * o.n / n
+	p.n = n
+}
+
+// corr returns the Pearson correlation, or nil if it is undefined.
+func (p *pairMoments) corr(minPeriods int, diagonal bool) interface{} {
+	if p.n < float64(minPeriods) || p.m2x <= 0 || p.m2y <= 0 {
+		return nil
+	}
+	if diagonal {
+		return 1.0
+	}
+	return math.Max(-1, math.Min(1, p.cxy/math.Sqrt(p.m2x*p.m2y)))
+}
+
+// cov returns the sample covariance, or nil if it is undefined.
+func (p *pairMoments) cov(minPeriods int) interface{} {
+	if p.n < float64(minPeriods) || p.n < 2 {
+		return nil
+	}
+	return p.cxy / (p.n - 1)
+}
+
+// numericFields returns the fields that may hold numbers: numeric ones and
+// interface{} ones, which hold numbers if all their values do.
+func numericFields(fields []Field) []Field {
+	var numeric []Field
+	for _, field := range fields {
+		if describeKind(field.Type) != describeCategorical {
+			numeric = append(numeric, field)
+		}
+	}
+	return numeric
+}
+
+// numericRow fills vals with the values of the columns of a row, NaN for
+// nulls, and counts the values that are not numbers.
+func numericRow(row Row, cols []Field, vals []float64, nonNumeric []int) {
+	for i, col := range cols {
+		vals[i] = math.NaN()
+		val, ok := statValue(row[col.Name])
+		if !ok {
+			continue
+		}
+		if x, ok := numericValue(val); ok {
+			vals[i] = x
+		} else {
+			nonNumeric[i]++
+		}
+	}
+}
+
+// numericIDs returns the ids of all rows, less those of chunks whose zone maps
+// show no values in any of the columns. The caller must hold df.mutex.
+func (df *DataFrame) numericIDs(cols []Field) []int {
+	return df.skipChunks(df.rowIDs(), func(stats *ChunkStats) bool {
+		for _, col := range cols {
+			if stats.Columns[col.Name].Count > 0 {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// pairwiseMoments accumulates the moments of every pair of columns i <= j in
+// one pass over the chunks. The moments of pair (i, j) are at i*len(cols)+j.
+// The caller must hold df.mutex.
+func (df *DataFrame) pairwiseMoments(cols []Field) ([]pairMoments, []int, error) {
+	k := len(cols)
+	totals := make([]pairMoments, k*k)
+	nonNumeric := make([]int, k)
+	var totalsMutex sync.Mutex
+	err := df.runChunks(groupByChunk(df.numericIDs(cols)), func(_ int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		moments := make([]pairMoments, k*k)
+		counts := make([]int, k)
+		vals := make([]float64, k)
+		for _, row := range chunk.Rows {
+			numericRow(row, cols, vals, counts)
+			for i, x := range vals {
+				if math.IsNaN(x) {
+					continue
+				}
+				for j := i; j < k; j++ {
+					if y := vals[j]; !math.IsNaN(y) {
+						moments[i*k+j].add(x, y)
+					}
+				}
+			}
+		}
+
+		totalsMutex.Lock()
+		defer totalsMutex.Unlock()
+		for i := range moments {
+			totals[i].merge(&moments[i])
+		}
+		for i, n := range counts {
+			nonNumeric[i] += n
+		}
+		return nil
+	})
+	return totals, nonNumeric, err
+}
+
+// columnValues returns the values of each column in id order, NaN for nulls.
+// The caller must hold df.mutex.
+func (df *DataFrame) columnValues(cols []Field) ([][]float64, []int, error) {
+	tasks := groupByChunk(df.numericIDs(cols))
+	chunks := make([][][]float64, len(tasks))
+	counts := make([][]int, len(tasks))
+	err := df.runChunks(tasks, func(t int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		values := make([][]float64, len(cols))
+		for i := range values {
+			values[i] = make([]float64, len(chunk.Rows))
+		}
+		counts[t] = make([]int, len(cols))
+		vals := make([]float64, len(cols))
+		for r, row := range chunk.Rows {
+			numericRow(row, cols, vals, counts[t])
+			for i, x := range vals {
+				values[i][r] = x
+			}
+		}
+		chunks[t] = values
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([][]float64, len(cols))
+	nonNumeric := make([]int, len(cols))
+	for t := range chunks {
+		for i := range cols {
+			values[i] = append(values[i], chunks[t][i]...)
+			nonNumeric[i] += counts[t][i]
+		}
+	}
+	return values, nonNumeric, nil
+}
+
+// completePairs returns the values of x and y in the rows where both are non-null.
+func completePairs(x, y []float64) ([]float64, []float64) {
+	var cx, cy []float64
+	for r := range x {
+		if !math.IsNaN(x[r]) && !math.IsNaN(y[r]) {
+			cx = append(cx, x[r])
+			cy = append(cy, y[r])
+		}
+	}
+	return cx, cy
+}
+
+// ranks returns the rank of each value starting at 1, tied values sharing
+// the average of their ranks.
+func ranks(x []float64) []float64 {
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return x[order[a]] < x[order[b]] })
+
+	r := make([]float64, len(x))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && x[order[end]] == x[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for _, i := range order[start:end] {
+			r[i] = rank
+		}
+		start = end
+	}
+	return r
+}
+
+// spearman returns the Spearman rank correlation of x and y, or nil if it is undefined.
+func spearman(x, y []float64, minPeriods int, diagonal bool) interface{} {
+	var p pairMoments
+	rx, ry := ranks(x), ranks(y)
+	for i := range rx {
+		p.add(rx[i], ry[i])
+	}
+	return p.corr(minPeriods, diagonal)
+}
+
+// kendall returns Kendall's tau-b of x and y, or nil if it is undefined. Pairs
+// of rows are counted in O(n log n) by sorting on x and counting the swaps a
+// merge sort on y makes (Knight's algorithm).
+func kendall(x, y []float64, minPeriods int) interface{} {
+	n := len(x)
+	if n < minPeriods || n < 2 {
+		return nil
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		return x[i] < x[j] || x[i] == x[j] && y[i] < y[j]
+	})
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	for i, o := range order {
+		xs[i], ys[i] = x[o], y[o]
+	}
+
+	// Pairs tied on x, and pairs tied on both
+	var xTies, bothTies float64
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && xs[end] == xs[start] {
+			end++
+		}
+		xTies += ties(end - start)
+		for s := start; s < end; {
+			e := s + 1
+			for e < end && ys[e] == ys[s] {
+				e++
+			}
+			bothTies += ties(e - s)
+			s = e
+		}
+		start = end
+	}
+
+	discordant := float64(mergeCount(ys, make([]float64, n)))
+	var yTies float64
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && ys[end] == ys[start] {
+			end++
+		}
+		yTies += ties(end - start)
+		start = end
+	}
+
+	total := ties(n)
+	if total == xTies || total == yTies {
+		return nil
+	}
+	tau := (total - xTies - yTies + bothTies - 2*discordant) / math.Sqrt((total-xTies)*(total-yTies))
+	return math.Max(-1, math.Min(1, tau))
+}
+
+// ties returns the number of pairs among n tied values.
+func ties(n int) float64 {
+	return float64(n) * float64(n-1) / 2
+}
+
+// mergeCount sorts a with a merge sort and returns the number of pairs it had
+// out of order.
+func mergeCount(a, buf []float64) int {
+	if len(a) < 2 {
+		return 0
+	}
+	mid := len(a) / 2
+	swaps := mergeCount(a[:mid], buf[:mid]) + mergeCount(a[mid:], buf[mid:])
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(a) {
+		if a[j] < a[i] {
+			buf[k] = a[j]
+			swaps += mid - i
+			j++
+		} else {
+			buf[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(buf[k:], a[i:mid])
+	copy(buf[k:], a[j:])
+	copy(a, buf[:len(a)])
+	return swaps
+}
+
+// Corr returns the matrix of correlations between the numeric columns, with
+// one row and one column per numeric column, in the same order: row i holds
+// the correlations of the i-th column. Method is "pearson" (the default when
+// empty), "spearman" or "kendall". Each pair is computed over the rows where
+// both columns are non-null, and is null when there are fewer than the minimum
+// periods of them or a column is constant. Pearson correlations are computed
+// in a single pass that accumulates every pair at once. Spearman and Kendall
+// correlations are not: they rank the values of a column against all of its
+// others, so every numeric column is read into memory as float64s and each
+// pair of columns is then ranked and compared separately.
+func (df *DataFrame) Corr(method string, opts ...CorrOption) (*DataFrame, error) {
+	cfg := newCorrConfig(opts)
+	switch method {
+	case "":
+		method = "pearson"
+	case "pearson", "spearman", "kendall":
+	default:
+		return nil, fmt.Errorf("unknown correlation method %s", method)
+	}
+
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	cols := numericFields(df.fields)
+	k := len(cols)
+	matrix := make([][]interface{}, k)
+	for i := range matrix {
+		matrix[i] = make([]interface{}, k)
+	}
+	var nonNumeric []int
+
+	if method == "pearson" {
+		moments, counts, err := df.pairwiseMoments(cols)
+		if err != nil {
+			return nil, err
+		}
+		nonNumeric = counts
+		for i := 0; i < k; i++ {
+			for j := i; j < k; j++ {
+				matrix[i][j] = moments[i*k+j].corr(cfg.minPeriods, i == j)
+				matrix[j][i] = matrix[i][j]
+			}
+		}
+	} else {
+		values, counts, err := df.columnValues(cols)
+		if err != nil {
+			return nil, err
+		}
+		nonNumeric = counts
+		for i := 0; i < k; i++ {
+			for j := i; j < k; j++ {
+				x, y := completePairs(values[i], values[j])
+				if method == "spearman" {
+					matrix[i][j] = spearman(x, y, cfg.minPeriods, i == j)
+				} else {
+					matrix[i][j] = kendall(x, y, cfg.minPeriods)
+				}
+				matrix[j][i] = matrix[i][j]
+			}
+		}
+	}
+	return matrixFrame(df.Name+"_corr", cols, nonNumeric, matrix)
+}
+
+// Cov returns the matrix of sample covariances between the numeric columns,
+// laid out as by Corr. Each pair is computed over the rows where both columns
+// are non-null, in a single pass that accumulates every pair at once.
+func (df *DataFrame) Cov(opts ...CorrOption) (*DataFrame, error) {
+	cfg := newCorrConfig(opts)
+
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	cols := numericFields(df.fields)
+	k := len(cols)
+	moments, nonNumeric, err := df.pairwiseMoments(cols)
+	if err != nil {
+		return nil, err
+	}
+	matrix := make([][]interface{}, k)
+	for i := range matrix {
+		matrix[i] = make([]interface{}, k)
+		for j := range matrix[i] {
+			if i <= j {
+				matrix[i][j] = moments[i*k+j].cov(cfg.minPeriods)
+			} else {
+				matrix[i][j] = moments[j*k+i].cov(cfg.minPeriods)
+			}
+		}
+	}
+	return matrixFrame(df.Name+"_cov", cols, nonNumeric, matrix)
+}
+
+// matrixFrame builds the DataFrame of a matrix over the given columns, leaving
+// out interface{} columns that held values other than numbers.
+func matrixFrame(name string, cols []Field, nonNumeric []int, matrix [][]interface{}) (*DataFrame, error) {
+	var keep []int
+	for i, col := range cols {
+		if describeKind(col.Type) == describeNumeric || nonNumeric[i] == 0 {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("DataFrame has no numeric columns")
+	}
+
+	fields := make([]Field, len(keep))
+	for j, c := range keep {
+		fields[j] = Field{Name: cols[c].Name, Type: reflect.TypeOf(float64(0))}
+	}
+	ids := make([]int, len(keep))
+	rows := make([]Row, len(keep))
+	for i, r := range keep {
+		ids[i] = i
+		rows[i] = make(Row, len(keep))
+		for _, c := range keep {
+			rows[i][cols[c].Name] = matrix[r][c]
+		}
+	}
+	return newDataFrameFromRows(name, fields, ids, rows)
+}
+
+// ToMatrix returns the numeric columns as a matrix with one row per row in id
+// order, along with the names of the columns. Nulls become NaN. The result of
+// Corr or Cov can be passed on to viz.PlotCorrMat.
+func (df *DataFrame) ToMatrix() ([][]float64, []string, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	cols := numericFields(df.fields)
+	var rows [][]float64
+	nonNumeric := make([]int, len(cols))
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		vals := make([]float64, len(cols))
+		numericRow(row, cols, vals, nonNumeric)
+		rows = append(rows, vals)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keep []int
+	var columns []string
+	for i, col := range cols {
+		if describeKind(col.Type) == describeNumeric || nonNumeric[i] == 0 {
+			keep = append(keep, i)
+			columns = append(columns, col.Name)
+		}
+	}
+	if len(keep) == 0 {
+		return nil, nil, fmt.Errorf("DataFrame %s has no numeric columns", df.Name)
+	}
+	matrix := make([][]float64, len(rows))
+	for r, vals := range rows {
+		matrix[r] = make([]float64, len(keep))
+		for j, i := range keep {
+			matrix[r][j] = vals[i]
+		}
+	}
+	return matrix, columns, nil
+}
@@ -0,0 +1,125 @@
+package dataframe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// corrItems returns a frame whose rows lie in different chunks, so that the
+// moments of separate chunks are merged.
+func corrItems(t *testing.T) *DataFrame {
+	t.Helper()
+	floatType := reflect.TypeOf(0.0)
+	anyType := reflect.TypeOf((*interface{})(nil)).Elem()
+	fields := []Field{
+		{Name: "Name", Type: reflect.TypeOf("")},
+		{Name: "X", Type: reflect.TypeOf(0)},
+		{Name: "Y", Type: floatType},
+		{Name: "Z", Type: floatType},
+		{Name: "W", Type: reflect.TypeOf((*int)(nil))},
+		{Name: "C", Type: floatType},
+		{Name: "Mixed", Type: anyType},
+	}
+	w := func(n int) *int { return &n }
+	df, err := newDataFrameFromRows("items", fields, []int{0, 1000, 2000, 3000, 4000}, []Row{
+		{"Name": "a", "X": 1, "Y": 2.0, "Z": 5.0, "W": w(1), "C": 7.0, "Mixed": 1},
+		{"Name": "b", "X": 2, "Y": 4.0, "Z": 4.0, "W": nil, "C": 7.0, "Mixed": "x"},
+		{"Name": "c", "X": 3, "Y": 5.0, "Z": 3.0, "W": w(3), "C": 7.0, "Mixed": 2},
+		{"Name": "d", "X": 4, "Y": 4.0, "Z": 2.0, "W": nil, "C": 7.0, "Mixed": 3},
+		{"Name": "e", "X": 5, "Y": 5.0, "Z": 1.0, "W": w(2), "C": 7.0, "Mixed": 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(df.Close)
+	return df
+}
+
+// checkMatrix compares a matrix frame with the expected entries, keyed by the
+// names of their row and column. Entries not listed are not checked.
+func checkMatrix(t *testing.T, out *DataFrame, columns []string, want map[[2]string]interface{}) {
+	t.Helper()
+	if got := fieldNames(out.fields); !reflect.DeepEqual(got, columns) {
+		t.Fatalf("matrix columns = %v, want %v", got, columns)
+	}
+	rows := concatRows(t, out)
+	if len(rows) != len(columns) {
+		t.Fatalf("matrix has %d rows, want %d", len(rows), len(columns))
+	}
+	for pair, w := range want {
+		for _, p := range [][2]string{pair, {pair[1], pair[0]}} {
+			row := -1
+			for i, col := range columns {
+				if col == p[0] {
+					row = i
+				}
+			}
+			got := rows[row][p[1]]
+			if g, ok := got.(float64); ok {
+				if w, ok := w.(float64); ok && math.Abs(g-w) < 1e-9 {
+					continue
+				}
+			}
+			if got != w {
+				t.Errorf("entry %v = %v, want %v", p, got, w)
+			}
+		}
+	}
+}
+
+func TestCorr(t *testing.T) {
+	df := corrItems(t)
+	columns := []string{"X", "Y", "Z", "W", "C"}
+
+	tests := []struct {
+		method string
+		opts   []CorrOption
+		want   map[[2]string]interface{}
+	}{
+		{"", nil, map[[2]string]interface{}{
+			{"X", "X"}: 1.0, {"X", "Y"}: 6 / math.Sqrt(60), {"X", "Z"}: -1.0, {"X", "W"}: 0.5, {"X", "C"}: nil, {"C", "C"}: nil,
+		}},
+		{"spearman", nil, map[[2]string]interface{}{
+			{"X", "X"}: 1.0, {"X", "Y"}: 7 / math.Sqrt(90), {"X", "Z"}: -1.0, {"X", "W"}: 0.5, {"X", "C"}: nil,
+		}},
+		{"kendall", nil, map[[2]string]interface{}{
+			{"X", "X"}: 1.0, {"X", "Y"}: 6 / math.Sqrt(80), {"X", "Z"}: -1.0, {"Y", "Z"}: -6 / math.Sqrt(80), {"X", "W"}: 1.0 / 3, {"X", "C"}: nil,
+		}},
+		{"pearson", []CorrOption{WithMinPeriods(4)}, map[[2]string]interface{}{
+			{"X", "Y"}: 6 / math.Sqrt(60), {"X", "W"}: nil, {"W", "W"}: nil,
+		}},
+	}
+	for _, tt := range tests {
+		out, err := df.Corr(tt.method, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer out.Close()
+		checkMatrix(t, out, columns, tt.want)
+	}
+
+	if _, err := df.Corr("cosine"); err == nil {
+		t.Error("Corr with an unknown method succeeded")
+	}
+}
+
+func TestCov(t *testing.T) {
+	df := corrItems(t)
+	out, err := df.Cov()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	checkMatrix(t, out, []string{"X", "Y", "Z", "W", "C"}, map[[2]string]interface{}{
+		{"X", "X"}: 2.5, {"X", "Y"}: 1.5, {"Y", "Y"}: 1.5, {"X", "Z"}: -2.5, {"X", "W"}: 1.0, {"W", "W"}: 1.0, {"C", "C"}: 0.0, {"X", "C"}: 0.0,
+	})
+
+	matrix, names, err := out.ToMatrix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matrix) != len(names) || matrix[0][1] != 1.5 {
+		t.Errorf("ToMatrix of the covariances = %v %v", names, matrix)
+	}
+}
@@ -1,13 +1,17 @@
 package dataframe
 
 import (
+	"bytes"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"sync"
+	"time"
 	"github.com/aggnr/bluejay/db" // Import the db package
 )
 
@@ -18,25 +22,62 @@ const (
 	maxCacheSize   = 1 << 30 // 1GB
 )
 
+// Field describes a single column of a DataFrame.
+type Field struct {
+	Name string
+	Type reflect.Type
+}
+
 type DataFrame struct {
 	Name       string
 	StructType reflect.Type
-	Indexes    []*db.BPlusTree // Use multiple BPlusTrees
+	fields     []Field         // Columns in schema order
+	Indexes    []IDIndex // Use multiple BPlusTrees
 	mutex      sync.RWMutex
+	flushMutex sync.Mutex // Held while chunk files are written, taken before mutex
 	numTrees   int
 	chunkDir   string
+	store      ChunkStore // Where chunk files are kept, a DiskStore on chunkDir by default
 	chunkCount int
 	cache      map[int]map[int]interface{}
 	cacheSize  int
+	deleted    map[int]map[int]bool // Ids deleted since the last flush, by chunk
+	wal        *wal                 // Write-ahead log, nil for temporary frames
+	durable    bool                 // Chunk files outlive the frame
+
+	manifest    []byte      // Encoding of the manifest last persisted, for durable frames
+	codec       ChunkCodec  // Codec of the chunk files written
+	compression Compression // Compression of the chunk files written
+
+	indexPoolPages int // Pages cached per index shard with WithPagedIndex, 0 for in-memory shards
+
+	stats           map[int]*ChunkStats      // Statistics of the chunk files, by chunk
+	bloomColumns    []string                 // Columns with a Bloom filter in every chunk file
+	timeIndex       *timeIndex               // Ordered index of a time column, set by SetTimeIndex
+	categories      map[string]*categoryDict // Dictionaries of the categorical columns
+	savedCategories map[string]int           // Sizes of the dictionaries in the store, guarded by flushMutex
+
+	// Rows being written to chunk files by a flush that runs without the write lock
+	flushing     map[int]map[int]interface{}
+	flushDeleted map[int]map[int]bool
+
+	version   uint64               // Number of committed writes
+	snapshots map[*Snapshot]bool   // Open snapshots
+	history   map[int][]rowVersion // Prior versions of rows written while snapshots are open
+
+	mapMutex sync.Mutex           // Guards mapped, which readers fill under the read lock
+	mapped   map[int]*mappedChunk // Memory-mapped chunk files, by chunk
 }
 
 func init() {
 	gob.Register(&db.BPlusTree{})
 	gob.Register(&db.BPlusTreeNode{})
 	gob.Register(map[string]interface{}{})
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
 }
 
-func NewDataFrame(data interface{}) (*DataFrame, error) {
+func NewDataFrame(data interface{}, opts ...FrameOption) (*DataFrame, error) {
 	v := reflect.ValueOf(data)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
@@ -46,28 +87,63 @@ func NewDataFrame(data interface{}) (*DataFrame, error) {
 		return nil, fmt.Errorf("data slice is empty")
 	}
 
-	numTrees := int(float64(v.Len()) * treePercentage)
+	df, err := newDataFrame(v.Len(), "")
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(df)
+	}
+	if err := df.openPagedIndexes(); err != nil {
+		return nil, err
+	}
+
+	if err := df.FromStructs(data); err != nil {
+		return nil, err
+	}
+
+	return df, nil
+}
+
+// newDataFrame allocates an empty DataFrame sized for the expected number of rows,
+// storing its chunks in dir, or in a private temporary directory if dir is empty.
+func newDataFrame(size int, dir string) (*DataFrame, error) {
+	numTrees := int(float64(size) * treePercentage)
+	if chunks := (size + chunkSize - 1) / chunkSize; numTrees > chunks {
+		numTrees = chunks // A chunk never spans trees, so more trees than chunks stay empty
+	}
 	if numTrees == 0 {
 		numTrees = 1 // Ensure at least one tree
 	}
 
 	df := &DataFrame{
-		Indexes:  make([]*db.BPlusTree, numTrees), // Initialize multiple BPlusTrees
-		numTrees: numTrees,
-		chunkDir: chunkDir,
-		cache:    make(map[int]map[int]interface{}),
+		Indexes:    make([]IDIndex, numTrees), // Initialize multiple BPlusTrees
+		numTrees:   numTrees,
+		cache:      make(map[int]map[int]interface{}),
+		deleted:    make(map[int]map[int]bool),
+		chunkDir:   dir,
+		durable:    dir != "",
+		codec:      IndexedCodec{},
+		snapshots:  make(map[*Snapshot]bool),
+		history:    make(map[int][]rowVersion),
+		mapped:     make(map[int]*mappedChunk),
+		stats:      make(map[int]*ChunkStats),
+		categories: make(map[string]*categoryDict),
 	}
 
-	if err := df.createChunkDir(); err != nil {
+	if dir == "" {
+		if err := df.createChunkDir(); err != nil {
+			return nil, err
+		}
+	}
+	store, err := NewDiskStore(df.chunkDir)
+	if err != nil {
 		return nil, err
 	}
+	df.store = store
 
 	for i := 0; i < numTrees; i++ {
-		df.Indexes[i] = db.NewBPlusTree(v.Len())
-	}
-
-	if err := df.FromStructs(data); err != nil {
-		return nil, err
+		df.Indexes[i] = db.NewBPlusTree(size)
 	}
 
 	// Set a finalizer to ensure Close is called when df goes out of scope
@@ -78,17 +154,90 @@ func NewDataFrame(data interface{}) (*DataFrame, error) {
 	return df, nil
 }
 
-// createChunkDir ensures the chunk directory exists.
+// createChunkDir creates a chunk directory private to this DataFrame, so that
+// frames derived from one another never overwrite each other's chunk files.
 func (df *DataFrame) createChunkDir() error {
-	if _, err := os.Stat(df.chunkDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(df.chunkDir, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create chunk directory: %v", err)
-		}
+	if err := os.MkdirAll(chunkDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create chunk directory: %v", err)
+	}
+	dir, err := os.MkdirTemp(chunkDir, "frame_")
+	if err != nil {
+		return fmt.Errorf("failed to create chunk directory: %v", err)
 	}
+	df.chunkDir = dir
 	return nil
 }
 
-// FromStructs creates a DataFrame from a slice of structs.
+// newDataFrameFromRows builds a DataFrame with the given schema from column maps keyed by id.
+func newDataFrameFromRows(name string, fields []Field, ids []int, rows []Row) (*DataFrame, error) {
+	df, err := newDataFrame(len(ids), "")
+	if err != nil {
+		return nil, err
+	}
+	df.Name = name
+	df.fields = fields
+	df.insertRowMaps(ids, rows)
+	return df, nil
+}
+
+// derivedFrame returns an empty DataFrame for size rows with the given fields,
+// the values of fields[i] coming from column source[i] of df, or from no column
+// if it is empty. Like df, it writes its chunk files with the codec and
+// compression of df and keeps the Bloom filters of the columns it takes, along
+// with copies of their dictionaries, so that chunk files of df copied to it
+// stay readable. The caller must hold df.mutex.
+func (df *DataFrame) derivedFrame(size int, fields []Field, source []string) (*DataFrame, error) {
+	out, err := newDataFrame(size, "")
+	if err != nil {
+		return nil, err
+	}
+	out.Name = df.Name
+	out.fields = fields
+	out.codec = df.codec
+	out.compression = df.compression
+	for i, field := range fields {
+		if source[i] == "" {
+			continue
+		}
+		if contains(df.bloomColumns, source[i]) {
+			out.bloomColumns = append(out.bloomColumns, field.Name)
+		}
+		if dict, ok := df.categories[source[i]]; ok {
+			out.categories[field.Name] = dict.clone()
+		}
+	}
+	return out, nil
+}
+
+// derivedFrameFromRows builds a DataFrame derived from df as derivedFrame does
+// from column maps keyed by id. The caller must hold df.mutex.
+func (df *DataFrame) derivedFrameFromRows(fields []Field, source []string, ids []int, rows []Row) (*DataFrame, error) {
+	out, err := df.derivedFrame(len(ids), fields, source)
+	if err != nil {
+		return nil, err
+	}
+	out.insertRowMaps(ids, rows)
+	return out, nil
+}
+
+// insertRowMaps inserts column maps keyed by id.
+func (df *DataFrame) insertRowMaps(ids []int, rows []Row) {
+	values := make(map[int]interface{}, len(ids))
+	for i, id := range ids {
+		values[id] = map[string]interface{}(rows[i])
+	}
+	df.InsertRows(values)
+}
+
+// FromStructs creates a DataFrame from a slice of structs. Columns are mapped
+// from fields by their bluejay tags, falling back to json tags, and nested
+// structs are flattened into columns such as "Address.City":
+//
+//	Name  string `bluejay:"name"`             // Column "name"
+//	Note  string `bluejay:"note,omitempty"`    // Zero values stored as nil
+//	City  string `bluejay:"city,categorical"`  // Dictionary-encoded, as WithCategorical
+//	Email string `bluejay:"email,index"`       // Bloom filter per chunk, as WithBloomFilters
+//	Token string `bluejay:"-"`                 // Skipped
 func (df *DataFrame) FromStructs(data interface{}) error {
 	v := reflect.ValueOf(data)
 	if v.Kind() == reflect.Ptr {
@@ -107,147 +256,624 @@ func (df *DataFrame) FromStructs(data interface{}) error {
 
 	df.StructType = elemType
 	df.Name = elemType.Name()
-
-	rows := make(map[int]interface{})
-	for i := 0; i < v.Len(); i++ {
-		structVal := v.Index(i)
-		values := make(map[string]interface{})
-		for j := 0; j < structVal.NumField(); j++ {
-			values[structVal.Type().Field(j).Name] = structVal.Field(j).Interface()
+	cols := structColumns(elemType)
+	df.fields = make([]Field, len(cols))
+	for i, col := range cols {
+		df.fields[i] = Field{Name: col.name, Type: col.typ}
+		if col.indexed && !contains(df.bloomColumns, col.name) {
+			df.bloomColumns = append(df.bloomColumns, col.name)
 		}
-		rows[i] = values
+		if col.categorical && df.categories[col.name] == nil {
+			df.categories[col.name] = newCategoryDict()
+		}
+	}
+
+	ids := make([]int, v.Len())
+	for i := range ids {
+		ids[i] = i
 	}
 
-	df.InsertRows(rows)
+	df.mutex.Lock()
+	if err := df.saveHistory(ids); err != nil {
+		df.mutex.Unlock()
+		return err
+	}
+
+	// Structs are converted to column maps by the workers that store them
+	df.ingest(ids, func(i int) interface{} {
+		return structRow(v.Index(i), cols)
+	})
+	df.version++
+	df.mutex.Unlock()
+
+	// Bulk loads go straight to chunk files rather than through the log
+	if df.wal != nil {
+		return df.Checkpoint()
+	}
+	df.flushIfFull()
 	return nil
 }
 
-// InsertRows inserts multiple rows into the DataFrame concurrently.
+// InsertRows inserts multiple rows into the DataFrame in one batch, partitioned
+// by chunk across the worker pool. Readers see either none or all of the rows.
 func (df *DataFrame) InsertRows(rows map[int]interface{}) {
-	var wg sync.WaitGroup
+	ids := make([]int, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
 
-	for id, row := range rows {
-		wg.Add(1)
-		go func(id int, row interface{}) {
-			defer wg.Done()
-			df.InsertRow(id, row)
-		}(id, row)
+	defer df.flushIfFull()
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if df.wal != nil {
+		records := make([]walRecord, len(ids))
+		for i, id := range ids {
+			records[i] = walRecord{Op: walInsert, ID: id, Row: toRowMap(rows[id])}
+		}
+		if err := df.wal.append(records...); err != nil {
+			fmt.Printf("Error writing to log: %v\n", err)
+			return
+		}
+	}
+	if err := df.saveHistory(ids); err != nil {
+		fmt.Printf("Error inserting rows: %v\n", err)
+		return
 	}
-	wg.Wait()
+
+	df.ingest(ids, func(id int) interface{} {
+		return normalizeRow(rows[id])
+	})
+	df.version++
 }
 
 func (df *DataFrame) InsertRow(id int, row interface{}) {
+	defer df.flushIfFull()
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	row = normalizeRow(row)
+	if err := df.commit([]walRecord{{Op: walInsert, ID: id, Row: toRowMap(row)}}); err != nil {
+		fmt.Printf("Error inserting row: %v\n", err)
+	}
+}
+
+// UpdateRow sets the given columns of an existing row, leaving the others unchanged.
+func (df *DataFrame) UpdateRow(id int, newValues map[string]interface{}) error {
+	defer df.flushIfFull()
 	df.mutex.Lock()
 	defer df.mutex.Unlock()
 
+	row, err := df.readRow(id)
+	if err != nil {
+		return err
+	}
+
+	// The full row is logged so that replaying the record is idempotent
+	return df.commit([]walRecord{{Op: walUpdate, ID: id, Row: mergeRow(row, newValues)}})
+}
+
+// DeleteRow removes a row.
+func (df *DataFrame) DeleteRow(id int) error {
+	defer df.flushIfFull()
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if !df.hasRow(id) {
+		return fmt.Errorf("row with id %d not found", id)
+	}
+	return df.commit([]walRecord{{Op: walDelete, ID: id}})
+}
+
+// mergeRow returns a copy of row with the given columns set, so that a chunk
+// read from disk or shared with readers is not modified.
+func mergeRow(row interface{}, newValues map[string]interface{}) map[string]interface{} {
+	updated := make(map[string]interface{})
+	for col, val := range toRowMap(row) {
+		updated[col] = val
+	}
+	for col, val := range newValues {
+		updated[col] = val
+	}
+	return updated
+}
+
+// commit logs records as one batch and applies them as one write, so that both
+// recovery and readers see either all of them or none. The caller must hold the write lock.
+func (df *DataFrame) commit(records []walRecord) error {
+	if df.wal != nil {
+		if err := df.wal.append(records...); err != nil {
+			return fmt.Errorf("error writing to log: %v", err)
+		}
+	}
+	ids := make([]int, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	if err := df.saveHistory(ids); err != nil {
+		return err
+	}
+	df.apply(records)
+	df.version++
+	return nil
+}
+
+// apply applies logged records in order. The caller must hold the write lock.
+func (df *DataFrame) apply(records []walRecord) {
+	for _, record := range records {
+		switch record.Op {
+		case walInsert, walUpdate:
+			df.applyInsert(record.ID, record.Row)
+		case walDelete:
+			if df.hasRow(record.ID) {
+				df.applyDelete(record.ID)
+			}
+		}
+	}
+}
+
+// applyInsert stores a row in the cache and indexes it. The caller must hold the write lock.
+func (df *DataFrame) applyInsert(id int, row interface{}) {
 	chunkID := id / chunkSize
 	if df.cache[chunkID] == nil {
 		df.cache[chunkID] = make(map[int]interface{})
 	}
 
+	df.internCategories(row)
 	df.cache[chunkID][id] = row
 	df.cacheSize += int(reflect.TypeOf(row).Size())
 
-	if df.cacheSize >= maxCacheSize {
-		df.flushCache()
+	tree := df.Indexes[df.shardOf(chunkID)]
+	if !tree.Search(id) {
+		tree.Insert(id) // Insert the key into the appropriate BPlusTree
 	}
+	if df.timeIndex != nil {
+		df.timeIndex.add(id, row)
+	}
+}
 
-	treeIndex := id % df.numTrees
-	df.Indexes[treeIndex].Insert(id) // Insert the key into the appropriate BPlusTree
+// applyDelete drops a row from the cache and the index and records it for
+// removal from its chunk file on the next flush. The caller must hold the write lock.
+func (df *DataFrame) applyDelete(id int) {
+	chunkID := id / chunkSize
+	delete(df.cache[chunkID], id)
+	if df.deleted[chunkID] == nil {
+		df.deleted[chunkID] = make(map[int]bool)
+	}
+	df.deleted[chunkID][id] = true
+	df.Indexes[df.shardOf(chunkID)].Delete(id)
+	if df.timeIndex != nil {
+		df.timeIndex.remove(id)
+	}
+}
+
+// Columns returns the column names of the DataFrame in schema order.
+func (df *DataFrame) Columns() []string {
+	columns := make([]string, len(df.fields))
+	for i, field := range df.fields {
+		columns[i] = field.Name
+	}
+	return columns
+}
+
+// Schema returns the columns of the DataFrame along with their types.
+func (df *DataFrame) Schema() []Field {
+	return append([]Field(nil), df.fields...)
 }
 
 func (df *DataFrame) ReadRow(id int) (interface{}, error) {
 	df.mutex.RLock()
 	defer df.mutex.RUnlock()
+	return df.readRow(id)
+}
 
-	treeIndex := id % df.numTrees
-	if !df.Indexes[treeIndex].Search(id) {
+// readRow looks up a row by id. The caller must hold df.mutex.
+func (df *DataFrame) readRow(id int) (interface{}, error) {
+	if !df.hasRow(id) {
 		return nil, fmt.Errorf("row with id %d not found", id)
 	}
 
 	chunkID := id / chunkSize
-	if chunk, exists := df.cache[chunkID]; exists {
-		if row, exists := chunk[id]; exists {
-			return row, nil
-		}
+	if row, exists := df.cachedRow(id); exists {
+		return row, nil
 	}
 
-	chunkFile := filepath.Join(df.chunkDir, fmt.Sprintf("chunk_%d.gob", chunkID))
-	chunk, err := df.readChunk(chunkFile)
+	name := chunkName(chunkID)
+	data, err := df.chunkBytes(chunkID)
 	if err != nil {
-		return nil, fmt.Errorf("error reading chunk file %s: %v", chunkFile, err)
+		return nil, fmt.Errorf("error reading chunk file %s: %v", name, err)
+	}
+	row, exists, err := decodeChunkRow(data, id, df.categories)
+	if err != nil {
+		return nil, fmt.Errorf("error reading chunk file %s: %v", name, err)
 	}
-
-	row, exists := chunk[id]
 	if !exists {
 		return nil, fmt.Errorf("row with id %d not found", id)
 	}
 	return row, nil
 }
 
-func (df *DataFrame) flushCache() {
-	for chunkID, chunk := range df.cache {
-		chunkFile := filepath.Join(df.chunkDir, fmt.Sprintf("chunk_%d.gob", chunkID))
-		existingChunk, err := df.readChunk(chunkFile)
-		if err == nil {
-			for id, row := range chunk {
-				existingChunk[id] = row
+// cachedRow looks up a row not yet written to its chunk file. The caller must hold df.mutex.
+func (df *DataFrame) cachedRow(id int) (interface{}, bool) {
+	chunkID := id / chunkSize
+	if row, exists := df.cache[chunkID][id]; exists {
+		return row, true
+	}
+	row, exists := df.flushing[chunkID][id]
+	return row, exists
+}
+
+// rowIDs returns the ids of all rows in ascending order. The caller must hold df.mutex.
+func (df *DataFrame) rowIDs() []int {
+	var ids []int
+	for _, tree := range df.Indexes {
+		ids = append(ids, tree.Keys()...)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// scanRows calls fn for every row in id order, decoding each chunk file at most once.
+// Rows are passed as column maps. The caller must hold df.mutex.
+func (df *DataFrame) scanRows(fn func(id int, row map[string]interface{}) error) error {
+	return df.scanIDs(df.rowIDs(), fn)
+}
+
+// scanIDs calls fn for each of the given sorted ids that has a row, in order.
+// Chunks are loaded a window at a time on the worker pool. The caller must hold df.mutex.
+func (df *DataFrame) scanIDs(ids []int, fn func(id int, row map[string]interface{}) error) error {
+	tasks := groupByChunk(ids)
+	window := 2 * workerCount()
+	for start := 0; start < len(tasks); start += window {
+		end := start + window
+		if end > len(tasks) {
+			end = len(tasks)
+		}
+		chunks := make([]Chunk, end-start)
+		err := df.runChunks(tasks[start:end], func(i int, task chunkTask) error {
+			var err error
+			chunks[i], err = df.loadChunk(task)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			for i, id := range chunk.IDs {
+				if err := fn(id, chunk.Rows[i]); err != nil {
+					return err
+				}
 			}
-		} else {
-			existingChunk = chunk
 		}
-		df.writeChunk(chunkFile, existingChunk)
 	}
+	return nil
+}
+
+// idRange returns the sorted ids of all rows with lo <= id <= hi. The caller must hold df.mutex.
+func (df *DataFrame) idRange(lo, hi int) []int {
+	var ids []int
+	if lo >= 0 && hi-lo < df.numTrees*chunkSize {
+		// Only the shards of the chunks overlapping the range can hold matches
+		seen := make(map[int]bool)
+		for chunkID := lo / chunkSize; chunkID <= hi/chunkSize; chunkID++ {
+			if shard := df.shardOf(chunkID); !seen[shard] {
+				seen[shard] = true
+				ids = append(ids, df.Indexes[shard].Range(lo, hi)...)
+			}
+		}
+	} else {
+		for _, tree := range df.Indexes {
+			ids = append(ids, tree.Range(lo, hi)...)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// hasRow reports whether a row with the given id exists. The caller must hold df.mutex.
+func (df *DataFrame) hasRow(id int) bool {
+	if id < 0 {
+		return false
+	}
+	return df.Indexes[df.shardOf(id/chunkSize)].Search(id)
+}
+
+// ToFloat64 converts a numeric value to float64.
+func ToFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// normalizeRow stores struct rows as column maps, so that chunk files and the
+// log can encode them without registering every struct type with gob.
+func normalizeRow(row interface{}) interface{} {
+	if values := toRowMap(row); values != nil {
+		return values
+	}
+	return row
+}
+
+// toRowMap converts a stored row, either a column map or a struct, into a column map.
+func toRowMap(row interface{}) map[string]interface{} {
+	if values, ok := row.(map[string]interface{}); ok {
+		return values
+	}
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return structRow(v, structColumns(v.Type()))
+}
+
+// chunkName returns the name of the chunk file of a chunk in the store.
+func chunkName(chunkID int) string {
+	return fmt.Sprintf("chunk_%d.gob", chunkID)
+}
+
+// readChunkFile decodes the chunk file of a chunk.
+func (df *DataFrame) readChunkFile(chunkID int) (map[int]interface{}, error) {
+	name := chunkName(chunkID)
+	data, err := df.chunkBytes(chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading chunk file %s: %v", name, err)
+	}
+	chunk, err := decodeChunk(data, df.categories)
+	if err != nil {
+		return nil, fmt.Errorf("error reading chunk file %s: %v", name, err)
+	}
+	return chunk, nil
+}
+
+// flushIfFull flushes the cache once it outgrows maxCacheSize. The chunk files
+// are written without holding the write lock, so readers and writers are not
+// held up by the disk. The caller must not hold df.mutex.
+func (df *DataFrame) flushIfFull() {
+	df.mutex.RLock()
+	full := df.cacheSize >= maxCacheSize
+	df.mutex.RUnlock()
+
+	if full {
+		if err := df.flush(); err != nil {
+			fmt.Printf("Error flushing cache: %v\n", err)
+		}
+	}
+}
+
+// flush moves the cache aside and writes it to the chunk files while reads and
+// writes continue against a fresh cache. On error the rows are moved back.
+func (df *DataFrame) flush() error {
+	df.flushMutex.Lock()
+	defer df.flushMutex.Unlock()
+
+	df.mutex.Lock()
+	df.flushing, df.flushDeleted = df.cache, df.deleted
 	df.cache = make(map[int]map[int]interface{})
+	df.deleted = make(map[int]map[int]bool)
+	size := df.cacheSize
 	df.cacheSize = 0
-}
+	df.mutex.Unlock()
 
-func (df *DataFrame) writeChunk(filename string, chunk map[int]interface{}) error {
-	file, err := os.Create(filename)
+	stats, err := df.writeChunks(df.flushing, df.flushDeleted)
+
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	df.unmapChunks(df.flushing, df.flushDeleted)
+	df.setStats(stats)
 	if err != nil {
-		return err
+		// Rows and deletes made during the flush are newer than the ones being moved back
+		for chunkID, rows := range df.flushing {
+			for id, row := range rows {
+				if _, ok := df.cache[chunkID][id]; ok || df.deleted[chunkID][id] {
+					continue
+				}
+				if df.cache[chunkID] == nil {
+					df.cache[chunkID] = make(map[int]interface{})
+				}
+				df.cache[chunkID][id] = row
+			}
+		}
+		for chunkID, ids := range df.flushDeleted {
+			for id := range ids {
+				if _, ok := df.cache[chunkID][id]; ok {
+					continue
+				}
+				if df.deleted[chunkID] == nil {
+					df.deleted[chunkID] = make(map[int]bool)
+				}
+				df.deleted[chunkID][id] = true
+			}
+		}
+		df.cacheSize += size
 	}
-	defer file.Close()
+	df.flushing, df.flushDeleted = nil, nil
+	return err
+}
 
-	encoder := gob.NewEncoder(file)
-	if err := encoder.Encode(chunk); err != nil {
+// flushCache writes the cache to the chunk files while holding the write lock.
+// On error the cache is kept. The caller must hold df.flushMutex and the write lock.
+func (df *DataFrame) flushCache() error {
+	stats, err := df.writeChunks(df.cache, df.deleted)
+	df.unmapChunks(df.cache, df.deleted)
+	df.setStats(stats)
+	if err != nil {
 		return err
 	}
+	df.cache = make(map[int]map[int]interface{})
+	df.deleted = make(map[int]map[int]bool)
+	df.cacheSize = 0
 	return nil
 }
 
-func (df *DataFrame) readChunk(filename string) (map[int]interface{}, error) {
-	file, err := os.Open(filename)
-	if err != nil {
+// writeChunks merges rows and deletes into the chunk files. Each chunk file is
+// replaced atomically, so a crash or a concurrent reader sees either the old or
+// the new version of it. It returns the statistics of the chunk files written,
+// nil for the ones removed, even on error. The caller must hold df.flushMutex.
+func (df *DataFrame) writeChunks(cache map[int]map[int]interface{}, deleted map[int]map[int]bool) (map[int]*ChunkStats, error) {
+	var tasks []chunkTask
+	for chunkID := range cache {
+		tasks = append(tasks, chunkTask{chunkID: chunkID})
+	}
+	for chunkID := range deleted {
+		if _, ok := cache[chunkID]; !ok {
+			tasks = append(tasks, chunkTask{chunkID: chunkID})
+		}
+	}
+	if err := df.saveCategories(); err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	var chunk map[int]interface{}
-	decoder := gob.NewDecoder(file)
-	if err := decoder.Decode(&chunk); err != nil {
+	var statsMutex sync.Mutex
+	written := make(map[int]*ChunkStats, len(tasks))
+	err := df.runChunks(tasks, func(_ int, task chunkTask) error {
+		name := chunkName(task.chunkID)
+		existingChunk, err := df.readChunk(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			existingChunk = make(map[int]interface{})
+		} else if err != nil {
+			return fmt.Errorf("error reading chunk file %s: %v", name, err)
+		}
+		for id := range deleted[task.chunkID] {
+			delete(existingChunk, id)
+		}
+		for id, row := range cache[task.chunkID] {
+			existingChunk[id] = row
+		}
+
+		var stats *ChunkStats
+		if len(existingChunk) == 0 {
+			if err := df.store.Delete(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		} else {
+			stats = computeChunkStats(task.chunkID, existingChunk, df.bloomColumns)
+			if err := df.writeChunk(name, existingChunk, stats); err != nil {
+				return fmt.Errorf("error writing chunk file %s: %v", name, err)
+			}
+		}
+
+		statsMutex.Lock()
+		written[task.chunkID] = stats
+		statsMutex.Unlock()
+		return nil
+	})
+	return written, err
+}
+
+// setStats installs the statistics of chunk files written by writeChunks. The
+// caller must hold the write lock.
+func (df *DataFrame) setStats(stats map[int]*ChunkStats) {
+	for chunkID, s := range stats {
+		if s == nil {
+			delete(df.stats, chunkID)
+		} else {
+			df.stats[chunkID] = s
+		}
+	}
+}
+
+// writeChunk encodes a chunk and its statistics with the codec of the DataFrame
+// and puts it in the store. Categorical columns are stored as codes into the
+// frame dictionaries.
+func (df *DataFrame) writeChunk(name string, chunk map[int]interface{}, stats *ChunkStats) error {
+	encodedStats, err := encodeChunkStats(stats)
+	if err != nil {
+		return err
+	}
+	chunk, coded := encodeCategoryCodes(chunk, df.categories)
+	var buf bytes.Buffer
+	if err := encodeChunk(&buf, df.codec, df.compression, chunk, encodedStats, coded); err != nil {
+		return err
+	}
+	return df.store.Put(name, buf.Bytes())
+}
+
+// readChunk decodes a chunk file with the codec named in its header.
+func (df *DataFrame) readChunk(name string) (map[int]interface{}, error) {
+	data, err := df.store.Get(name)
+	if err != nil {
 		return nil, err
 	}
-	return chunk, nil
+	return decodeChunk(data, df.categories)
 }
 
-// Close deletes all the disk caches and writes B+Trees to the data directory.
+// Close deletes the disk caches of the DataFrame. Durable frames opened with
+// CreateDataFrame or OpenDataFrame are checkpointed and kept on disk instead.
 func (df *DataFrame) Close() {
+	df.flushMutex.Lock()
+	defer df.flushMutex.Unlock()
 	df.mutex.Lock()
 	defer df.mutex.Unlock()
+	defer df.unmapAll()
+
+	if df.durable {
+		if df.wal == nil {
+			return // Already closed
+		}
+		if err := df.checkpoint(); err != nil {
+			fmt.Printf("Error checkpointing %s: %v\n", df.chunkDir, err)
+			return
+		}
+		if err := df.wal.close(); err != nil {
+			fmt.Printf("Error closing log: %v\n", err)
+		}
+		df.wal = nil
+		df.closeIndexes()
+		return
+	}
+	df.closeIndexes()
 
 	// Remove all chunk files
+	if _, ok := df.store.(*DiskStore); !ok {
+		names, err := df.store.List()
+		if err != nil {
+			fmt.Printf("Error listing chunk files: %v\n", err)
+		}
+		for _, name := range names {
+			if _, ok := parseChunkName(name); ok {
+				if err := df.store.Delete(name); err != nil {
+					fmt.Printf("Error deleting chunk file %s: %v\n", name, err)
+				}
+			}
+		}
+	}
 	err := os.RemoveAll(df.chunkDir)
 	if err != nil {
 		fmt.Printf("Error deleting chunk directory %s: %v\n", df.chunkDir, err)
 	}
 
-	// Recreate the chunk directory
-	if err := os.MkdirAll(df.chunkDir, os.ModePerm); err != nil {
-		fmt.Printf("Failed to recreate chunk directory: %v\n", err)
-		return
-	}
-
 	//// Write each B+Tree to a file in the data directory
 	//for i, tree := range df.Indexes {
 	//	treeFile := filepath.Join(df.chunkDir, fmt.Sprintf("bplustree_%d.gob", i))
@@ -0,0 +1,388 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// exactQuantileLimit is the number of values of a column up to which Describe
+// computes exact percentiles. Larger columns are summarized by a t-digest.
+const exactQuantileLimit = 10000
+
+// Kinds of columns summarized by Describe.
+const (
+	describeAuto        = iota // interface{} columns, numeric if all their values are
+	describeNumeric            // count, mean, std, min, percentiles, max
+	describeCategorical        // count, unique, top, freq
+)
+
+// describeKind returns how Describe summarizes a column of the given type.
+func describeKind(t reflect.Type) int {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return describeAuto
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return describeNumeric
+	case reflect.Interface:
+		return describeAuto
+	}
+	return describeCategorical
+}
+
+// numericValue converts a value of any numeric type, including named ones, to float64.
+func numericValue(val interface{}) (float64, bool) {
+	if f, ok := ToFloat64(val); ok {
+		return f, true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// numericSummary accumulates the statistics of numeric values. Summaries of
+// separate chunks merge into the summary of all of them.
+type numericSummary struct {
+	count    int
+	mean, m2 float64 // Running mean and sum of squared deviations (Welford)
+	min, max float64
+	values   []float64 // Every value, until there are more than exactQuantileLimit
+	digest   *tdigest  // Sketch of the values once there are too many to keep
+}
+
+func newNumericSummary() *numericSummary {
+	return &numericSummary{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (s *numericSummary) add(x float64) {
+	s.count++
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+	s.min = math.Min(s.min, x)
+	s.max = math.Max(s.max, x)
+	if s.digest != nil {
+		s.digest.add(x)
+		return
+	}
+	s.values = append(s.values, x)
+	if len(s.values) > exactQuantileLimit {
+		s.toDigest()
+	}
+}
+
+// toDigest moves the values kept so far into a t-digest.
+func (s *numericSummary) toDigest() {
+	if s.digest == nil {
+		s.digest = newTDigest()
+	}
+	for _, x := range s.values {
+		s.digest.add(x)
+	}
+	s.values = nil
+}
+
+func (s *numericSummary) merge(o *numericSummary) {
+	if o.count == 0 {
+		return
+	}
+	n := float64(s.count + o.count)
+	delta := o.mean - s.mean
+	s.m2 += o.m2 + delta*delta*float64(s.count)*float64(o.count)/n
+	s.mean += delta * float64(o.count) / n
+	s.count += o.count
+	s.min = math.Min(s.min, o.min)
+	s.max = math.Max(s.max, o.max)
+
+	if s.digest == nil && o.digest == nil && len(s.values)+len(o.values) <= exactQuantileLimit {
+		s.values = append(s.values, o.values...)
+		return
+	}
+	s.toDigest()
+	for _, x := range o.values {
+		s.digest.add(x)
+	}
+	if o.digest != nil {
+		s.digest.merge(o.digest)
+	}
+}
+
+// quantile returns the value at quantile q, interpolating linearly between the
+// closest values when they are all kept.
+func (s *numericSummary) quantile(q float64) float64 {
+	if s.digest != nil {
+		return s.digest.quantile(q)
+	}
+	if !sort.Float64sAreSorted(s.values) {
+		sort.Float64s(s.values)
+	}
+	pos := q * float64(len(s.values)-1)
+	lo := int(math.Floor(pos))
+	if lo+1 >= len(s.values) {
+		return s.values[len(s.values)-1]
+	}
+	return s.values[lo] + (s.values[lo+1]-s.values[lo])*(pos-float64(lo))
+}
+
+// categoricalSummary counts the occurrences of each value.
+type categoricalSummary struct {
+	count  int
+	counts map[interface{}]int
+}
+
+func newCategoricalSummary() *categoricalSummary {
+	return &categoricalSummary{counts: make(map[interface{}]int)}
+}
+
+func (s *categoricalSummary) add(val interface{}) {
+	s.count++
+	if !reflect.TypeOf(val).Comparable() {
+		val = fmt.Sprint(val)
+	}
+	s.counts[val]++
+}
+
+func (s *categoricalSummary) merge(o *categoricalSummary) {
+	s.count += o.count
+	for val, n := range o.counts {
+		s.counts[val] += n
+	}
+}
+
+// top returns the most frequent value and its count. Ties go to the smallest value.
+func (s *categoricalSummary) top() (interface{}, int) {
+	var best interface{}
+	freq := 0
+	for val, n := range s.counts {
+		if n > freq || n == freq && lessValue(val, best) {
+			best, freq = val, n
+		}
+	}
+	return best, freq
+}
+
+// lessValue orders values with compareValues, falling back to their formatting.
+func lessValue(a, b interface{}) bool {
+	if cmp, ok := compareValues(a, b); ok {
+		return cmp < 0
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// columnSummary accumulates the statistics of one column.
+type columnSummary struct {
+	kind       int
+	numeric    *numericSummary
+	cat        *categoricalSummary
+	nonNumeric int // Values of an auto column that are not numbers
+}
+
+func newColumnSummary(kind int) *columnSummary {
+	return &columnSummary{kind: kind, numeric: newNumericSummary(), cat: newCategoricalSummary()}
+}
+
+func (s *columnSummary) add(val interface{}) {
+	val, ok := statValue(val)
+	if !ok {
+		return
+	}
+	if s.kind != describeCategorical {
+		if x, ok := numericValue(val); ok {
+			if !math.IsNaN(x) {
+				s.numeric.add(x)
+			}
+		} else {
+			s.nonNumeric++
+		}
+	}
+	if s.kind != describeNumeric {
+		if x, ok := val.(float64); !ok || !math.IsNaN(x) {
+			s.cat.add(val)
+		}
+	}
+}
+
+func (s *columnSummary) merge(o *columnSummary) {
+	s.numeric.merge(o.numeric)
+	s.cat.merge(o.cat)
+	s.nonNumeric += o.nonNumeric
+}
+
+// isNumeric reports whether the column is summarized as numeric.
+func (s *columnSummary) isNumeric() bool {
+	if s.kind == describeAuto {
+		return s.nonNumeric == 0 && s.numeric.count > 0
+	}
+	return s.kind == describeNumeric
+}
+
+// stat returns the value of a statistic of the column, or nil if it does not
+// apply. Percentile statistics are keyed by their label.
+func (s *columnSummary) stat(name string, percentiles map[string]float64) interface{} {
+	if !s.isNumeric() {
+		switch name {
+		case "count":
+			return s.cat.count
+		case "unique":
+			return len(s.cat.counts)
+		case "top":
+			top, _ := s.cat.top()
+			return top
+		case "freq":
+			if _, freq := s.cat.top(); freq > 0 {
+				return freq
+			}
+		}
+		return nil
+	}
+
+	n := s.numeric
+	if name == "count" {
+		return float64(n.count)
+	}
+	if n.count == 0 {
+		return nil
+	}
+	switch name {
+	case "mean":
+		return n.mean
+	case "std":
+		if n.count < 2 {
+			return nil
+		}
+		return math.Sqrt(n.m2 / float64(n.count-1))
+	case "min":
+		return n.min
+	case "max":
+		return n.max
+	}
+	if p, ok := percentiles[name]; ok {
+		return n.quantile(p)
+	}
+	return nil
+}
+
+// Describe summarizes every column in one streaming pass over the chunks. The
+// result has a "stat" column naming each statistic and one column per column of
+// df. Numeric columns get count, mean, std, min, the given percentiles and max;
+// other columns get count, unique, top and freq. Percentiles are fractions
+// in [0, 1] and default to the quartiles. They are exact for up to 10000 values
+// per column and estimated with a t-digest beyond that.
+func (df *DataFrame) Describe(percentiles ...float64) (*DataFrame, error) {
+	if len(percentiles) == 0 {
+		percentiles = []float64{0.25, 0.5, 0.75}
+	}
+	percentiles = append([]float64(nil), percentiles...)
+	sort.Float64s(percentiles)
+	labels := make(map[string]float64, len(percentiles))
+	var percentileLabels []string
+	for _, p := range percentiles {
+		if p < 0 || p > 1 || math.IsNaN(p) {
+			return nil, fmt.Errorf("percentile %v is not between 0 and 1", p)
+		}
+		label := strconv.FormatFloat(p*100, 'f', -1, 64) + "%"
+		if _, ok := labels[label]; !ok {
+			labels[label] = p
+			percentileLabels = append(percentileLabels, label)
+		}
+	}
+
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if len(df.fields) == 0 {
+		return nil, fmt.Errorf("DataFrame %s has no columns", df.Name)
+	}
+	if hasField(df.fields, "stat") {
+		return nil, fmt.Errorf("column stat clashes with the statistic names of Describe")
+	}
+	newSummaries := func() []*columnSummary {
+		summaries := make([]*columnSummary, len(df.fields))
+		for i, field := range df.fields {
+			summaries[i] = newColumnSummary(describeKind(field.Type))
+		}
+		return summaries
+	}
+
+	// Each chunk is summarized on its own and merged into the totals, so only
+	// the summaries are held in memory
+	totals := newSummaries()
+	var totalsMutex sync.Mutex
+	err := df.runChunks(groupByChunk(df.rowIDs()), func(_ int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		summaries := newSummaries()
+		for _, row := range chunk.Rows {
+			for i, field := range df.fields {
+				summaries[i].add(row[field.Name])
+			}
+		}
+
+		totalsMutex.Lock()
+		defer totalsMutex.Unlock()
+		for i, s := range summaries {
+			totals[i].merge(s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var hasNumeric, hasCategorical bool
+	for _, s := range totals {
+		if s.isNumeric() {
+			hasNumeric = true
+		} else {
+			hasCategorical = true
+		}
+	}
+	stats := []string{"count"}
+	if hasCategorical {
+		stats = append(stats, "unique", "top", "freq")
+	}
+	if hasNumeric {
+		stats = append(stats, "mean", "std", "min")
+		stats = append(stats, percentileLabels...)
+		stats = append(stats, "max")
+	}
+
+	anyType := reflect.TypeOf((*interface{})(nil)).Elem()
+	fields := []Field{{Name: "stat", Type: reflect.TypeOf("")}}
+	for i, field := range df.fields {
+		t := anyType
+		if totals[i].isNumeric() {
+			t = reflect.TypeOf(float64(0))
+		}
+		fields = append(fields, Field{Name: field.Name, Type: t})
+	}
+
+	ids := make([]int, len(stats))
+	rows := make([]Row, len(stats))
+	for i, stat := range stats {
+		ids[i] = i
+		rows[i] = Row{"stat": stat}
+		for j, field := range df.fields {
+			rows[i][field.Name] = totals[j].stat(stat, labels)
+		}
+	}
+	return newDataFrameFromRows(df.Name+"_describe", fields, ids, rows)
+}
@@ -0,0 +1,121 @@
+package dataframe
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// describeStats returns the statistics of a Describe result by name and column.
+func describeStats(t *testing.T, out *DataFrame) ([]string, map[string]map[string]interface{}) {
+	t.Helper()
+	rows := concatRows(t, out)
+	names := make([]string, len(rows))
+	stats := make(map[string]map[string]interface{}, len(rows))
+	for id, row := range rows {
+		name := row["stat"].(string)
+		names[id] = name
+		stats[name] = row
+	}
+	return names, stats
+}
+
+func TestDescribe(t *testing.T) {
+	anyType := reflect.TypeOf((*interface{})(nil)).Elem()
+	fields := []Field{
+		{Name: "Name", Type: reflect.TypeOf("")},
+		{Name: "Qty", Type: reflect.TypeOf(0)},
+		{Name: "Price", Type: reflect.TypeOf((*float64)(nil))},
+		{Name: "Mixed", Type: anyType},
+		{Name: "Numbers", Type: anyType},
+	}
+	price := func(f float64) *float64 { return &f }
+	// Rows lie in different chunks, so their summaries are merged
+	df, err := newDataFrameFromRows("items", fields, []int{0, 1, 1500, 2500, 2501}, []Row{
+		{"Name": "pen", "Qty": 4, "Price": price(2.5), "Mixed": 1, "Numbers": 1},
+		{"Name": "ink", "Qty": 1, "Price": nil, "Mixed": "x", "Numbers": 2.5},
+		{"Name": "pen", "Qty": 5, "Price": price(math.NaN()), "Mixed": nil, "Numbers": int64(3)},
+		{"Name": "cap", "Qty": 2, "Price": price(4.5), "Mixed": "x", "Numbers": uint8(4)},
+		{"Name": "ink", "Qty": 3, "Price": price(0.5), "Mixed": 2, "Numbers": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	out, err := df.Describe(0.1, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	names, stats := describeStats(t, out)
+	wantNames := []string{"count", "unique", "top", "freq", "mean", "std", "min", "10%", "50%", "max"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("statistics = %v, want %v", names, wantNames)
+	}
+
+	tests := []struct {
+		column string
+		want   map[string]interface{}
+	}{
+		{"Name", map[string]interface{}{"count": 5, "unique": 3, "top": "ink", "freq": 2}},
+		{"Qty", map[string]interface{}{"count": 5.0, "mean": 3.0, "std": math.Sqrt(2.5), "min": 1.0, "10%": 1.4, "50%": 3.0, "max": 5.0}},
+		{"Price", map[string]interface{}{"count": 3.0, "mean": 2.5, "std": 2.0, "min": 0.5, "10%": 0.9, "50%": 2.5, "max": 4.5}},
+		{"Mixed", map[string]interface{}{"count": 4, "unique": 3, "top": "x", "freq": 2}},
+		{"Numbers", map[string]interface{}{"count": 4.0, "mean": 2.625, "std": math.Sqrt(4.6875 / 3), "min": 1.0, "10%": 1.45, "50%": 2.75, "max": 4.0}},
+	}
+	for _, tt := range tests {
+		for _, name := range wantNames {
+			got, want := stats[name][tt.column], tt.want[name]
+			if g, ok := got.(float64); ok {
+				if w, ok := want.(float64); ok && math.Abs(g-w) < 1e-9 {
+					continue
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s of %s = %v, want %v", name, tt.column, got, want)
+			}
+		}
+	}
+
+	for _, p := range []float64{-0.1, 1.5, math.NaN()} {
+		if _, err := df.Describe(p); err == nil {
+			t.Errorf("Describe(%v) succeeded", p)
+		}
+	}
+}
+
+func TestDescribeEstimatedPercentiles(t *testing.T) {
+	const n = 3 * exactQuantileLimit
+	fields := []Field{{Name: "Qty", Type: reflect.TypeOf(0)}}
+	ids := make([]int, n)
+	rows := make([]Row, n)
+	for i, v := range rand.New(rand.NewSource(1)).Perm(n) {
+		ids[i], rows[i] = i, Row{"Qty": v}
+	}
+	df, err := newDataFrameFromRows("items", fields, ids, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	out, err := df.Describe(0.01, 0.5, 0.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	_, stats := describeStats(t, out)
+	if got := stats["mean"]["Qty"].(float64); math.Abs(got-(n-1)/2.0) > 1e-6 {
+		t.Errorf("mean = %v, want %v", got, (n-1)/2.0)
+	}
+	if got := stats["min"]["Qty"]; got != 0.0 {
+		t.Errorf("min = %v, want 0", got)
+	}
+	for label, p := range map[string]float64{"1%": 0.01, "50%": 0.5, "99%": 0.99} {
+		want := p * (n - 1)
+		if got := stats[label]["Qty"].(float64); math.Abs(got-want) > 0.005*n {
+			t.Errorf("%s = %v, want about %v", label, got, want)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package dataframe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+const (
+	maxDistinctKeys = 1 << 20 // Distinct keys held in memory before spilling to disk
+	spillPartitions = 64      // Files distinct keys are spilled to, by hash
+)
+
+// DropDuplicates returns a copy of the DataFrame without the rows that repeat
+// the values of the subset columns, or of every column if subset is empty.
+// keep is "first" (or "") to keep the first of the repeated rows in id order,
+// "last" to keep the last, and "none" to drop them all.
+func (df *DataFrame) DropDuplicates(subset []string, keep string) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	ids, err := df.keptIDs(subset, keep)
+	if err != nil {
+		return nil, err
+	}
+	return df.copyFrame(ids, nil)
+}
+
+// Duplicated reports for every row id whether the row repeats the values of
+// the subset columns, or of every column if subset is empty, of a row that
+// DropDuplicates would keep with the same keep.
+func (df *DataFrame) Duplicated(subset []string, keep string) (map[int]bool, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	ids, err := df.keptIDs(subset, keep)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		kept[id] = true
+	}
+	duplicated := make(map[int]bool)
+	for _, id := range df.rowIDs() {
+		duplicated[id] = !kept[id]
+	}
+	return duplicated, nil
+}
+
+// Unique returns the distinct values of a column, nil included, in the order
+// they first appear.
+func (df *DataFrame) Unique(column string) ([]interface{}, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	states, err := df.distinctStates(column, false)
+	if err != nil {
+		return nil, err
+	}
+	values, err := df.distinctValues(column, states)
+	if err != nil {
+		return nil, err
+	}
+	unique := make([]interface{}, len(states))
+	for i, s := range states {
+		unique[i] = values[s.first]
+	}
+	return unique, nil
+}
+
+// NUnique returns the number of distinct non-null values of a column.
+func (df *DataFrame) NUnique(column string) (int, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if !hasField(df.fields, column) {
+		return 0, fmt.Errorf("column %s not found", column)
+	}
+	n := 0
+	err := df.scanDistinct([]string{column}, true, func(distinctState) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// ValueCounts returns a DataFrame with a row for every distinct non-null value
+// of a column, holding the value and the number of rows with it in a "count"
+// column, or their fraction of the non-null rows in a "proportion" column if
+// normalize is set. Rows are ordered by decreasing count, then by first appearance.
+func (df *DataFrame) ValueCounts(column string, normalize bool) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	states, err := df.distinctStates(column, true)
+	if err != nil {
+		return nil, err
+	}
+	values, err := df.distinctValues(column, states)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].count > states[j].count
+	})
+
+	total := 0
+	for _, s := range states {
+		total += s.count
+	}
+	fields := pickFields(df.fields, []string{column})
+	if normalize {
+		fields = append(fields, Field{Name: "proportion", Type: reflect.TypeOf(0.0)})
+	} else {
+		fields = append(fields, Field{Name: "count", Type: reflect.TypeOf(0)})
+	}
+	ids := make([]int, len(states))
+	rows := make([]Row, len(states))
+	for i, s := range states {
+		ids[i] = i
+		rows[i] = Row{column: values[s.first]}
+		if normalize {
+			rows[i]["proportion"] = float64(s.count) / float64(total)
+		} else {
+			rows[i]["count"] = s.count
+		}
+	}
+	return newDataFrameFromRows(df.Name+"_counts", fields, ids, rows)
+}
+
+// keptIDs returns the sorted ids of the rows DropDuplicates keeps. The caller
+// must hold df.mutex.
+func (df *DataFrame) keptIDs(subset []string, keep string) ([]int, error) {
+	if keep != "" && keep != "first" && keep != "last" && keep != "none" {
+		return nil, fmt.Errorf("unsupported keep %q, use first, last or none", keep)
+	}
+	for _, col := range subset {
+		if !hasField(df.fields, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+	}
+	if len(subset) == 0 {
+		for _, field := range df.fields {
+			subset = append(subset, field.Name)
+		}
+	}
+
+	var ids []int
+	err := df.scanDistinct(subset, false, func(s distinctState) error {
+		switch {
+		case keep == "last":
+			ids = append(ids, s.last)
+		case keep != "none":
+			ids = append(ids, s.first)
+		case s.count == 1:
+			ids = append(ids, s.first)
+		}
+		return nil
+	})
+	sort.Ints(ids)
+	return ids, err
+}
+
+// distinctStates returns the states of the distinct values of a column in the
+// order they first appear. The caller must hold df.mutex.
+func (df *DataFrame) distinctStates(column string, skipNulls bool) ([]distinctState, error) {
+	if !hasField(df.fields, column) {
+		return nil, fmt.Errorf("column %s not found", column)
+	}
+	var states []distinctState
+	err := df.scanDistinct([]string{column}, skipNulls, func(s distinctState) error {
+		states = append(states, s)
+		return nil
+	})
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].first < states[j].first
+	})
+	return states, err
+}
+
+// distinctValues reads the values of a column in the first row of each state.
+// The caller must hold df.mutex.
+func (df *DataFrame) distinctValues(column string, states []distinctState) (map[int]interface{}, error) {
+	ids := make([]int, len(states))
+	for i, s := range states {
+		ids[i] = s.first
+	}
+	sort.Ints(ids)
+	values := make(map[int]interface{}, len(ids))
+	err := df.scanIDs(ids, func(id int, row map[string]interface{}) error {
+		values[id] = row[column]
+		return nil
+	})
+	return values, err
+}
+
+// distinctState tracks the rows holding one distinct key.
+type distinctState struct {
+	first, last int // Lowest and highest ids
+	count       int
+}
+
+func (s *distinctState) merge(other distinctState) {
+	if other.first < s.first {
+		s.first = other.first
+	}
+	if other.last > s.last {
+		s.last = other.last
+	}
+	s.count += other.count
+}
+
+// scanDistinct calls fn once for every distinct key of the given columns, in
+// no particular order. Rows with a null in any of the columns are skipped if
+// skipNulls is set. Chunks are hashed on the worker pool; once the distinct
+// keys outgrow maxDistinctKeys they are spilled to files partitioned by hash,
+// which are then merged one at a time. The caller must hold df.mutex.
+func (df *DataFrame) scanDistinct(columns []string, skipNulls bool, fn func(distinctState) error) error {
+	table := &distinctTable{states: make(map[string]distinctState)}
+	defer table.close()
+
+	var mutex sync.Mutex
+	err := df.runChunks(groupByChunk(df.rowIDs()), func(_ int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		states := make(map[string]distinctState, len(chunk.IDs))
+	rows:
+		for i, id := range chunk.IDs {
+			if skipNulls {
+				for _, col := range columns {
+					if _, ok := deref(chunk.Rows[i][col]); !ok {
+						continue rows
+					}
+				}
+			}
+			key := groupKey(id, chunk.Rows[i], columns)
+			s, ok := states[key]
+			if !ok {
+				s = distinctState{first: id, last: id}
+			}
+			s.merge(distinctState{first: id, last: id, count: 1})
+			states[key] = s
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		return table.add(states)
+	})
+	if err != nil {
+		return err
+	}
+	return table.each(fn)
+}
+
+// distinctTable merges the distinct keys of chunks, spilling them to disk
+// when they outgrow maxDistinctKeys.
+type distinctTable struct {
+	states  map[string]distinctState
+	dir     string // Directory of the spill files, "" until the first spill
+	files   []*os.File
+	writers []*bufio.Writer
+}
+
+// add merges the states of a chunk into the table.
+func (t *distinctTable) add(states map[string]distinctState) error {
+	for key, s := range states {
+		if current, ok := t.states[key]; ok {
+			current.merge(s)
+			t.states[key] = current
+		} else {
+			t.states[key] = s
+		}
+	}
+	if len(t.states) > maxDistinctKeys {
+		return t.spill()
+	}
+	return nil
+}
+
+// spill appends the states in memory to the spill files and clears them.
+func (t *distinctTable) spill() error {
+	if t.dir == "" {
+		if err := os.MkdirAll(chunkDir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create spill directory: %v", err)
+		}
+		dir, err := os.MkdirTemp(chunkDir, "spill_")
+		if err != nil {
+			return fmt.Errorf("failed to create spill directory: %v", err)
+		}
+		t.dir = dir
+		for i := 0; i < spillPartitions; i++ {
+			f, err := os.Create(filepath.Join(dir, fmt.Sprintf("part_%d", i)))
+			if err != nil {
+				return fmt.Errorf("failed to create spill file: %v", err)
+			}
+			t.files = append(t.files, f)
+			t.writers = append(t.writers, bufio.NewWriter(f))
+		}
+	}
+
+	var buf []byte
+	for key, s := range t.states {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		buf = appendBinaryString(buf[:0], key)
+		buf = binary.AppendVarint(buf, int64(s.first))
+		buf = binary.AppendVarint(buf, int64(s.last))
+		buf = binary.AppendUvarint(buf, uint64(s.count))
+		if _, err := t.writers[h.Sum32()%spillPartitions].Write(buf); err != nil {
+			return fmt.Errorf("error writing spill file: %v", err)
+		}
+	}
+	t.states = make(map[string]distinctState)
+	return nil
+}
+
+// each calls fn for every distinct key, merging the spill files one at a time.
+func (t *distinctTable) each(fn func(distinctState) error) error {
+	if t.dir == "" {
+		for _, s := range t.states {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := t.spill(); err != nil {
+		return err
+	}
+	for i, f := range t.files {
+		if err := t.writers[i].Flush(); err != nil {
+			return fmt.Errorf("error writing spill file: %v", err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("error reading spill file: %v", err)
+		}
+		states, err := readSpill(bufio.NewReader(f))
+		if err != nil {
+			return fmt.Errorf("error reading spill file: %v", err)
+		}
+		for _, s := range states {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readSpill merges the states of a spill file.
+func readSpill(r *bufio.Reader) (map[string]distinctState, error) {
+	states := make(map[string]distinctState)
+	for {
+		key, err := readBinaryString(r)
+		if errors.Is(err, io.EOF) {
+			return states, nil
+		} else if err != nil {
+			return nil, err
+		}
+		first, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		last, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		s := distinctState{first: int(first), last: int(last), count: int(count)}
+		if current, ok := states[key]; ok {
+			current.merge(s)
+			s = current
+		}
+		states[key] = s
+	}
+}
+
+// close removes the spill files.
+func (t *distinctTable) close() {
+	for _, f := range t.files {
+		f.Close()
+	}
+	if t.dir != "" {
+		os.RemoveAll(t.dir)
+	}
+}
@@ -0,0 +1,64 @@
+package dataframe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDropDuplicates(t *testing.T) {
+	now := time.Now() // Carries a monotonic clock reading until it is stored
+	fields := []Field{
+		{Name: "Name", Type: reflect.TypeOf("")},
+		{Name: "At", Type: reflect.TypeOf(time.Time{})},
+		{Name: "Qty", Type: reflect.TypeOf((*interface{})(nil)).Elem()},
+	}
+	df, err := newDataFrameFromRows("items", fields, []int{0, 1}, []Row{
+		{"Name": "pen", "At": now, "Qty": 1},
+		{"Name": "pen", "At": now, "Qty": 1.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	if err := df.flush(); err != nil {
+		t.Fatal(err)
+	}
+	later := now.Add(time.Nanosecond)
+	df.InsertRows(map[int]interface{}{ // Left in the cache
+		2: map[string]interface{}{"Name": "pen", "At": now, "Qty": int64(1)},
+		3: map[string]interface{}{"Name": "ink", "At": later, "Qty": 2},
+		4: map[string]interface{}{"Name": "ink", "At": later.In(time.FixedZone("X", -7200)), "Qty": uint8(2)},
+		5: map[string]interface{}{"Name": "cap", "At": now, "Qty": 2.5},
+		6: map[string]interface{}{"Name": "cap", "At": now, "Qty": float32(2.5)},
+		7: map[string]interface{}{"Name": "cap", "At": now, "Qty": 0.0},
+		8: map[string]interface{}{"Name": "cap", "At": now, "Qty": -0.0},
+		9: map[string]interface{}{"Name": "cap", "At": later, "Qty": "0"},
+	})
+
+	tests := []struct {
+		name   string
+		subset []string
+		keep   string
+		want   []int
+	}{
+		{"first", nil, "first", []int{0, 3, 5, 7, 9}},
+		{"default keep", nil, "", []int{0, 3, 5, 7, 9}},
+		{"last", nil, "last", []int{2, 4, 6, 8, 9}},
+		{"none", nil, "none", []int{9}},
+		{"time subset", []string{"At"}, "first", []int{0, 3}},
+		{"number subset", []string{"Qty"}, "first", []int{0, 3, 5, 7, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := df.DropDuplicates(tt.subset, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if got := out.rowIDs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DropDuplicates(%v, %q) kept %v, want %v", tt.subset, tt.keep, got, tt.want)
+			}
+		})
+	}
+}
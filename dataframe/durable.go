@@ -0,0 +1,337 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	manifestFile = "frame.gob" // Name and schema of a durable DataFrame
+	walFile      = "frame.wal" // Mutations not yet checkpointed into chunk files
+)
+
+// manifest is the on-disk description of a durable DataFrame. Column types are
+// stored by name, since reflect.Type cannot be encoded.
+type manifest struct {
+	Name           string
+	Columns        []string
+	Types          []string
+	Codec          string // Codec new chunk files are written with
+	Compression    Compression
+	BloomColumns   []string // Columns with Bloom filters in new chunk files
+	Categorical    []string // Dictionary-encoded columns
+	IndexPoolPages int      // Pages cached per index shard with WithPagedIndex
+}
+
+// manifestTypes maps the type names recorded in a manifest back to types.
+// Columns of any other type are reopened as interface{}.
+var manifestTypes = func() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	for _, v := range []interface{}{
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), "", false, time.Time{},
+	} {
+		t := reflect.TypeOf(v)
+		types[t.String()] = t
+	}
+	return types
+}()
+
+// CreateDataFrame creates a durable DataFrame in dir from a slice of structs.
+// Mutations of a durable frame are written to a write-ahead log before they are
+// applied, and Close keeps its files so that OpenDataFrame can reopen it.
+func CreateDataFrame(dir string, data interface{}, opts ...FrameOption) (*DataFrame, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Len() == 0 {
+		return nil, fmt.Errorf("data slice is empty")
+	}
+
+	df, err := newDataFrame(v.Len(), dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(df)
+	}
+	if _, err := df.store.Get(manifestFile); err == nil {
+		return nil, fmt.Errorf("a DataFrame already exists in %s", dir)
+	}
+	if err := df.openPagedIndexes(); err != nil {
+		return nil, err
+	}
+	if err := df.openDurable(); err != nil {
+		df.closeIndexes()
+		return nil, err
+	}
+
+	if err := df.FromStructs(data); err != nil {
+		df.wal.close()
+		df.closeIndexes()
+		return nil, err
+	}
+	return df, nil
+}
+
+// OpenDataFrame reopens a durable DataFrame created with CreateDataFrame. Mutations
+// logged but not yet written to the chunk files when the process stopped are
+// replayed, so every mutation that returned before a crash is recovered. Chunk
+// files keep being written with the codec the frame was created with unless an
+// option overrides it.
+func OpenDataFrame(dir string, opts ...FrameOption) (*DataFrame, error) {
+	store, err := frameStore(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	m, data, err := readManifest(store)
+	if err != nil {
+		return nil, fmt.Errorf("no DataFrame found in %s: %v", dir, err)
+	}
+	if disk, ok := store.(*DiskStore); ok {
+		disk.removeTemp()
+	}
+
+	chunkIDs, err := listChunks(store)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readWAL(filepath.Join(dir, walFile))
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks are mostly full, so size the index as if they were
+	df, err := newDataFrame(len(chunkIDs)*chunkSize+len(records), dir)
+	if err != nil {
+		return nil, err
+	}
+	df.store = store
+	df.manifest = data
+
+	df.Name = m.Name
+	if m.Codec != "" {
+		if df.codec, err = lookupChunkCodec(m.Codec); err != nil {
+			return nil, err
+		}
+		df.compression = m.Compression
+	}
+	df.bloomColumns = m.BloomColumns
+	if err := df.loadCategories(); err != nil {
+		return nil, err
+	}
+	for _, col := range m.Categorical {
+		if df.categories[col] == nil {
+			df.categories[col] = newCategoryDict()
+		}
+	}
+	df.indexPoolPages = m.IndexPoolPages
+	for _, opt := range opts {
+		opt(df)
+	}
+	if err := df.openPagedIndexes(); err != nil {
+		return nil, err
+	}
+
+	idsByChunk := make([][]int, len(chunkIDs))
+	statsByChunk := make([]*ChunkStats, len(chunkIDs))
+	err = runOnWorkers(len(chunkIDs), func(i int) int { return i }, func(i int) error {
+		name := chunkName(chunkIDs[i])
+		data, err := df.chunkBytes(chunkIDs[i])
+		if err != nil {
+			return fmt.Errorf("error reading chunk file %s: %v", name, err)
+		}
+		chunk, err := decodeChunk(data, df.categories)
+		if err != nil {
+			return fmt.Errorf("error reading chunk file %s: %v", name, err)
+		}
+		if statsByChunk[i], err = chunkFileStats(chunkIDs[i], data, chunk); err != nil {
+			return fmt.Errorf("error reading chunk file %s: %v", name, err)
+		}
+		for id, row := range chunk {
+			idsByChunk[i] = append(idsByChunk[i], id)
+			df.internCategories(row)
+		}
+		return nil
+	})
+	if err != nil {
+		df.closeIndexes()
+		return nil, err
+	}
+	var ids []int
+	for i, chunkIDs := range idsByChunk {
+		ids = append(ids, chunkIDs...)
+		df.stats[statsByChunk[i].ID] = statsByChunk[i]
+	}
+	sort.Ints(ids)
+
+	for i, name := range m.Columns {
+		t, ok := manifestTypes[m.Types[i]]
+		if !ok {
+			t = reflect.TypeOf((*interface{})(nil)).Elem()
+		}
+		df.fields = append(df.fields, Field{Name: name, Type: t})
+	}
+	df.rebuildIndexes(ids)
+
+	df.flushMutex.Lock()
+	defer df.flushMutex.Unlock()
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	df.apply(records)
+
+	if err := df.openDurable(); err != nil {
+		df.closeIndexes()
+		return nil, err
+	}
+	if len(records) > 0 {
+		if err := df.checkpoint(); err != nil {
+			df.wal.close()
+			df.closeIndexes()
+			return nil, err
+		}
+	}
+	return df, nil
+}
+
+// Checkpoint writes all logged mutations to the chunk files and empties the log.
+func (df *DataFrame) Checkpoint() error {
+	df.flushMutex.Lock()
+	defer df.flushMutex.Unlock()
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if df.wal == nil {
+		return fmt.Errorf("DataFrame %s is not durable", df.Name)
+	}
+	return df.checkpoint()
+}
+
+// checkpoint is Checkpoint without locking. The caller must hold df.flushMutex and the write lock.
+func (df *DataFrame) checkpoint() error {
+	if err := df.flushCache(); err != nil {
+		return err
+	}
+	if err := df.writeManifest(); err != nil {
+		return err
+	}
+	if err := df.wal.truncate(); err != nil {
+		return fmt.Errorf("error truncating log: %v", err)
+	}
+	return nil
+}
+
+// openDurable starts logging the mutations of the DataFrame.
+func (df *DataFrame) openDurable() error {
+	w, err := openWAL(filepath.Join(df.chunkDir, walFile))
+	if err != nil {
+		return err
+	}
+	df.wal = w
+	return nil
+}
+
+// frameStore returns the store an option sets, or a DiskStore on dir.
+func frameStore(dir string, opts []FrameOption) (ChunkStore, error) {
+	probe := &DataFrame{}
+	for _, opt := range opts {
+		opt(probe)
+	}
+	if probe.store != nil {
+		return probe.store, nil
+	}
+	return NewDiskStore(dir)
+}
+
+// writeManifest replaces the manifest with the current name and schema if they changed.
+func (df *DataFrame) writeManifest() error {
+	m := manifest{Name: df.Name, Codec: df.codec.Name(), Compression: df.compression, BloomColumns: df.bloomColumns, Categorical: df.categoricalColumns(), IndexPoolPages: df.indexPoolPages}
+	for _, field := range df.fields {
+		m.Columns = append(m.Columns, field.Name)
+		m.Types = append(m.Types, field.Type.String())
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	if bytes.Equal(buf.Bytes(), df.manifest) {
+		return nil
+	}
+	if err := df.store.Put(manifestFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	df.manifest = buf.Bytes()
+	return nil
+}
+
+// readManifest returns the manifest in store along with its encoding.
+func readManifest(store ChunkStore) (manifest, []byte, error) {
+	var m manifest
+	data, err := store.Get(manifestFile)
+	if err != nil {
+		return m, nil, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+		return m, nil, fmt.Errorf("error reading manifest: %v", err)
+	}
+	if len(m.Types) != len(m.Columns) {
+		return m, nil, fmt.Errorf("corrupt manifest")
+	}
+	return m, data, nil
+}
+
+// parseChunkName returns the chunk id of a chunk file name.
+func parseChunkName(name string) (int, bool) {
+	if !strings.HasPrefix(name, "chunk_") || !strings.HasSuffix(name, ".gob") {
+		return 0, false
+	}
+	chunkID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "chunk_"), ".gob"))
+	return chunkID, err == nil
+}
+
+// listChunks returns the ids of the chunks in store in ascending order.
+func listChunks(store ChunkStore) ([]int, error) {
+	names, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("error listing chunk files: %v", err)
+	}
+	var chunkIDs []int
+	for _, name := range names {
+		if chunkID, ok := parseChunkName(name); ok {
+			chunkIDs = append(chunkIDs, chunkID)
+		}
+	}
+	sort.Ints(chunkIDs)
+	return chunkIDs, nil
+}
+
+// rebuildIndexes loads every index shard with the sorted ids it holds, as
+// indexIDs does, so that no row is left out of the index.
+func (df *DataFrame) rebuildIndexes(ids []int) {
+	byShard := make([][]int, df.numTrees)
+	for _, id := range ids {
+		shard := df.shardOf(id / chunkSize)
+		byShard[shard] = append(byShard[shard], id)
+	}
+
+	runOnWorkers(df.numTrees, func(i int) int {
+		return i
+	}, func(i int) error {
+		if len(byShard[i]) > 0 {
+			df.loadShard(i, byShard[i])
+		}
+		return nil
+	})
+}
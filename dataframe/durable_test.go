@@ -0,0 +1,178 @@
+package dataframe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type durableItem struct {
+	Name string
+	Qty  int
+}
+
+// mutateItems applies the same mutations to a durable frame and to the rows
+// expected in it.
+func mutateItems(t *testing.T, df *DataFrame, want map[int]map[string]interface{}) {
+	t.Helper()
+	if err := df.UpdateRow(1, map[string]interface{}{"Qty": 30}); err != nil {
+		t.Fatal(err)
+	}
+	want[1]["Qty"] = 30
+	if err := df.DeleteRow(2); err != nil {
+		t.Fatal(err)
+	}
+	delete(want, 2)
+	df.InsertRow(1500, durableItem{"pad", 7})
+	want[1500] = map[string]interface{}{"Name": "pad", "Qty": 7}
+}
+
+// durableItems creates a durable frame of n items in dir, returning the rows
+// expected in it.
+func durableItems(t *testing.T, dir string, n int, opts ...FrameOption) (*DataFrame, map[int]map[string]interface{}) {
+	t.Helper()
+	items := make([]durableItem, n)
+	want := make(map[int]map[string]interface{}, n)
+	for i := range items {
+		items[i] = durableItem{[]string{"pen", "ink", "cap"}[i%3], i}
+		want[i] = map[string]interface{}{"Name": items[i].Name, "Qty": i}
+	}
+	df, err := CreateDataFrame(dir, items, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return df, want
+}
+
+// checkItems compares the rows of df with want.
+func checkItems(t *testing.T, df *DataFrame, want map[int]map[string]interface{}) {
+	t.Helper()
+	got := make(map[int]map[string]interface{})
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		got[id] = row
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened frame has %d rows, want %d", len(got), len(want))
+		for id, row := range want {
+			if !reflect.DeepEqual(got[id], row) {
+				t.Errorf("row %d = %v, want %v", id, got[id], row)
+				break
+			}
+		}
+	}
+}
+
+var durableOptions = []struct {
+	name string
+	opts []FrameOption
+}{
+	{"default", nil},
+	{"binary zstd", []FrameOption{WithChunkCodec(BinaryCodec{}, Zstd)}},
+	{"msgpack snappy", []FrameOption{WithChunkCodec(MsgpackCodec{}, Snappy)}},
+	{"categorical", []FrameOption{WithCategorical("Name")}},
+	{"paged index", []FrameOption{WithPagedIndex(4)}},
+}
+
+func TestOpenDataFrame(t *testing.T) {
+	for _, tt := range durableOptions {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "items")
+			df, want := durableItems(t, dir, 2500, tt.opts...)
+			mutateItems(t, df, want)
+			df.Close()
+
+			reopened, err := OpenDataFrame(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			checkItems(t, reopened, want)
+			if !reflect.DeepEqual(reopened.fields, df.fields) {
+				t.Errorf("fields = %v, want %v", reopened.fields, df.fields)
+			}
+			if found, err := reopened.Lazy().Filter(Col("Qty").Eq(30)).Collect(); err != nil || len(found.rowIDs()) != 2 {
+				t.Errorf("filter on the reopened frame = %v, %v, want 2 rows", found, err)
+			}
+		})
+	}
+}
+
+func TestOpenDataFrameReplaysLog(t *testing.T) {
+	tests := []struct {
+		name string
+		cut  int  // Bytes removed from the end of the log
+		torn bool // The cut tears the last batch, losing the insert
+	}{
+		{"complete log", 0, false},
+		{"torn last batch", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "items")
+			df, want := durableItems(t, dir, 1200, WithChunkCodec(BinaryCodec{}, NoCompression))
+			defer df.Close()
+			mutateItems(t, df, want)
+			if tt.torn {
+				delete(want, 1500)
+			}
+
+			// Copy the files of the open frame as a crash would leave them
+			crashed := filepath.Join(t.TempDir(), "crashed")
+			if err := os.CopyFS(crashed, os.DirFS(dir)); err != nil {
+				t.Fatal(err)
+			}
+			log := filepath.Join(crashed, walFile)
+			info, err := os.Stat(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() == 0 {
+				t.Fatal("mutations were not logged")
+			}
+			if err := os.Truncate(log, info.Size()-int64(tt.cut)); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened, err := OpenDataFrame(crashed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			checkItems(t, reopened, want)
+			if info, err := os.Stat(log); err != nil || info.Size() != 0 {
+				t.Errorf("log was not checkpointed when reopening: %v, %v", info, err)
+			}
+		})
+	}
+}
+
+func TestCreateDataFrameExisting(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "items")
+	df, want := durableItems(t, dir, 1200, WithPagedIndex(4))
+	df.Close()
+	pages := filepath.Join(dir, "index_0.pages")
+	before, err := os.Stat(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreateDataFrame(dir, []durableItem{{"pen", 1}}, WithPagedIndex(4)); err == nil {
+		t.Fatal("CreateDataFrame over an existing frame succeeded")
+	}
+	after, err := os.Stat(pages)
+	if err != nil || !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		t.Errorf("index file was replaced: %v, %v", after, err)
+	}
+
+	reopened, err := OpenDataFrame(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	checkItems(t, reopened, want)
+}
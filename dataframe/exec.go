@@ -0,0 +1,233 @@
+package dataframe
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// indexFillFactor is how full bulk loaded index leaves are packed. Row ids are
+// mostly appended in ascending order, so leaves rarely need room for inserts.
+const indexFillFactor = 1.0
+
+// Chunk is a block of rows stored together in one chunk file, in id order.
+type Chunk struct {
+	ID   int // Rows of chunk ID have ids in [ID*chunkSize, (ID+1)*chunkSize)
+	IDs  []int
+	Rows []Row
+}
+
+// chunkTask is the work for one chunk: the sorted ids it holds.
+type chunkTask struct {
+	chunkID int
+	ids     []int
+}
+
+// groupByChunk splits sorted ids into one task per chunk, in chunk order.
+func groupByChunk(ids []int) []chunkTask {
+	var tasks []chunkTask
+	for _, id := range ids {
+		chunkID := id / chunkSize
+		if len(tasks) == 0 || tasks[len(tasks)-1].chunkID != chunkID {
+			tasks = append(tasks, chunkTask{chunkID: chunkID})
+		}
+		task := &tasks[len(tasks)-1]
+		task.ids = append(task.ids, id)
+	}
+	return tasks
+}
+
+// shardOf returns the index shard holding the ids of a chunk.
+func (df *DataFrame) shardOf(chunkID int) int {
+	return chunkID % df.numTrees
+}
+
+// workerCount is the size of the worker pool used for chunk-level work.
+func workerCount() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// runChunks calls fn for every task on a pool of GOMAXPROCS workers. All chunks
+// of an index shard are handed to the same worker, so a worker exclusively owns
+// the cache entries and the index shards of its chunks while it runs and workers
+// never wait on each other. fn receives the position of the task in tasks.
+func (df *DataFrame) runChunks(tasks []chunkTask, fn func(i int, task chunkTask) error) error {
+	return runOnWorkers(len(tasks), func(i int) int {
+		return df.shardOf(tasks[i].chunkID)
+	}, func(i int) error {
+		return fn(i, tasks[i])
+	})
+}
+
+// runOnWorkers calls fn(i) for i in [0, n) on a pool of GOMAXPROCS workers.
+// Items with the same owner always run on the same worker, one after another.
+func runOnWorkers(n int, owner func(i int) int, fn func(i int) error) error {
+	workers := workerCount()
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	queues := make([][]int, workers)
+	for i := 0; i < n; i++ {
+		w := owner(i) % workers
+		queues[w] = append(queues[w], i)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		failed   atomic.Bool
+	)
+	for _, queue := range queues {
+		wg.Add(1)
+		go func(queue []int) {
+			defer wg.Done()
+			for _, i := range queue {
+				if failed.Load() {
+					return
+				}
+				if err := fn(i); err != nil {
+					once.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+			}
+		}(queue)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// loadChunk reads the rows of a task, taking cached rows from memory and the
+// rest from the chunk file. Ids without a row are skipped. The caller must hold df.mutex.
+func (df *DataFrame) loadChunk(task chunkTask) (Chunk, error) {
+	chunk := Chunk{ID: task.chunkID}
+
+	var stored map[int]interface{}
+	for _, id := range task.ids {
+		row, ok := df.cachedRow(id)
+		if !ok {
+			if stored == nil {
+				var err error
+				if stored, err = df.readChunkFile(task.chunkID); err != nil {
+					return chunk, err
+				}
+			}
+			if row, ok = stored[id]; !ok {
+				continue
+			}
+		}
+		chunk.IDs = append(chunk.IDs, id)
+		chunk.Rows = append(chunk.Rows, toRowMap(row))
+	}
+	return chunk, nil
+}
+
+// MapChunks calls fn for every chunk of df on the worker pool and returns the
+// results in chunk order.
+func MapChunks[T any](df *DataFrame, fn func(Chunk) (T, error)) ([]T, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+	return mapChunks(df, df.rowIDs(), fn)
+}
+
+// ReduceChunks calls fn for every chunk of df on the worker pool and folds the
+// partial results into init with reduce, in chunk order.
+func ReduceChunks[T any](df *DataFrame, init T, fn func(Chunk) (T, error), reduce func(acc, part T) T) (T, error) {
+	parts, err := MapChunks(df, fn)
+	if err != nil {
+		return init, err
+	}
+	acc := init
+	for _, part := range parts {
+		acc = reduce(acc, part)
+	}
+	return acc, nil
+}
+
+// mapChunks is MapChunks restricted to the chunks holding the given sorted ids.
+// The caller must hold df.mutex.
+func mapChunks[T any](df *DataFrame, ids []int, fn func(Chunk) (T, error)) ([]T, error) {
+	tasks := groupByChunk(ids)
+	results := make([]T, len(tasks))
+	err := df.runChunks(tasks, func(i int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		results[i], err = fn(chunk)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ingest stores a row for every id on the worker pool, with each worker filling
+// the cache entries and index shards of its own chunks. value is called
+// concurrently and must be safe for that. The caller must hold the write lock
+// and call flushIfFull once it is released.
+func (df *DataFrame) ingest(ids []int, value func(id int) interface{}) {
+	tasks := groupByChunk(ids)
+	for _, task := range tasks {
+		if df.cache[task.chunkID] == nil {
+			df.cache[task.chunkID] = make(map[int]interface{}, len(task.ids))
+		}
+	}
+
+	var size atomic.Int64
+	df.runChunks(tasks, func(_ int, task chunkTask) error {
+		chunk := df.cache[task.chunkID]
+		var chunkBytes int
+		for _, id := range task.ids {
+			row := value(id)
+			df.internCategories(row)
+			chunk[id] = row
+			chunkBytes += int(reflect.TypeOf(row).Size())
+		}
+		size.Add(int64(chunkBytes))
+		return nil
+	})
+	df.indexIDs(tasks)
+	if df.timeIndex != nil {
+		for _, task := range tasks {
+			for _, id := range task.ids {
+				df.timeIndex.add(id, df.cache[task.chunkID][id])
+			}
+		}
+	}
+
+	df.cacheSize += int(size.Load())
+}
+
+// indexIDs adds the ids of the tasks to the index shards, one worker per shard.
+// Empty shards are bulk loaded from the sorted ids instead of inserted into.
+func (df *DataFrame) indexIDs(tasks []chunkTask) {
+	byShard := make(map[int][]int)
+	var shards []int
+	for _, task := range tasks {
+		shard := df.shardOf(task.chunkID)
+		if _, ok := byShard[shard]; !ok {
+			shards = append(shards, shard)
+		}
+		byShard[shard] = append(byShard[shard], task.ids...)
+	}
+
+	runOnWorkers(len(shards), func(i int) int {
+		return shards[i]
+	}, func(i int) error {
+		df.loadShard(shards[i], byShard[shards[i]])
+		return nil
+	})
+}
@@ -0,0 +1,313 @@
+package dataframe
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeConfig holds the formatting settings shared by the Write* exporters.
+type writeConfig struct {
+	floatPrecision int
+	timeLayout     string
+	nullValue      string
+	includeIndex   bool
+	indexName      string
+}
+
+// WriteOption configures how values are rendered by WriteCSV, WriteJSON, WriteMarkdown and WriteHTML.
+type WriteOption func(*writeConfig)
+
+// WithFloatPrecision sets the number of digits written after the decimal point.
+// A negative precision writes the shortest representation that round-trips.
+func WithFloatPrecision(precision int) WriteOption {
+	return func(c *writeConfig) {
+		c.floatPrecision = precision
+	}
+}
+
+// WithTimeLayout sets the layout used to format time.Time values.
+func WithTimeLayout(layout string) WriteOption {
+	return func(c *writeConfig) {
+		c.timeLayout = layout
+	}
+}
+
+// WithNullValue sets the text written for nil values. JSON output always uses null.
+func WithNullValue(null string) WriteOption {
+	return func(c *writeConfig) {
+		c.nullValue = null
+	}
+}
+
+// WithIndex writes the row id as the first column under the given name.
+func WithIndex(name string) WriteOption {
+	return func(c *writeConfig) {
+		c.includeIndex = true
+		c.indexName = name
+	}
+}
+
+func newWriteConfig(opts []WriteOption) *writeConfig {
+	c := &writeConfig{
+		floatPrecision: -1,
+		timeLayout:     time.RFC3339,
+		indexName:      "id",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// headers returns the column headers written by the exporters.
+func (c *writeConfig) headers(columns []string) []string {
+	if c.includeIndex {
+		return append([]string{c.indexName}, columns...)
+	}
+	return columns
+}
+
+// format renders a single value as text.
+func (c *writeConfig) format(val interface{}) string {
+	val, ok := deref(val)
+	if !ok {
+		return c.nullValue
+	}
+	switch v := val.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', c.floatPrecision, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', c.floatPrecision, 32)
+	case time.Time:
+		return v.Format(c.timeLayout)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatJSON renders a single value as a JSON literal.
+func (c *writeConfig) formatJSON(val interface{}) ([]byte, error) {
+	val, ok := deref(val)
+	if !ok {
+		return []byte("null"), nil
+	}
+	switch v := val.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return []byte("null"), nil
+		}
+		return []byte(strconv.FormatFloat(v, 'f', c.floatPrecision, 64)), nil
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return []byte("null"), nil
+		}
+		return []byte(strconv.FormatFloat(float64(v), 'f', c.floatPrecision, 32)), nil
+	case time.Time:
+		return json.Marshal(v.Format(c.timeLayout))
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// deref unwraps pointers and reports false for nil values.
+func deref(val interface{}) (interface{}, bool) {
+	if val == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), true
+}
+
+// WriteCSV writes the DataFrame to w as CSV with a header row, in schema column order.
+func (df *DataFrame) WriteCSV(w io.Writer, opts ...WriteOption) error {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	c := newWriteConfig(opts)
+	columns := df.Columns()
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(c.headers(columns)); err != nil {
+		return err
+	}
+
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		var record []string
+		if c.includeIndex {
+			record = append(record, strconv.Itoa(id))
+		}
+		for _, col := range columns {
+			record = append(record, c.format(row[col]))
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the DataFrame to w as a JSON array of objects whose keys follow schema column order.
+func (df *DataFrame) WriteJSON(w io.Writer, opts ...WriteOption) error {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	c := newWriteConfig(opts)
+	columns := df.Columns()
+
+	// Encode the keys once since they are the same for every row
+	keys := make([][]byte, len(columns))
+	for i, col := range columns {
+		key, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+	indexKey, err := json.Marshal(c.indexName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err = df.scanRows(func(id int, row map[string]interface{}) error {
+		var buf strings.Builder
+		if !first {
+			buf.WriteString(",")
+		}
+		first = false
+		buf.WriteString("\n  {")
+		if c.includeIndex {
+			buf.Write(indexKey)
+			buf.WriteString(":")
+			buf.WriteString(strconv.Itoa(id))
+		}
+		for i, col := range columns {
+			if i > 0 || c.includeIndex {
+				buf.WriteString(",")
+			}
+			val, err := c.formatJSON(row[col])
+			if err != nil {
+				return fmt.Errorf("error encoding column %s of row %d: %v", col, id, err)
+			}
+			buf.Write(keys[i])
+			buf.WriteString(":")
+			buf.Write(val)
+		}
+		buf.WriteString("}")
+		_, err := io.WriteString(w, buf.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if first {
+		_, err = io.WriteString(w, "]\n")
+	} else {
+		_, err = io.WriteString(w, "\n]\n")
+	}
+	return err
+}
+
+// WriteMarkdown writes the DataFrame to w as a GitHub-flavored Markdown table.
+func (df *DataFrame) WriteMarkdown(w io.Writer, opts ...WriteOption) error {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	c := newWriteConfig(opts)
+	columns := df.Columns()
+	headers := c.headers(columns)
+
+	escape := strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+	writeLine := func(cells []string) error {
+		for i, cell := range cells {
+			cells[i] = escape.Replace(cell)
+		}
+		_, err := io.WriteString(w, "| "+strings.Join(cells, " | ")+" |\n")
+		return err
+	}
+
+	if err := writeLine(append([]string(nil), headers...)); err != nil {
+		return err
+	}
+	separator := make([]string, len(headers))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	if err := writeLine(separator); err != nil {
+		return err
+	}
+
+	return df.scanRows(func(id int, row map[string]interface{}) error {
+		var cells []string
+		if c.includeIndex {
+			cells = append(cells, strconv.Itoa(id))
+		}
+		for _, col := range columns {
+			cells = append(cells, c.format(row[col]))
+		}
+		return writeLine(cells)
+	})
+}
+
+// WriteHTML writes the DataFrame to w as an HTML table. Values are HTML-escaped.
+func (df *DataFrame) WriteHTML(w io.Writer, opts ...WriteOption) error {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	c := newWriteConfig(opts)
+	columns := df.Columns()
+
+	var buf strings.Builder
+	buf.WriteString("<table>\n  <thead>\n    <tr>")
+	for _, header := range c.headers(columns) {
+		buf.WriteString("<th>" + html.EscapeString(header) + "</th>")
+	}
+	buf.WriteString("</tr>\n  </thead>\n  <tbody>\n")
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return err
+	}
+
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		var buf strings.Builder
+		buf.WriteString("    <tr>")
+		if c.includeIndex {
+			buf.WriteString("<td>" + strconv.Itoa(id) + "</td>")
+		}
+		for _, col := range columns {
+			buf.WriteString("<td>" + html.EscapeString(c.format(row[col])) + "</td>")
+		}
+		buf.WriteString("</tr>\n")
+		_, err := io.WriteString(w, buf.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "  </tbody>\n</table>\n")
+	return err
+}
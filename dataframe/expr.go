@@ -0,0 +1,585 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Row holds the values of a single row keyed by column name.
+type Row map[string]interface{}
+
+// Expr is an expression evaluated against a single row. Expressions are built
+// with Col, Lit and ID and combined with the comparison, logical, arithmetic,
+// string and time methods, for example Col("Age").Gt(30).And(Col("City").Eq("Paris")).
+type Expr struct {
+	op    string      // Operator, or "col", "lit" and "id" for leaves
+	name  string      // Column name for "col"
+	value interface{} // Literal value for "lit"
+	args  []Expr      // Operands
+}
+
+// Col references the value of a column.
+func Col(name string) Expr {
+	return Expr{op: "col", name: name}
+}
+
+// Lit wraps a constant value.
+func Lit(value interface{}) Expr {
+	return Expr{op: "lit", value: value}
+}
+
+// ID references the row id. Predicates on ID are answered from the B+Tree indexes.
+func ID() Expr {
+	return Expr{op: "id"}
+}
+
+// toExpr wraps plain values as literals so builder methods accept either.
+func toExpr(v interface{}) Expr {
+	if e, ok := v.(Expr); ok {
+		return e
+	}
+	return Lit(v)
+}
+
+func (e Expr) binary(op string, v interface{}) Expr {
+	return Expr{op: op, args: []Expr{e, toExpr(v)}}
+}
+
+// Eq is true when the expression equals v.
+func (e Expr) Eq(v interface{}) Expr { return e.binary("==", v) }
+
+// Ne is true when the expression does not equal v.
+func (e Expr) Ne(v interface{}) Expr { return e.binary("!=", v) }
+
+// Lt is true when the expression is less than v.
+func (e Expr) Lt(v interface{}) Expr { return e.binary("<", v) }
+
+// Le is true when the expression is less than or equal to v.
+func (e Expr) Le(v interface{}) Expr { return e.binary("<=", v) }
+
+// Gt is true when the expression is greater than v.
+func (e Expr) Gt(v interface{}) Expr { return e.binary(">", v) }
+
+// Ge is true when the expression is greater than or equal to v.
+func (e Expr) Ge(v interface{}) Expr { return e.binary(">=", v) }
+
+// Between is true when lo <= e <= hi.
+func (e Expr) Between(lo, hi interface{}) Expr {
+	return e.Ge(lo).And(e.Le(hi))
+}
+
+// In is true when the expression equals any of the given values.
+func (e Expr) In(values ...interface{}) Expr {
+	args := []Expr{e}
+	for _, v := range values {
+		args = append(args, toExpr(v))
+	}
+	return Expr{op: "in", args: args}
+}
+
+// IsNull is true when the expression is nil.
+func (e Expr) IsNull() Expr {
+	return Expr{op: "isnull", args: []Expr{e}}
+}
+
+// And is true when the expression and all others are true.
+func (e Expr) And(others ...Expr) Expr {
+	return Expr{op: "and", args: append([]Expr{e}, others...)}
+}
+
+// Or is true when the expression or any of the others is true.
+func (e Expr) Or(others ...Expr) Expr {
+	return Expr{op: "or", args: append([]Expr{e}, others...)}
+}
+
+// Not negates a boolean expression.
+func (e Expr) Not() Expr {
+	return Expr{op: "not", args: []Expr{e}}
+}
+
+// Add adds v to the expression, or concatenates it to a string.
+func (e Expr) Add(v interface{}) Expr { return e.binary("+", v) }
+
+// Sub subtracts v from the expression.
+func (e Expr) Sub(v interface{}) Expr { return e.binary("-", v) }
+
+// Mul multiplies the expression by v.
+func (e Expr) Mul(v interface{}) Expr { return e.binary("*", v) }
+
+// Div divides the expression by v. The result is always a float64.
+func (e Expr) Div(v interface{}) Expr { return e.binary("/", v) }
+
+// Mod is the remainder of dividing the expression by v.
+func (e Expr) Mod(v interface{}) Expr { return e.binary("%", v) }
+
+func (e Expr) call(fn string, args ...interface{}) Expr {
+	call := Expr{op: fn, args: []Expr{e}}
+	for _, arg := range args {
+		call.args = append(call.args, toExpr(arg))
+	}
+	return call
+}
+
+// Upper converts a string expression to upper case.
+func (e Expr) Upper() Expr { return e.call("upper") }
+
+// Lower converts a string expression to lower case.
+func (e Expr) Lower() Expr { return e.call("lower") }
+
+// Trim removes the leading and trailing white space of a string expression.
+func (e Expr) Trim() Expr { return e.call("trim") }
+
+// Len is the number of characters of a string expression.
+func (e Expr) Len() Expr { return e.call("len") }
+
+// Contains is true when a string expression contains substr.
+func (e Expr) Contains(substr interface{}) Expr { return e.call("contains", substr) }
+
+// StartsWith is true when a string expression begins with prefix.
+func (e Expr) StartsWith(prefix interface{}) Expr { return e.call("startswith", prefix) }
+
+// EndsWith is true when a string expression ends with suffix.
+func (e Expr) EndsWith(suffix interface{}) Expr { return e.call("endswith", suffix) }
+
+// Replace replaces every occurrence of old in a string expression by new.
+func (e Expr) Replace(old, new interface{}) Expr { return e.call("replace", old, new) }
+
+// Year is the year of a time expression.
+func (e Expr) Year() Expr { return e.call("year") }
+
+// Month is the month of a time expression, from 1 to 12.
+func (e Expr) Month() Expr { return e.call("month") }
+
+// Day is the day of the month of a time expression.
+func (e Expr) Day() Expr { return e.call("day") }
+
+// Hour is the hour of a time expression.
+func (e Expr) Hour() Expr { return e.call("hour") }
+
+// Minute is the minute of a time expression.
+func (e Expr) Minute() Expr { return e.call("minute") }
+
+// Weekday is the day of the week of a time expression, from 0 for Sunday to 6.
+func (e Expr) Weekday() Expr { return e.call("weekday") }
+
+// FillNull is v when the expression is nil, and the expression otherwise.
+func (e Expr) FillNull(v interface{}) Expr { return e.call("fillnull", v) }
+
+// If is then when cond is true and otherwise when it is not.
+func If(cond Expr, then, otherwise interface{}) Expr {
+	return cond.call("if", then, otherwise)
+}
+
+// Columns returns the names of the columns referenced by the expression.
+func (e Expr) Columns() []string {
+	seen := make(map[string]bool)
+	var columns []string
+	e.walk(func(node Expr) {
+		if node.op == "col" && !seen[node.name] {
+			seen[node.name] = true
+			columns = append(columns, node.name)
+		}
+	})
+	return columns
+}
+
+// walk calls fn for the expression and every sub-expression.
+func (e Expr) walk(fn func(Expr)) {
+	fn(e)
+	for _, arg := range e.args {
+		arg.walk(fn)
+	}
+}
+
+// usesID reports whether the expression references the row id.
+func (e Expr) usesID() bool {
+	found := false
+	e.walk(func(node Expr) {
+		if node.op == "id" {
+			found = true
+		}
+	})
+	return found
+}
+
+// String renders the expression for Explain output.
+func (e Expr) String() string {
+	switch e.op {
+	case "col":
+		return e.name
+	case "id":
+		return "id()"
+	case "lit":
+		if s, ok := e.value.(string); ok {
+			return strconv.Quote(s)
+		}
+		return fmt.Sprintf("%v", e.value)
+	case "and", "or":
+		parts := make([]string, len(e.args))
+		for i, arg := range e.args {
+			parts[i] = arg.String()
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(e.op)+" ") + ")"
+	case "not":
+		return "NOT " + e.args[0].String()
+	case "isnull":
+		return e.args[0].String() + " IS NULL"
+	case "in":
+		parts := make([]string, len(e.args)-1)
+		for i, arg := range e.args[1:] {
+			parts[i] = arg.String()
+		}
+		return e.args[0].String() + " IN (" + strings.Join(parts, ", ") + ")"
+	case "==", "!=", "<", "<=", ">", ">=", "+", "-", "*", "/", "%":
+		return "(" + e.args[0].String() + " " + e.op + " " + e.args[1].String() + ")"
+	default:
+		parts := make([]string, len(e.args))
+		for i, arg := range e.args {
+			parts[i] = arg.String()
+		}
+		return e.op + "(" + strings.Join(parts, ", ") + ")"
+	}
+}
+
+// Eval evaluates the expression against a row with the given id.
+func (e Expr) Eval(id int, row Row) (interface{}, error) {
+	switch e.op {
+	case "col":
+		val, ok := row[e.name]
+		if !ok {
+			return nil, fmt.Errorf("column %s not found", e.name)
+		}
+		return val, nil
+	case "lit":
+		return e.value, nil
+	case "id":
+		return id, nil
+	case "and", "or":
+		for _, arg := range e.args {
+			ok, err := arg.Match(id, row)
+			if err != nil {
+				return nil, err
+			}
+			if e.op == "and" && !ok {
+				return false, nil
+			}
+			if e.op == "or" && ok {
+				return true, nil
+			}
+		}
+		return e.op == "and", nil
+	case "not":
+		ok, err := e.args[0].Match(id, row)
+		return !ok, err
+	case "isnull":
+		val, err := e.args[0].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		_, ok := deref(val)
+		return !ok, nil
+	case "in":
+		val, err := e.args[0].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range e.args[1:] {
+			candidate, err := arg.Eval(id, row)
+			if err != nil {
+				return nil, err
+			}
+			if cmp, ok := compareValues(val, candidate); ok && cmp == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		left, err := e.args[0].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.args[1].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		cmp, ok := compareValues(left, right)
+		if !ok {
+			// Mismatched or null operands only satisfy !=
+			return e.op == "!=", nil
+		}
+		switch e.op {
+		case "==":
+			return cmp == 0, nil
+		case "!=":
+			return cmp != 0, nil
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "+", "-", "*", "/", "%":
+		left, err := e.args[0].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.args[1].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic(e.op, left, right)
+	case "if":
+		ok, err := e.args[0].Match(id, row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return e.args[1].Eval(id, row)
+		}
+		return e.args[2].Eval(id, row)
+	case "fillnull":
+		val, err := e.args[0].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := deref(val); ok {
+			return val, nil
+		}
+		return e.args[1].Eval(id, row)
+	case "upper", "lower", "trim", "len", "contains", "startswith", "endswith", "replace":
+		return e.evalString(id, row)
+	case "year", "month", "day", "hour", "minute", "weekday":
+		val, err := e.args[0].Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := deref(val); !ok {
+			return nil, nil
+		}
+		t, ok := timeValue(val)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a time, got %T", e.op, val)
+		}
+		switch e.op {
+		case "year":
+			return t.Year(), nil
+		case "month":
+			return int(t.Month()), nil
+		case "day":
+			return t.Day(), nil
+		case "hour":
+			return t.Hour(), nil
+		case "minute":
+			return t.Minute(), nil
+		default:
+			return int(t.Weekday()), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operator: %s", e.op)
+	}
+}
+
+// evalString evaluates a string function. Null strings give nil.
+func (e Expr) evalString(id int, row Row) (interface{}, error) {
+	args := make([]string, len(e.args))
+	for i, arg := range e.args {
+		val, err := arg.Eval(id, row)
+		if err != nil {
+			return nil, err
+		}
+		val, ok := deref(val)
+		if !ok {
+			return nil, nil
+		}
+		if args[i], ok = val.(string); !ok {
+			return nil, fmt.Errorf("%s expects a string, got %T", e.op, val)
+		}
+	}
+
+	s := args[0]
+	switch e.op {
+	case "upper":
+		return strings.ToUpper(s), nil
+	case "lower":
+		return strings.ToLower(s), nil
+	case "trim":
+		return strings.TrimSpace(s), nil
+	case "len":
+		return utf8.RuneCountInString(s), nil
+	case "contains":
+		return strings.Contains(s, args[1]), nil
+	case "startswith":
+		return strings.HasPrefix(s, args[1]), nil
+	case "endswith":
+		return strings.HasSuffix(s, args[1]), nil
+	default:
+		return strings.ReplaceAll(s, args[1], args[2]), nil
+	}
+}
+
+// arithmetic applies an arithmetic operator. Integers give integers except for
+// division, other numbers give float64, strings concatenate and times shift by
+// durations. A null operand or an integer division by zero gives nil.
+func arithmetic(op string, a, b interface{}) (interface{}, error) {
+	a, okA := deref(a)
+	b, okB := deref(b)
+	if !okA || !okB {
+		return nil, nil
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok && op == "+" {
+			return x + y, nil
+		}
+	case time.Time:
+		switch y := b.(type) {
+		case time.Duration:
+			if op == "+" {
+				return x.Add(y), nil
+			} else if op == "-" {
+				return x.Add(-y), nil
+			}
+		case time.Time:
+			if op == "-" {
+				return x.Sub(y), nil
+			}
+		}
+	case time.Duration:
+		if y, ok := b.(time.Duration); ok && (op == "+" || op == "-") {
+			if op == "+" {
+				return x + y, nil
+			}
+			return x - y, nil
+		}
+	}
+
+	if x, ok := integerValue(a); ok && op != "/" {
+		if y, ok := integerValue(b); ok {
+			switch op {
+			case "+":
+				return x + y, nil
+			case "-":
+				return x - y, nil
+			case "*":
+				return x * y, nil
+			default:
+				if y == 0 {
+					return nil, nil
+				}
+				return x % y, nil
+			}
+		}
+	}
+
+	x, okX := ToFloat64(a)
+	y, okY := ToFloat64(b)
+	if !okX || !okY {
+		return nil, fmt.Errorf("cannot apply %s to %T and %T", op, a, b)
+	}
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		return x / y, nil
+	default:
+		return math.Mod(x, y), nil
+	}
+}
+
+// integerValue returns the value of an integer of any type as an int.
+func integerValue(val interface{}) (int, bool) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, isDuration := val.(time.Duration); !isDuration {
+			return int(v.Int()), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint()), true
+	}
+	return 0, false
+}
+
+// Match evaluates a boolean expression against a row with the given id.
+func (e Expr) Match(id int, row Row) (bool, error) {
+	val, err := e.Eval(id, row)
+	if err != nil {
+		return false, err
+	}
+	ok, isBool := val.(bool)
+	if !isBool {
+		return false, fmt.Errorf("expression %s is not boolean", e)
+	}
+	return ok, nil
+}
+
+// compareValues orders two values, reporting false when they are not comparable.
+// Numbers of any type compare numerically.
+func compareValues(a, b interface{}) (int, bool) {
+	a, okA := deref(a)
+	b, okB := deref(b)
+	if !okA || !okB {
+		return 0, false
+	}
+
+	if x, ok := ToFloat64(a); ok {
+		y, ok := ToFloat64(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case time.Time:
+		y, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return x.Compare(y), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x == y:
+			return 0, true
+		case !x:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+
+	if reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() && a == b {
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,173 @@
+package dataframe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aggnr/bluejay/db"
+)
+
+// IDIndex is an index shard holding the ids of the rows of its chunks. Shards
+// are in-memory db.BPlusTrees unless the frame is created with WithPagedIndex.
+type IDIndex interface {
+	Insert(id int)
+	Delete(id int)
+	Search(id int) bool
+	Range(lo, hi int) []int     // Ids between lo and hi inclusive, ascending
+	Ceiling(id int) (int, bool) // Smallest id at or above id
+	Keys() []int
+	Empty() bool
+	Len() int
+	Select(ranks []int) []int // Ids at sorted ranks, positions in ascending order from 0
+}
+
+// WithPagedIndex keeps the index shards in page files in the directory of the
+// DataFrame rather than in memory, caching at most poolPages 4KB pages of each
+// shard, so that the index of a frame may grow larger than memory. Durable
+// frames remember the option and rebuild the page files from their chunk files
+// when opened.
+func WithPagedIndex(poolPages int) FrameOption {
+	return func(df *DataFrame) {
+		if poolPages < 1 {
+			poolPages = 1
+		}
+		df.indexPoolPages = poolPages
+	}
+}
+
+// openPagedIndexes replaces the empty in-memory index shards with page files
+// if WithPagedIndex was given. Page files left by an earlier run are replaced.
+func (df *DataFrame) openPagedIndexes() error {
+	if df.indexPoolPages == 0 {
+		return nil
+	}
+	stale, err := filepath.Glob(filepath.Join(df.chunkDir, "index_*.pages"))
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("error removing index file %s: %v", path, err)
+		}
+	}
+
+	indexes := make([]IDIndex, df.numTrees)
+	for i := range indexes {
+		path := filepath.Join(df.chunkDir, fmt.Sprintf("index_%d.pages", i))
+		tree, err := db.OpenPagedBPlusTree(path, df.indexPoolPages)
+		if err != nil {
+			for _, opened := range indexes[:i] {
+				opened.(*pagedIndex).close()
+			}
+			return fmt.Errorf("error opening index file %s: %v", path, err)
+		}
+		indexes[i] = &pagedIndex{tree: tree}
+	}
+	df.Indexes = indexes
+	return nil
+}
+
+// closeIndexes closes the page files of paged index shards.
+func (df *DataFrame) closeIndexes() {
+	for _, index := range df.Indexes {
+		if paged, ok := index.(*pagedIndex); ok {
+			if err := paged.close(); err != nil {
+				fmt.Printf("Error closing index: %v\n", err)
+			}
+		}
+	}
+}
+
+// loadShard adds sorted ids to an index shard. Empty in-memory shards are bulk
+// loaded; ids are inserted one by one into other shards, or if that fails.
+func (df *DataFrame) loadShard(shard int, ids []int) {
+	if tree, ok := df.Indexes[shard].(*db.BPlusTree); ok && tree.Empty() {
+		if loaded, err := db.BulkLoad(ids, indexFillFactor); err == nil {
+			df.Indexes[shard] = loaded
+			return
+		}
+	}
+	index := df.Indexes[shard]
+	for _, id := range ids {
+		if !index.Search(id) {
+			index.Insert(id)
+		}
+	}
+}
+
+// pagedIndex is an index shard stored in a page file. Errors reading or writing
+// the page file are printed, as IDIndex has no way to return them.
+type pagedIndex struct {
+	tree   *db.PagedBPlusTree
+	closed bool
+}
+
+func (ix *pagedIndex) Insert(id int) {
+	if err := ix.tree.Insert(id); err != nil {
+		fmt.Printf("Error inserting %d into index: %v\n", id, err)
+	}
+}
+
+func (ix *pagedIndex) Delete(id int) {
+	if err := ix.tree.Delete(id); err != nil {
+		fmt.Printf("Error deleting %d from index: %v\n", id, err)
+	}
+}
+
+func (ix *pagedIndex) Search(id int) bool {
+	found, err := ix.tree.Search(id)
+	if err != nil {
+		fmt.Printf("Error searching index for %d: %v\n", id, err)
+	}
+	return found
+}
+
+func (ix *pagedIndex) Range(lo, hi int) []int {
+	ids, err := ix.tree.Range(lo, hi)
+	if err != nil {
+		fmt.Printf("Error reading index: %v\n", err)
+	}
+	return ids
+}
+
+func (ix *pagedIndex) Ceiling(id int) (int, bool) {
+	next, ok, err := ix.tree.Ceiling(id)
+	if err != nil {
+		fmt.Printf("Error reading index: %v\n", err)
+	}
+	return next, ok
+}
+
+func (ix *pagedIndex) Keys() []int {
+	ids, err := ix.tree.Keys()
+	if err != nil {
+		fmt.Printf("Error reading index: %v\n", err)
+	}
+	return ids
+}
+
+func (ix *pagedIndex) Empty() bool {
+	return ix.tree.Len() == 0
+}
+
+func (ix *pagedIndex) Len() int {
+	return ix.tree.Len()
+}
+
+func (ix *pagedIndex) Select(ranks []int) []int {
+	ids, err := ix.tree.Select(ranks)
+	if err != nil {
+		fmt.Printf("Error reading index: %v\n", err)
+	}
+	return ids
+}
+
+// close flushes and closes the page file once.
+func (ix *pagedIndex) close() error {
+	if ix.closed {
+		return nil
+	}
+	ix.closed = true
+	return ix.tree.Close()
+}
@@ -0,0 +1,153 @@
+package dataframe
+
+import (
+	"container/heap"
+	"fmt"
+	"iter"
+	"math"
+)
+
+// Chunks iterates over the chunks of the DataFrame in id order, taking cached
+// rows from memory and the rest from the chunk files. Only one chunk is held
+// at a time: the next chunk is looked up in the B+Tree indexes when it is
+// reached, from the smallest id of every index shard not yet passed, so the
+// ids of the DataFrame are never collected. No lock is held while the loop
+// body runs, so the body may write to the DataFrame; rows written to chunks
+// not yet reached are seen, and those written to chunks already passed are
+// not. After a write every shard is looked up again. Rows must not be
+// modified. A chunk that cannot be read ends the iteration with an error
+// printed; ChunksErr yields it instead.
+func (df *DataFrame) Chunks() iter.Seq[Chunk] {
+	return func(yield func(Chunk) bool) {
+		for chunk, err := range df.ChunksErr() {
+			if err != nil {
+				fmt.Printf("Error reading chunk %d: %v\n", chunk.ID, err)
+				return
+			}
+			if !yield(chunk) {
+				return
+			}
+		}
+	}
+}
+
+// ChunksErr is Chunks yielding the error that ends the iteration, along with
+// a Chunk holding only the id of the chunk that could not be read.
+func (df *DataFrame) ChunksErr() iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		var heads shardHeads
+		var version uint64
+		for lo := math.MinInt; ; {
+			df.mutex.RLock()
+			if heads == nil || df.version != version {
+				// Written ids may lie before the heads, so every shard is looked up again
+				heads, version = df.indexHeads(lo), df.version
+			}
+			task, ok := df.nextChunk(&heads)
+			var chunk Chunk
+			var err error
+			if ok {
+				chunk, err = df.loadChunk(task)
+			}
+			df.mutex.RUnlock()
+
+			if !ok {
+				return
+			}
+			if err != nil {
+				yield(Chunk{ID: task.chunkID}, err)
+				return
+			}
+			if len(chunk.IDs) > 0 && !yield(chunk, nil) {
+				return
+			}
+			lo = (task.chunkID + 1) * chunkSize
+		}
+	}
+}
+
+// shardHead is the smallest id of an index shard not yet iterated over.
+type shardHead struct {
+	id, shard int
+}
+
+// shardHeads is a min-heap of the heads of the index shards, by id.
+type shardHeads []shardHead
+
+func (h shardHeads) Len() int            { return len(h) }
+func (h shardHeads) Less(i, j int) bool  { return h[i].id < h[j].id }
+func (h shardHeads) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *shardHeads) Push(x interface{}) { *h = append(*h, x.(shardHead)) }
+func (h *shardHeads) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// indexHeads returns the smallest id at or above lo of every index shard that
+// has one. The caller must hold df.mutex.
+func (df *DataFrame) indexHeads(lo int) shardHeads {
+	heads := make(shardHeads, 0, len(df.Indexes))
+	for shard, index := range df.Indexes {
+		if id, ok := index.Ceiling(lo); ok {
+			heads = append(heads, shardHead{id, shard})
+		}
+	}
+	heap.Init(&heads)
+	return heads
+}
+
+// nextChunk returns the ids of the chunk of the smallest head, and moves the
+// head of its shard past the chunk. The caller must hold df.mutex.
+func (df *DataFrame) nextChunk(heads *shardHeads) (chunkTask, bool) {
+	if heads.Len() == 0 {
+		return chunkTask{}, false
+	}
+	head := (*heads)[0]
+	chunkID := head.id / chunkSize
+	end := (chunkID + 1) * chunkSize
+	index := df.Indexes[head.shard]
+	task := chunkTask{chunkID: chunkID, ids: index.Range(head.id, end-1)}
+	if next, ok := index.Ceiling(end); ok {
+		(*heads)[0].id = next
+		heap.Fix(heads, 0)
+	} else {
+		heap.Pop(heads)
+	}
+	return task, true
+}
+
+// All iterates over the ids and rows of the DataFrame in id order, one chunk
+// at a time as Chunks does.
+func (df *DataFrame) All() iter.Seq2[int, Row] {
+	return func(yield func(int, Row) bool) {
+		for chunk := range df.Chunks() {
+			for i, id := range chunk.IDs {
+				if !yield(id, chunk.Rows[i]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Column iterates over the values of a column in id order, one chunk at a
+// time as Chunks does. Rows without the column give nil, and a column not in
+// the schema ends the iteration at once with an error printed.
+func (df *DataFrame) Column(column string) iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		df.mutex.RLock()
+		found := hasField(df.fields, column)
+		df.mutex.RUnlock()
+		if !found {
+			fmt.Printf("Error iterating over column %s: column not found\n", column)
+			return
+		}
+		for _, row := range df.All() {
+			if !yield(row[column]) {
+				return
+			}
+		}
+	}
+}
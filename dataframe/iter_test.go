@@ -0,0 +1,94 @@
+package dataframe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChunksWritesDuringIteration(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []FrameOption
+	}{
+		{"memory index", nil},
+		{"paged index", []FrameOption{WithPagedIndex(4)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "items")
+			df, want := durableItems(t, dir, 2500, tt.opts...)
+			defer df.Close()
+			df.InsertRow(1000000, durableItem{"far", 1}) // Past a long run of empty chunks
+			want[1000000] = map[string]interface{}{"Name": "far", "Qty": 1}
+
+			var ids []int
+			var chunks []int
+			for chunk := range df.Chunks() {
+				chunks = append(chunks, chunk.ID)
+				for i, id := range chunk.IDs {
+					ids = append(ids, id)
+					if !reflect.DeepEqual(map[string]interface{}(chunk.Rows[i]), want[id]) {
+						t.Fatalf("row %d = %v, want %v", id, chunk.Rows[i], want[id])
+					}
+				}
+				if chunk.ID == 0 {
+					df.InsertRow(5, durableItem{"passed", 5})    // Chunk already passed
+					df.InsertRow(2700, durableItem{"ahead", 27}) // Chunk not yet reached
+					if err := df.DeleteRow(1500); err != nil {
+						t.Fatal(err)
+					}
+					want[2700] = map[string]interface{}{"Name": "ahead", "Qty": 27}
+					delete(want, 1500)
+				}
+			}
+
+			if !reflect.DeepEqual(chunks, []int{0, 1, 2, 1000}) {
+				t.Errorf("chunks = %v, want [0 1 2 1000]", chunks)
+			}
+			var wantIDs []int
+			for id := 0; id <= 2700; id++ {
+				if _, ok := want[id]; ok {
+					wantIDs = append(wantIDs, id)
+				}
+			}
+			wantIDs = append(wantIDs, 1000000)
+			if !reflect.DeepEqual(ids, wantIDs) {
+				t.Errorf("iterated over %d ids, want %d", len(ids), len(wantIDs))
+			}
+		})
+	}
+}
+
+func TestChunksErr(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "items")
+	df, _ := durableItems(t, dir, 2500)
+	defer df.Close()
+	if err := os.Remove(filepath.Join(dir, chunkName(1))); err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []int
+	var failed error
+	for chunk, err := range df.ChunksErr() {
+		if err != nil {
+			if chunk.ID != 1 {
+				t.Errorf("error reported for chunk %d, want 1", chunk.ID)
+			}
+			failed = err
+			continue
+		}
+		chunks = append(chunks, chunk.ID)
+	}
+	if failed == nil || !reflect.DeepEqual(chunks, []int{0}) {
+		t.Errorf("ChunksErr yielded chunks %v and error %v, want chunk 0 and an error", chunks, failed)
+	}
+
+	n := 0
+	for range df.All() {
+		n++
+	}
+	if n != chunkSize {
+		t.Errorf("All yielded %d rows before the missing chunk, want %d", n, chunkSize)
+	}
+}
@@ -0,0 +1,1064 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LazyFrame records operations on DataFrames as a logical plan instead of
+// materializing a new DataFrame, and chunk files, for every step. The plan is
+// optimized and executed by Collect.
+type LazyFrame struct {
+	plan planNode
+}
+
+// LazyGroupBy is a grouping waiting for its aggregations.
+type LazyGroupBy struct {
+	input planNode
+	keys  []string
+}
+
+// Agg is an aggregation computed per group by LazyGroupBy.Agg.
+type Agg struct {
+	fn     string
+	column string
+	alias  string
+}
+
+// Sum adds up the values of a numeric column.
+func Sum(column string) Agg { return Agg{fn: "sum", column: column} }
+
+// Mean averages the values of a numeric column.
+func Mean(column string) Agg { return Agg{fn: "mean", column: column} }
+
+// Min takes the smallest value of a column.
+func Min(column string) Agg { return Agg{fn: "min", column: column} }
+
+// Max takes the largest value of a column.
+func Max(column string) Agg { return Agg{fn: "max", column: column} }
+
+// Count counts the non-null values of a column.
+func Count(column string) Agg { return Agg{fn: "count", column: column} }
+
+// As sets the name of the output column.
+func (a Agg) As(name string) Agg {
+	a.alias = name
+	return a
+}
+
+// Name returns the output column name, <column>_<fn> unless set with As.
+func (a Agg) Name() string {
+	if a.alias != "" {
+		return a.alias
+	}
+	return a.column + "_" + a.fn
+}
+
+func (a Agg) String() string {
+	return fmt.Sprintf("%s(%s) AS %s", a.fn, a.column, a.Name())
+}
+
+// planNode is a node of a logical query plan.
+type planNode interface {
+	fields() []Field
+	describe() string
+	inputs() []planNode
+}
+
+// scanNode reads rows from a DataFrame. Predicates on the row id are pushed
+// into it as index lookups and the remaining ones are evaluated while reading.
+type scanNode struct {
+	df      *DataFrame
+	columns []string // Columns to read, nil reads every column
+	lo, hi  int      // Id bounds answered by the B+Tree indexes
+	ids     []int    // Exact ids to look up, nil when unset
+	filter  []Expr   // Residual predicates evaluated per row
+}
+
+type filterNode struct {
+	input planNode
+	pred  Expr
+}
+
+type projectNode struct {
+	input   planNode
+	columns []string
+}
+
+type joinNode struct {
+	left, right planNode
+	how         string
+	on          []string // Key columns, joins on the row id when empty
+}
+
+type groupByNode struct {
+	input planNode
+	keys  []string
+	aggs  []Agg
+}
+
+// Lazy starts a lazy query on the DataFrame.
+func (df *DataFrame) Lazy() *LazyFrame {
+	return &LazyFrame{plan: &scanNode{df: df, lo: math.MinInt, hi: math.MaxInt}}
+}
+
+// Filter keeps the rows for which pred is true.
+func (lf *LazyFrame) Filter(pred Expr) *LazyFrame {
+	return &LazyFrame{plan: &filterNode{input: lf.plan, pred: pred}}
+}
+
+// Select keeps only the given columns, in the given order.
+func (lf *LazyFrame) Select(columns ...string) *LazyFrame {
+	return &LazyFrame{plan: &projectNode{input: lf.plan, columns: columns}}
+}
+
+// Join combines two frames on the given key columns, or on the row id when no
+// keys are given. how is one of "inner", "left", "right" or "outer". Columns of
+// other that clash with columns of lf are suffixed with "_other".
+func (lf *LazyFrame) Join(other *LazyFrame, how string, on ...string) *LazyFrame {
+	return &LazyFrame{plan: &joinNode{left: lf.plan, right: other.plan, how: how, on: on}}
+}
+
+// GroupBy groups rows sharing the same values in the key columns.
+func (lf *LazyFrame) GroupBy(keys ...string) *LazyGroupBy {
+	return &LazyGroupBy{input: lf.plan, keys: keys}
+}
+
+// Agg computes the aggregations for every group. The result has one row per
+// group holding the key columns followed by the aggregated columns.
+func (g *LazyGroupBy) Agg(aggs ...Agg) *LazyFrame {
+	return &LazyFrame{plan: &groupByNode{input: g.input, keys: g.keys, aggs: aggs}}
+}
+
+// Explain returns the optimized plan that Collect will execute.
+func (lf *LazyFrame) Explain() string {
+	var b strings.Builder
+	explainNode(&b, optimize(lf.plan), 0)
+	return b.String()
+}
+
+func explainNode(b *strings.Builder, node planNode, depth int) {
+	b.WriteString(strings.Repeat("  ", depth) + node.describe() + "\n")
+	for _, input := range node.inputs() {
+		explainNode(b, input, depth+1)
+	}
+}
+
+// Collect optimizes and executes the plan and materializes the result as a new DataFrame.
+func (lf *LazyFrame) Collect() (*DataFrame, error) {
+	if err := validate(lf.plan); err != nil {
+		return nil, err
+	}
+	plan := optimize(lf.plan)
+	res, err := execute(plan)
+	if err != nil {
+		return nil, err
+	}
+	return newDataFrameFromRows(planName(plan), plan.fields(), res.ids, res.rows)
+}
+
+func (n *scanNode) fields() []Field {
+	if n.columns == nil {
+		return n.df.fields
+	}
+	return pickFields(n.df.fields, n.columns)
+}
+
+func (n *scanNode) describe() string {
+	var parts []string
+	if n.columns != nil {
+		parts = append(parts, "columns=["+strings.Join(n.columns, ", ")+"]")
+	}
+	if n.ids != nil {
+		parts = append(parts, fmt.Sprintf("ids=%v", n.ids))
+	}
+	if n.lo != math.MinInt || n.hi != math.MaxInt {
+		parts = append(parts, fmt.Sprintf("id range=[%s, %s]", bound(n.lo), bound(n.hi)))
+	}
+	if len(n.filter) > 0 {
+		parts = append(parts, "filter="+conjoin(n.filter).String())
+	}
+	return strings.TrimSpace("Scan " + n.df.Name + " " + strings.Join(parts, " "))
+}
+
+func bound(v int) string {
+	switch v {
+	case math.MinInt:
+		return "-inf"
+	case math.MaxInt:
+		return "+inf"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (n *scanNode) inputs() []planNode { return nil }
+
+func (n *filterNode) fields() []Field    { return n.input.fields() }
+func (n *filterNode) describe() string   { return "Filter " + n.pred.String() }
+func (n *filterNode) inputs() []planNode { return []planNode{n.input} }
+
+func (n *projectNode) fields() []Field { return pickFields(n.input.fields(), n.columns) }
+func (n *projectNode) describe() string {
+	return "Select [" + strings.Join(n.columns, ", ") + "]"
+}
+func (n *projectNode) inputs() []planNode { return []planNode{n.input} }
+
+func (n *joinNode) fields() []Field {
+	fields, _ := joinFields(n.left.fields(), n.right.fields(), n.on)
+	return fields
+}
+
+func (n *joinNode) describe() string {
+	if len(n.on) == 0 {
+		return "Join " + n.how + " on id()"
+	}
+	return "Join " + n.how + " on [" + strings.Join(n.on, ", ") + "]"
+}
+
+func (n *joinNode) inputs() []planNode { return []planNode{n.left, n.right} }
+
+func (n *groupByNode) fields() []Field {
+	input := n.input.fields()
+	fields := pickFields(input, n.keys)
+	for _, agg := range n.aggs {
+		fields = append(fields, aggField(input, agg))
+	}
+	return fields
+}
+
+// aggField returns the output column of an aggregation over the input columns.
+func aggField(input []Field, agg Agg) Field {
+	var t reflect.Type
+	switch agg.fn {
+	case "count":
+		t = reflect.TypeOf(0)
+	case "sum", "mean":
+		t = reflect.TypeOf(0.0)
+	default:
+		if f := pickFields(input, []string{agg.column}); len(f) == 1 {
+			t = f[0].Type
+		}
+	}
+	return Field{Name: agg.Name(), Type: t}
+}
+
+func (n *groupByNode) describe() string {
+	aggs := make([]string, len(n.aggs))
+	for i, agg := range n.aggs {
+		aggs[i] = agg.String()
+	}
+	return "GroupBy [" + strings.Join(n.keys, ", ") + "] Agg [" + strings.Join(aggs, ", ") + "]"
+}
+
+func (n *groupByNode) inputs() []planNode { return []planNode{n.input} }
+
+// pickFields returns the named fields in the given order, skipping unknown names.
+func pickFields(fields []Field, names []string) []Field {
+	picked := make([]Field, 0, len(names))
+	for _, name := range names {
+		for _, field := range fields {
+			if field.Name == name {
+				picked = append(picked, field)
+				break
+			}
+		}
+	}
+	return picked
+}
+
+func hasField(fields []Field, name string) bool {
+	return len(pickFields(fields, []string{name})) == 1
+}
+
+// joinFields returns the output schema of a join together with the mapping from
+// output column names of the right side back to its input column names.
+func joinFields(left, right []Field, on []string) ([]Field, map[string]string) {
+	fields := append([]Field(nil), left...)
+	rename := make(map[string]string)
+	for _, field := range right {
+		if contains(on, field.Name) {
+			continue
+		}
+		name := field.Name
+		if hasField(fields, name) {
+			name += "_other"
+		}
+		rename[name] = field.Name
+		fields = append(fields, Field{Name: name, Type: field.Type})
+	}
+	return fields, rename
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// planName names the DataFrame produced by a plan after its sources.
+func planName(node planNode) string {
+	switch n := node.(type) {
+	case *scanNode:
+		return n.df.Name
+	case *joinNode:
+		return planName(n.left) + "_" + planName(n.right) + "_join"
+	default:
+		return planName(node.inputs()[0])
+	}
+}
+
+// validate checks that every column referenced by the plan exists.
+func validate(node planNode) error {
+	for _, input := range node.inputs() {
+		if err := validate(input); err != nil {
+			return err
+		}
+	}
+
+	var needed []string
+	var available []Field
+	switch n := node.(type) {
+	case *filterNode:
+		needed, available = n.pred.Columns(), n.input.fields()
+	case *projectNode:
+		needed, available = n.columns, n.input.fields()
+	case *groupByNode:
+		needed, available = n.keys, n.input.fields()
+		for _, agg := range n.aggs {
+			needed = append(needed, agg.column)
+		}
+	case *joinNode:
+		switch n.how {
+		case "inner", "left", "right", "outer":
+		default:
+			return fmt.Errorf("unsupported join type: %s", n.how)
+		}
+		for _, key := range n.on {
+			if !hasField(n.left.fields(), key) || !hasField(n.right.fields(), key) {
+				return fmt.Errorf("join key %s not found in both frames", key)
+			}
+		}
+	}
+	for _, name := range needed {
+		if !hasField(available, name) {
+			return fmt.Errorf("column %s not found", name)
+		}
+	}
+	return nil
+}
+
+// optimize rewrites a plan so that predicates are evaluated as early as possible,
+// ideally as index lookups, and only the columns that are needed are read.
+func optimize(node planNode) planNode {
+	node = pushDown(node, nil)
+	prune(node, columnSet(node.fields()))
+	return node
+}
+
+// splitConjuncts flattens nested ANDs into a list of predicates.
+func splitConjuncts(pred Expr) []Expr {
+	if pred.op != "and" {
+		return []Expr{pred}
+	}
+	var preds []Expr
+	for _, arg := range pred.args {
+		preds = append(preds, splitConjuncts(arg)...)
+	}
+	return preds
+}
+
+// conjoin combines predicates with AND.
+func conjoin(preds []Expr) Expr {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return preds[0].And(preds[1:]...)
+}
+
+// withFilter wraps node in a filter on preds, if there are any.
+func withFilter(node planNode, preds []Expr) planNode {
+	if len(preds) == 0 {
+		return node
+	}
+	return &filterNode{input: node, pred: conjoin(preds)}
+}
+
+// onlyColumns reports whether pred references no row id and only the given columns.
+func onlyColumns(pred Expr, columns []string) bool {
+	if pred.usesID() {
+		return false
+	}
+	for _, col := range pred.Columns() {
+		if !contains(columns, col) {
+			return false
+		}
+	}
+	return true
+}
+
+// renameColumns rewrites the column references of an expression.
+func renameColumns(e Expr, mapping map[string]string) Expr {
+	if e.op == "col" {
+		if name, ok := mapping[e.name]; ok {
+			return Col(name)
+		}
+		return e
+	}
+	if len(e.args) == 0 {
+		return e
+	}
+	args := make([]Expr, len(e.args))
+	for i, arg := range e.args {
+		args[i] = renameColumns(arg, mapping)
+	}
+	e.args = args
+	return e
+}
+
+// pushDown moves the pending predicates, and any filters found on the way,
+// as close to the scans as the semantics of each node allow.
+func pushDown(node planNode, preds []Expr) planNode {
+	switch n := node.(type) {
+	case *filterNode:
+		return pushDown(n.input, append(preds, splitConjuncts(n.pred)...))
+
+	case *projectNode:
+		return &projectNode{input: pushDown(n.input, preds), columns: n.columns}
+
+	case *groupByNode:
+		var below, above []Expr
+		for _, pred := range preds {
+			if onlyColumns(pred, n.keys) {
+				below = append(below, pred)
+			} else {
+				above = append(above, pred)
+			}
+		}
+		return withFilter(&groupByNode{input: pushDown(n.input, below), keys: n.keys, aggs: n.aggs}, above)
+
+	case *joinNode:
+		leftCols := fieldNames(n.left.fields())
+		_, rename := joinFields(n.left.fields(), n.right.fields(), n.on)
+		rightCols := append([]string(nil), n.on...)
+		for name := range rename {
+			rightCols = append(rightCols, name)
+		}
+
+		// Predicates may only move below a join into a side whose rows are not
+		// padded with nulls by it, otherwise they would drop the padded rows.
+		pushLeft := n.how == "inner" || n.how == "left"
+		pushRight := n.how == "inner" || n.how == "right"
+
+		var left, right, above []Expr
+		for _, pred := range preds {
+			switch {
+			case len(n.on) > 0 && onlyColumns(pred, n.on) && n.how == "inner":
+				left = append(left, pred)
+				right = append(right, pred)
+			case pushLeft && onlyColumns(pred, leftCols):
+				left = append(left, pred)
+			case pushRight && onlyColumns(pred, rightCols):
+				right = append(right, renameColumns(pred, rename))
+			default:
+				above = append(above, pred)
+			}
+		}
+		join := &joinNode{left: pushDown(n.left, left), right: pushDown(n.right, right), how: n.how, on: n.on}
+		return withFilter(join, above)
+
+	case *scanNode:
+		scan := *n
+		scan.filter = append([]Expr(nil), n.filter...)
+		for _, pred := range preds {
+			if !scan.pushIDPredicate(pred) {
+				scan.filter = append(scan.filter, pred)
+			}
+		}
+		return &scan
+	}
+	return node
+}
+
+// pushIDPredicate turns a comparison between the row id and a literal into an
+// index lookup. It reports false when the predicate has another shape.
+func (n *scanNode) pushIDPredicate(pred Expr) bool {
+	if pred.op == "in" && pred.args[0].op == "id" {
+		var ids []int
+		for _, arg := range pred.args[1:] {
+			id, ok := arg.value.(int)
+			if arg.op != "lit" || !ok {
+				return false
+			}
+			ids = append(ids, id)
+		}
+		n.restrictIDs(ids)
+		return true
+	}
+
+	if len(pred.args) != 2 {
+		return false
+	}
+	op, left, right := pred.op, pred.args[0], pred.args[1]
+	if left.op == "lit" && right.op == "id" {
+		// Flip "lit op id" into "id op lit"
+		flipped := map[string]string{"==": "==", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+		op, left, right = flipped[op], right, left
+	}
+	if left.op != "id" || right.op != "lit" {
+		return false
+	}
+	v, ok := right.value.(int)
+	if !ok {
+		return false
+	}
+
+	switch op {
+	case "==":
+		n.restrictIDs([]int{v})
+	case "<":
+		if v == math.MinInt {
+			n.restrictIDs([]int{})
+		} else {
+			n.hi = minInt(n.hi, v-1)
+		}
+	case "<=":
+		n.hi = minInt(n.hi, v)
+	case ">":
+		if v == math.MaxInt {
+			n.restrictIDs([]int{})
+		} else {
+			n.lo = maxInt(n.lo, v+1)
+		}
+	case ">=":
+		n.lo = maxInt(n.lo, v)
+	default:
+		return false
+	}
+	return true
+}
+
+// restrictIDs intersects the ids to look up with the given ids.
+func (n *scanNode) restrictIDs(ids []int) {
+	if n.ids == nil {
+		n.ids = append([]int{}, ids...)
+		return
+	}
+	allowed := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	kept := []int{}
+	for _, id := range n.ids {
+		if allowed[id] {
+			kept = append(kept, id)
+		}
+	}
+	n.ids = kept
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func fieldNames(fields []Field) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+func columnSet(fields []Field) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		set[field.Name] = true
+	}
+	return set
+}
+
+// prune narrows every node to the columns its parent needs, so that scans copy
+// only those fields out of each row map.
+func prune(node planNode, required map[string]bool) {
+	switch n := node.(type) {
+	case *scanNode:
+		needed := make(map[string]bool)
+		for col := range required {
+			needed[col] = true
+		}
+		for _, pred := range n.filter {
+			for _, col := range pred.Columns() {
+				needed[col] = true
+			}
+		}
+		var columns []string
+		for _, field := range n.df.fields {
+			if needed[field.Name] {
+				columns = append(columns, field.Name)
+			}
+		}
+		if len(columns) < len(n.df.fields) {
+			n.columns = append([]string{}, columns...)
+		}
+
+	case *filterNode:
+		needed := make(map[string]bool)
+		for col := range required {
+			needed[col] = true
+		}
+		for _, col := range n.pred.Columns() {
+			needed[col] = true
+		}
+		prune(n.input, needed)
+
+	case *projectNode:
+		var columns []string
+		for _, col := range n.columns {
+			if required[col] {
+				columns = append(columns, col)
+			}
+		}
+		n.columns = columns
+		prune(n.input, columnSet(pickFields(n.input.fields(), columns)))
+
+	case *joinNode:
+		_, rename := joinFields(n.left.fields(), n.right.fields(), n.on)
+		left := make(map[string]bool)
+		right := make(map[string]bool)
+		for _, key := range n.on {
+			left[key] = true
+			right[key] = true
+		}
+		for col := range required {
+			if name, ok := rename[col]; ok {
+				right[name] = true
+			} else {
+				left[col] = true
+			}
+		}
+		prune(n.left, left)
+		prune(n.right, right)
+
+	case *groupByNode:
+		needed := make(map[string]bool)
+		for _, key := range n.keys {
+			needed[key] = true
+		}
+		for _, agg := range n.aggs {
+			needed[agg.column] = true
+		}
+		prune(n.input, needed)
+	}
+}
+
+// result is the output of an executed plan node.
+type result struct {
+	ids  []int
+	rows []Row
+}
+
+func execute(node planNode) (*result, error) {
+	switch n := node.(type) {
+	case *scanNode:
+		return n.execute()
+
+	case *filterNode:
+		in, err := execute(n.input)
+		if err != nil {
+			return nil, err
+		}
+		out := &result{}
+		for i, row := range in.rows {
+			ok, err := n.pred.Match(in.ids[i], row)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out.ids = append(out.ids, in.ids[i])
+				out.rows = append(out.rows, row)
+			}
+		}
+		return out, nil
+
+	case *projectNode:
+		in, err := execute(n.input)
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range in.rows {
+			projected := make(Row, len(n.columns))
+			for _, col := range n.columns {
+				projected[col] = row[col]
+			}
+			in.rows[i] = projected
+		}
+		return in, nil
+
+	case *joinNode:
+		return n.execute()
+
+	case *groupByNode:
+		return n.execute()
+	}
+	return nil, fmt.Errorf("unsupported plan node %T", node)
+}
+
+func (n *scanNode) execute() (*result, error) {
+	return n.scan(nil)
+}
+
+// scan reads the rows of the scan, skipping chunks whose Bloom filters hold
+// none of the hashes of a column of probe.
+func (n *scanNode) scan(probe map[string][]uint64) (*result, error) {
+	df := n.df
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	var ids []int
+	if n.ids != nil {
+		for _, id := range n.ids {
+			if id >= n.lo && id <= n.hi && df.hasRow(id) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Ints(ids)
+	} else if n.lo != math.MinInt || n.hi != math.MaxInt {
+		ids = df.idRange(n.lo, n.hi)
+	} else {
+		ids = df.rowIDs()
+	}
+	ids = df.pruneChunks(ids, n.filter)
+	ids = df.pruneCategories(ids, n.filter)
+	ids = df.probeChunks(ids, probe)
+
+	columns := n.columns
+	if columns == nil {
+		columns = fieldNames(df.fields)
+	}
+
+	// Chunks are filtered and projected in parallel, then concatenated in id order
+	parts, err := mapChunks(df, ids, func(chunk Chunk) (*result, error) {
+		part := &result{}
+	rows:
+		for i, id := range chunk.IDs {
+			for _, pred := range n.filter {
+				ok, err := pred.Match(id, chunk.Rows[i])
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue rows
+				}
+			}
+			row := make(Row, len(columns))
+			for _, col := range columns {
+				row[col] = chunk.Rows[i][col]
+			}
+			part.ids = append(part.ids, id)
+			part.rows = append(part.rows, row)
+		}
+		return part, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &result{}
+	for _, part := range parts {
+		out.ids = append(out.ids, part.ids...)
+		out.rows = append(out.rows, part.rows...)
+	}
+	return out, nil
+}
+
+// groupKey builds a hashable key from the values of the given columns. Each
+// value is prefixed with its length, so that no value can be mistaken for the
+// separator between two others. Values that are equal share a key as they
+// share a Bloom filter hash: numbers of any type by their value, and times by
+// their instant, whatever their location or monotonic clock reading.
+func groupKey(id int, row Row, columns []string) string {
+	if len(columns) == 0 {
+		return fmt.Sprint(id)
+	}
+	var key strings.Builder
+	for _, col := range columns {
+		var part string
+		switch val := row[col].(type) {
+		case string:
+			part = "string:" + val // As formatted below, without its cost for the common case
+		default:
+			val, _ = deref(val) // Equal values behind different pointers are one key
+			if number, ok := numberKey(val); ok {
+				part = "number:" + number
+			} else if t, ok := val.(time.Time); ok {
+				part = "time:" + strconv.FormatInt(t.Unix(), 10) + "." + strconv.Itoa(t.Nanosecond())
+			} else {
+				part = fmt.Sprintf("%T:%v", val, val)
+			}
+		}
+		key.WriteString(strconv.Itoa(len(part)))
+		key.WriteByte(':')
+		key.WriteString(part)
+	}
+	return key.String()
+}
+
+// numberKey formats a number of a builtin numeric type so that equal numbers
+// format alike: integral values as integers, other floats by their shortest
+// representation, and -0 as 0.
+func numberKey(val interface{}) (string, bool) {
+	var f float64
+	switch v := val.(type) {
+	case int, int8, int16, int32, int64:
+		return strconv.FormatInt(reflect.ValueOf(v).Int(), 10), true
+	case uint, uint8, uint16, uint32, uint64:
+		return strconv.FormatUint(reflect.ValueOf(v).Uint(), 10), true
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		return "", false
+	}
+	switch {
+	case f != math.Trunc(f):
+	case f >= 0 && f < 1<<64:
+		return strconv.FormatUint(uint64(f), 10), true
+	case f < 0 && f >= -1<<63:
+		return strconv.FormatInt(int64(f), 10), true
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), true
+}
+
+func (n *joinNode) execute() (*result, error) {
+	right, err := execute(n.right)
+	if err != nil {
+		return nil, err
+	}
+	left, err := n.executeProbe(right)
+	if err != nil {
+		return nil, err
+	}
+	fields, rename := joinFields(n.left.fields(), n.right.fields(), n.on)
+
+	// Build a hash table on the right side and probe it with the left side
+	table := make(map[string][]int)
+	for i, row := range right.rows {
+		key := groupKey(right.ids[i], row, n.on)
+		table[key] = append(table[key], i)
+	}
+
+	out := &result{}
+	emit := func(id int, l, r Row) {
+		row := make(Row, len(fields))
+		for _, field := range fields {
+			row[field.Name] = nil
+		}
+		for col, val := range l {
+			row[col] = val
+		}
+		for _, key := range n.on {
+			if l == nil {
+				row[key] = r[key]
+			}
+		}
+		for name, col := range rename {
+			if r != nil {
+				row[name] = r[col]
+			}
+		}
+		if len(n.on) > 0 {
+			// Key joins can repeat rows, so the result is renumbered
+			id = len(out.ids)
+		}
+		out.ids = append(out.ids, id)
+		out.rows = append(out.rows, row)
+	}
+
+	matched := make([]bool, len(right.rows))
+	for i, row := range left.rows {
+		key := groupKey(left.ids[i], row, n.on)
+		matches := table[key]
+		for _, j := range matches {
+			matched[j] = true
+			emit(left.ids[i], row, right.rows[j])
+		}
+		if len(matches) == 0 && (n.how == "left" || n.how == "outer") {
+			emit(left.ids[i], row, nil)
+		}
+	}
+	if n.how == "right" || n.how == "outer" {
+		for j, row := range right.rows {
+			if !matched[j] {
+				emit(right.ids[j], nil, row)
+			}
+		}
+	}
+
+	if len(n.on) == 0 {
+		// Id joins keep the ids, so restore id order after appending unmatched rows
+		sortResult(out)
+	}
+	return out, nil
+}
+
+// executeProbe executes the probe side of the join. When unmatched rows of the
+// probe side are dropped and it is a scan, chunks whose Bloom filters hold none
+// of the keys of the build side are skipped. Null keys match each other, so key
+// columns with nulls on the build side are not probed.
+func (n *joinNode) executeProbe(build *result) (*result, error) {
+	scan, ok := n.left.(*scanNode)
+	if !ok || len(n.on) == 0 || (n.how != "inner" && n.how != "right") || len(build.rows) > maxBloomProbes {
+		return execute(n.left)
+	}
+
+	keys := make(map[string][]uint64, len(n.on))
+columns:
+	for _, col := range n.on {
+		seen := make(map[uint64]bool)
+		hashes := []uint64{}
+		for _, row := range build.rows {
+			h, ok := bloomHash(row[col])
+			if !ok {
+				continue columns
+			}
+			if !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+		}
+		keys[col] = hashes
+	}
+	return scan.scan(keys)
+}
+
+// sortResult orders a result by id.
+func sortResult(res *result) {
+	sort.Sort(resultByID{res})
+}
+
+type resultByID struct{ *result }
+
+func (r resultByID) Len() int           { return len(r.ids) }
+func (r resultByID) Less(i, j int) bool { return r.ids[i] < r.ids[j] }
+func (r resultByID) Swap(i, j int) {
+	r.ids[i], r.ids[j] = r.ids[j], r.ids[i]
+	r.rows[i], r.rows[j] = r.rows[j], r.rows[i]
+}
+
+// aggState accumulates one aggregation of one group.
+type aggState struct {
+	count int
+	sum   float64
+	best  interface{}
+}
+
+func (s *aggState) add(fn string, val interface{}) error {
+	val, ok := deref(val)
+	if !ok {
+		return nil
+	}
+	s.count++
+	switch fn {
+	case "sum", "mean":
+		f, ok := ToFloat64(val)
+		if !ok {
+			return fmt.Errorf("non-numeric value encountered: %v", val)
+		}
+		s.sum += f
+	case "min", "max":
+		if s.best == nil {
+			s.best = val
+			return nil
+		}
+		cmp, ok := compareValues(val, s.best)
+		if !ok {
+			return fmt.Errorf("cannot compare %v with %v", val, s.best)
+		}
+		if (fn == "min" && cmp < 0) || (fn == "max" && cmp > 0) {
+			s.best = val
+		}
+	}
+	return nil
+}
+
+func (s *aggState) value(fn string) interface{} {
+	switch fn {
+	case "count":
+		return s.count
+	case "sum":
+		return s.sum
+	case "mean":
+		if s.count == 0 {
+			return nil
+		}
+		return s.sum / float64(s.count)
+	default:
+		return s.best
+	}
+}
+
+func (n *groupByNode) execute() (*result, error) {
+	in, err := execute(n.input)
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		keys Row
+		aggs []aggState
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for i, row := range in.rows {
+		key := groupKey(in.ids[i], row, n.keys)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{keys: make(Row, len(n.keys)), aggs: make([]aggState, len(n.aggs))}
+			for _, col := range n.keys {
+				g.keys[col] = row[col]
+			}
+			groups[key] = g
+			order = append(order, key)
+		}
+		for j, agg := range n.aggs {
+			if err := g.aggs[j].add(agg.fn, row[agg.column]); err != nil {
+				return nil, fmt.Errorf("error aggregating column %s: %v", agg.column, err)
+			}
+		}
+	}
+
+	out := &result{}
+	for i, key := range order {
+		g := groups[key]
+		row := make(Row, len(n.keys)+len(n.aggs))
+		for col, val := range g.keys {
+			row[col] = val
+		}
+		for j, agg := range n.aggs {
+			row[agg.Name()] = g.aggs[j].value(agg.fn)
+		}
+		out.ids = append(out.ids, i)
+		out.rows = append(out.rows, row)
+	}
+	return out, nil
+}
@@ -0,0 +1,112 @@
+package dataframe
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// resultRows formats the rows of a result with their ids. Key joins and
+// group-bys number their rows, so filters moved below them leave no gaps in
+// the numbering; their rows are compared in order without ids.
+func resultRows(res *result, numbered bool) []string {
+	rows := make([]string, len(res.ids))
+	for i, id := range res.ids {
+		if numbered {
+			id = 0
+		}
+		rows[i] = fmt.Sprintf("%d %v", id, res.rows[i])
+	}
+	return rows
+}
+
+func TestOptimizedPlanMatchesUnoptimized(t *testing.T) {
+	type store struct {
+		Name string
+		City string
+	}
+	items := make([]durableItem, 3000)
+	for i := range items {
+		items[i] = durableItem{[]string{"pen", "ink", "cap", "pad"}[i%4], i % 50}
+	}
+	df, err := NewDataFrame(items, WithCategorical("Name"), WithBloomFilters("Name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	if err := df.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := df.UpdateRow(1200, map[string]interface{}{"Qty": 1000}); err != nil {
+		t.Fatal(err) // Left unflushed
+	}
+	stores, err := NewDataFrame([]store{{"pen", "Oslo"}, {"ink", "Rome"}, {"cup", "Oslo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stores.Close()
+
+	tests := []struct {
+		name     string
+		plan     func() *LazyFrame
+		numbered bool // The plan numbers its rows rather than keeping their ids
+	}{
+		{"id range and column", func() *LazyFrame {
+			return df.Lazy().Filter(ID().Between(500, 1800)).Filter(Col("Qty").Gt(40)).Select("Name", "Qty")
+		}, false},
+		{"id list", func() *LazyFrame {
+			return df.Lazy().Filter(ID().In(3, 999, 1000, 1200, 2999, 5000))
+		}, false},
+		{"conjunction", func() *LazyFrame {
+			return df.Lazy().Filter(Col("Name").Eq("pen").And(Col("Qty").Ge(45), ID().Lt(2000)))
+		}, false},
+		{"disjunction", func() *LazyFrame {
+			return df.Lazy().Filter(Col("Name").Eq("cup").Or(Col("Qty").Eq(1000)))
+		}, false},
+		{"absent value", func() *LazyFrame {
+			return df.Lazy().Filter(Col("Name").In("cup", "mug"))
+		}, false},
+		{"inner join", func() *LazyFrame {
+			return df.Lazy().Join(stores.Lazy(), "inner", "Name").Filter(Col("City").Eq("Oslo").And(Col("Qty").Lt(3)))
+		}, true},
+		{"left join", func() *LazyFrame {
+			return df.Lazy().Join(stores.Lazy(), "left", "Name").Filter(Col("City").IsNull().And(Col("Qty").Eq(7)))
+		}, true},
+		{"right join", func() *LazyFrame {
+			return df.Lazy().Join(stores.Lazy(), "right", "Name").Filter(Col("Qty").IsNull().Or(Col("Qty").Eq(0)))
+		}, true},
+		{"outer join", func() *LazyFrame {
+			return df.Lazy().Join(stores.Lazy(), "outer", "Name").Filter(Col("Name").Ne("pad"))
+		}, true},
+		{"group by", func() *LazyFrame {
+			return df.Lazy().Filter(Col("Qty").Lt(25)).GroupBy("Name").Agg(Sum("Qty").As("total"), Count("Qty")).
+				Filter(Col("Name").Ne("ink").And(Col("total").Gt(100)))
+		}, true},
+		{"select then filter", func() *LazyFrame {
+			return df.Lazy().Select("Qty").Filter(Col("Qty").Between(10, 12))
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := tt.plan().plan
+			if err := validate(plain); err != nil {
+				t.Fatal(err)
+			}
+			want, err := execute(plain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			optimized := optimize(tt.plan().plan)
+			got, err := execute(optimized)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resultRows(got, tt.numbered), resultRows(want, tt.numbered)) {
+				t.Errorf("optimized plan gave %d rows, unoptimized %d\n%s", len(got.ids), len(want.ids), tt.plan().Explain())
+			}
+			if !reflect.DeepEqual(optimized.fields(), plain.fields()) {
+				t.Errorf("optimized plan has fields %v, want %v", optimized.fields(), plain.fields())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package dataframe
+
+import "fmt"
+
+// maxMappedChunks bounds the chunk files a DataFrame keeps memory-mapped. Chunks
+// beyond it are read from the store on every access.
+const maxMappedChunks = 1024
+
+// chunkMapper is implemented by stores whose blobs can be memory-mapped, such as DiskStore.
+type chunkMapper interface {
+	Map(name string) ([]byte, func() error, error)
+}
+
+// mappedChunk is a memory-mapped chunk file.
+type mappedChunk struct {
+	data  []byte
+	unmap func() error
+}
+
+// chunkBytes returns the encoded chunk file of a chunk, memory-mapping it if the
+// store allows. Mapped bytes stay valid until the chunk is unmapped under the
+// write lock, so the caller must hold df.mutex while using them.
+func (df *DataFrame) chunkBytes(chunkID int) ([]byte, error) {
+	mapper, ok := df.store.(chunkMapper)
+	if !ok {
+		return df.store.Get(chunkName(chunkID))
+	}
+
+	df.mapMutex.Lock()
+	defer df.mapMutex.Unlock()
+	if chunk, ok := df.mapped[chunkID]; ok {
+		return chunk.data, nil
+	}
+	if len(df.mapped) >= maxMappedChunks {
+		return df.store.Get(chunkName(chunkID))
+	}
+	data, unmap, err := mapper.Map(chunkName(chunkID))
+	if err != nil {
+		return nil, err
+	}
+	df.mapped[chunkID] = &mappedChunk{data: data, unmap: unmap}
+	return data, nil
+}
+
+// unmapChunks unmaps the chunks with rows or deletes in a flush, so that their
+// new chunk files are mapped on the next read. The caller must hold the write lock.
+func (df *DataFrame) unmapChunks(cache map[int]map[int]interface{}, deleted map[int]map[int]bool) {
+	df.mapMutex.Lock()
+	defer df.mapMutex.Unlock()
+	for chunkID := range cache {
+		df.unmapChunk(chunkID)
+	}
+	for chunkID := range deleted {
+		df.unmapChunk(chunkID)
+	}
+}
+
+// unmapAll unmaps every chunk. The caller must hold the write lock.
+func (df *DataFrame) unmapAll() {
+	df.mapMutex.Lock()
+	defer df.mapMutex.Unlock()
+	for chunkID := range df.mapped {
+		df.unmapChunk(chunkID)
+	}
+}
+
+// unmapChunk unmaps a chunk. The caller must hold df.mapMutex.
+func (df *DataFrame) unmapChunk(chunkID int) {
+	chunk, ok := df.mapped[chunkID]
+	if !ok {
+		return
+	}
+	if err := chunk.unmap(); err != nil {
+		fmt.Printf("Error unmapping chunk file %s: %v\n", chunkName(chunkID), err)
+	}
+	delete(df.mapped, chunkID)
+}
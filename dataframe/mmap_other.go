@@ -0,0 +1,14 @@
+//go:build !unix
+
+package dataframe
+
+import "os"
+
+// mmapFile reads a file into memory on platforms without mmap support.
+func mmapFile(filename string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
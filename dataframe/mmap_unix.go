@@ -0,0 +1,30 @@
+//go:build unix
+
+package dataframe
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps a file read-only into memory. The returned function unmaps it.
+func mmapFile(filename string) ([]byte, func() error, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: filename, Err: err}
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
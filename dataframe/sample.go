@@ -0,0 +1,429 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// sampleConfig holds the options of Sample, SampleFrac and TrainTestSplit.
+type sampleConfig struct {
+	seed     int64
+	seeded   bool
+	replace  bool
+	stratify string
+}
+
+// SampleOption configures Sample, SampleFrac and TrainTestSplit.
+type SampleOption func(*sampleConfig)
+
+// WithSeed makes sampling reproducible. Without it, every call draws a
+// different sample.
+func WithSeed(seed int64) SampleOption {
+	return func(c *sampleConfig) {
+		c.seed, c.seeded = seed, true
+	}
+}
+
+// WithReplacement lets a row be drawn several times. The sampled rows are then
+// numbered from 0 rather than keeping their ids.
+func WithReplacement() SampleOption {
+	return func(c *sampleConfig) {
+		c.replace = true
+	}
+}
+
+// WithStratify samples every distinct value of a column separately, so that
+// the sample keeps the proportions of the values.
+func WithStratify(column string) SampleOption {
+	return func(c *sampleConfig) {
+		c.stratify = column
+	}
+}
+
+func newSampleConfig(opts []SampleOption) sampleConfig {
+	var cfg sampleConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !cfg.seeded {
+		cfg.seed = rand.Int63()
+	}
+	return cfg
+}
+
+// Sample returns n random rows as a new DataFrame with the same ids and time
+// index. Without stratification only the chunks holding the sampled rows are
+// read, as the ids are drawn by rank from the B+Tree indexes. With it, a first
+// pass counts the rows of every stratum, ranks are drawn within the strata,
+// and a second pass reads only the chunks holding drawn ranks, so memory
+// grows with the number of strata and of sampled rows rather than of rows.
+func (df *DataFrame) Sample(n int, opts ...SampleOption) (*DataFrame, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("cannot sample %d rows", n)
+	}
+	return df.sample(newSampleConfig(opts), func(counts []int) []int {
+		return allocate(n, counts)
+	})
+}
+
+// SampleFrac is Sample with the number of rows given as a fraction of the rows
+// of the DataFrame, or of every stratum with WithStratify. A fraction above 1
+// requires WithReplacement.
+func (df *DataFrame) SampleFrac(frac float64, opts ...SampleOption) (*DataFrame, error) {
+	if frac < 0 || math.IsNaN(frac) {
+		return nil, fmt.Errorf("cannot sample a fraction of %v", frac)
+	}
+	return df.sample(newSampleConfig(opts), func(counts []int) []int {
+		sizes := make([]int, len(counts))
+		for i, count := range counts {
+			sizes[i] = int(math.Round(frac * float64(count)))
+		}
+		return sizes
+	})
+}
+
+// TrainTestSplit splits the rows at random into a training and a test
+// DataFrame, the test one holding testFrac of the rows, or of every stratum
+// with WithStratify. Both keep the ids of the rows. WithReplacement is ignored.
+func (df *DataFrame) TrainTestSplit(testFrac float64, opts ...SampleOption) (train, test *DataFrame, err error) {
+	if testFrac < 0 || testFrac > 1 || math.IsNaN(testFrac) {
+		return nil, nil, fmt.Errorf("test fraction %v is not between 0 and 1", testFrac)
+	}
+	cfg := newSampleConfig(opts)
+	cfg.replace = false
+
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	testIDs, err := df.sampleIDs(cfg, func(counts []int) []int {
+		sizes := make([]int, len(counts))
+		for i, count := range counts {
+			sizes[i] = int(math.Round(testFrac * float64(count)))
+		}
+		return sizes
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	inTest := make(map[int]bool, len(testIDs))
+	for _, id := range testIDs {
+		inTest[id] = true
+	}
+	var trainIDs []int
+	for _, id := range df.rowIDs() {
+		if !inTest[id] {
+			trainIDs = append(trainIDs, id)
+		}
+	}
+
+	if train, err = df.copyFrame(trainIDs, nil); err != nil {
+		return nil, nil, err
+	}
+	if test, err = df.copyFrame(testIDs, nil); err != nil {
+		train.Close()
+		return nil, nil, err
+	}
+	return train, test, nil
+}
+
+// sample draws rows as a new DataFrame, with sizes giving the number of rows
+// to draw from the rows of every stratum, or of the whole DataFrame.
+func (df *DataFrame) sample(cfg sampleConfig, sizes func(counts []int) []int) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	ids, err := df.sampleIDs(cfg, sizes)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.replace {
+		return df.copyFrame(ids, nil)
+	}
+
+	// Rows drawn several times are copied under new ids
+	distinct := make(map[int]Row)
+	err = df.scanIDs(uniqueInts(ids), func(id int, row map[string]interface{}) error {
+		distinct[id] = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	newIDs := make([]int, len(ids))
+	rows := make([]Row, len(ids))
+	for i, id := range ids {
+		newIDs[i] = i
+		rows[i] = make(Row, len(distinct[id]))
+		for col, val := range distinct[id] {
+			rows[i][col] = val
+		}
+	}
+	out, err := df.derivedFrameFromRows(df.fields, fieldNames(df.fields), newIDs, rows)
+	if err != nil {
+		return nil, err
+	}
+	if df.timeIndex != nil {
+		if err := out.SetTimeIndex(df.timeIndex.column); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// sampleIDs draws the sorted ids of a sample. The caller must hold df.mutex.
+func (df *DataFrame) sampleIDs(cfg sampleConfig, sizes func(counts []int) []int) ([]int, error) {
+	rng := rand.New(rand.NewSource(cfg.seed))
+
+	if cfg.stratify == "" {
+		total := 0
+		for _, index := range df.Indexes {
+			total += index.Len()
+		}
+		size := sizes([]int{total})[0]
+		if cfg.replace {
+			if total == 0 {
+				return nil, nil
+			}
+			ranks := make([]int, size)
+			for i := range ranks {
+				ranks[i] = rng.Intn(total)
+			}
+			sort.Ints(ranks)
+			return df.selectIDs(ranks), nil
+		}
+		if size > total {
+			return nil, fmt.Errorf("cannot sample %d rows from %d without replacement", size, total)
+		}
+		return df.selectIDs(distinctRanks(total, size, rng)), nil
+	}
+
+	if !hasField(df.fields, cfg.stratify) {
+		return nil, fmt.Errorf("column %s not found", cfg.stratify)
+	}
+	strata, err := df.countStrata(cfg.stratify)
+	if err != nil {
+		return nil, err
+	}
+	ranks := make([][]int, len(strata.counts)) // Drawn ranks within every stratum
+	for i, size := range sizes(strata.counts) {
+		count := strata.counts[i]
+		if cfg.replace {
+			ranks[i] = make([]int, size)
+			for j := range ranks[i] {
+				ranks[i][j] = rng.Intn(count)
+			}
+			sort.Ints(ranks[i])
+			continue
+		}
+		if size > count {
+			return nil, fmt.Errorf("cannot sample %d rows from %d without replacement", size, count)
+		}
+		ranks[i] = distinctRanks(count, size, rng)
+	}
+	return df.selectStrata(cfg.stratify, strata, ranks)
+}
+
+// distinctRanks draws k distinct ranks below n, sorted, by Floyd's algorithm,
+// which takes time and memory in k whatever n.
+func distinctRanks(n, k int, rng *rand.Rand) []int {
+	chosen := make(map[int]bool, k)
+	ranks := make([]int, 0, k)
+	for j := n - k; j < n; j++ {
+		rank := rng.Intn(j + 1)
+		if chosen[rank] {
+			rank = j
+		}
+		chosen[rank] = true
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	return ranks
+}
+
+// selectIDs returns the sorted ids at sorted ranks, counting the ids of the
+// index shards in shard order, without reading any row or copying the keys of
+// the shards. The caller must hold df.mutex.
+func (df *DataFrame) selectIDs(ranks []int) []int {
+	var ids []int
+	first := 0 // Rank of the first id of the shard
+	for _, index := range df.Indexes {
+		n := index.Len()
+		end := sort.SearchInts(ranks, first+n)
+		if end > 0 {
+			local := make([]int, end)
+			for i, rank := range ranks[:end] {
+				local[i] = rank - first
+			}
+			ids = append(ids, index.Select(local)...)
+			ranks = ranks[end:]
+		}
+		first += n
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// strata holds the number of rows holding every distinct value of a column,
+// in total and in every chunk.
+type strata struct {
+	keys   map[string]int // Stratum of every value, by group key, numbered in the order the values first appear
+	counts []int          // Rows of every stratum
+	tasks  []chunkTask    // Chunks of the rows, in id order
+	chunks []map[int]int  // Rows of every stratum in each chunk
+}
+
+// countStrata counts the rows of every distinct value of a column, keeping
+// the counts rather than the ids of the rows. The caller must hold df.mutex.
+func (df *DataFrame) countStrata(column string) (*strata, error) {
+	type chunkCounts struct {
+		keys   []string // In the order they first appear in the chunk
+		counts map[string]int
+	}
+	tasks := groupByChunk(df.rowIDs())
+	local := make([]chunkCounts, len(tasks))
+	err := df.runChunks(tasks, func(i int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		counts := chunkCounts{counts: make(map[string]int)}
+		for j, id := range chunk.IDs {
+			key := groupKey(id, chunk.Rows[j], []string{column})
+			if counts.counts[key] == 0 {
+				counts.keys = append(counts.keys, key)
+			}
+			counts.counts[key]++
+		}
+		local[i] = counts
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &strata{keys: make(map[string]int), tasks: tasks, chunks: make([]map[int]int, len(tasks))}
+	for i, counts := range local {
+		s.chunks[i] = make(map[int]int, len(counts.keys))
+		for _, key := range counts.keys {
+			stratum, ok := s.keys[key]
+			if !ok {
+				stratum = len(s.counts)
+				s.keys[key] = stratum
+				s.counts = append(s.counts, 0)
+			}
+			s.counts[stratum] += counts.counts[key]
+			s.chunks[i][stratum] = counts.counts[key]
+		}
+	}
+	return s, nil
+}
+
+// selectStrata returns the sorted ids at the given sorted ranks of every
+// stratum, the rank of a row being its position in id order among the rows of
+// its stratum. A rank drawn several times gives its id as many times. Only the
+// chunks holding a drawn rank are read again. The caller must hold df.mutex.
+func (df *DataFrame) selectStrata(column string, s *strata, ranks [][]int) ([]int, error) {
+	seen := make([]int, len(s.counts)) // Rows of every stratum in the chunks before
+	var tasks []chunkTask
+	var firsts []map[int]int // Rank of the first row of every stratum in each chunk read
+	for i, task := range s.tasks {
+		drawn := false
+		for stratum, count := range s.chunks[i] {
+			r := ranks[stratum]
+			j := sort.SearchInts(r, seen[stratum])
+			drawn = drawn || j < len(r) && r[j] < seen[stratum]+count
+		}
+		if drawn {
+			first := make(map[int]int, len(s.chunks[i]))
+			for stratum := range s.chunks[i] {
+				first[stratum] = seen[stratum]
+			}
+			tasks = append(tasks, task)
+			firsts = append(firsts, first)
+		}
+		for stratum, count := range s.chunks[i] {
+			seen[stratum] += count
+		}
+	}
+
+	picked := make([][]int, len(tasks))
+	err := df.runChunks(tasks, func(i int, task chunkTask) error {
+		chunk, err := df.loadChunk(task)
+		if err != nil {
+			return err
+		}
+		rank := firsts[i]
+		for j, id := range chunk.IDs {
+			stratum, ok := s.keys[groupKey(id, chunk.Rows[j], []string{column})]
+			if !ok {
+				return fmt.Errorf("row %d changed while sampling", id)
+			}
+			r := ranks[stratum]
+			for k := sort.SearchInts(r, rank[stratum]); k < len(r) && r[k] == rank[stratum]; k++ {
+				picked[i] = append(picked[i], id)
+			}
+			rank[stratum]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sampled []int
+	for _, ids := range picked {
+		sampled = append(sampled, ids...)
+	}
+	sort.Ints(sampled)
+	return sampled, nil
+}
+
+// allocate splits n rows between strata in proportion to their counts, giving
+// the rows left by rounding down to the strata with the largest remainders.
+func allocate(n int, counts []int) []int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	sizes := make([]int, len(counts))
+	if total == 0 {
+		if len(sizes) > 0 {
+			sizes[0] = n
+		}
+		return sizes
+	}
+
+	remainders := make([]float64, len(counts))
+	left := n
+	for i, count := range counts {
+		exact := float64(n) * float64(count) / float64(total)
+		sizes[i] = int(exact)
+		remainders[i] = exact - float64(sizes[i])
+		left -= sizes[i]
+	}
+	order := make([]int, len(counts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; i < left; i++ {
+		sizes[order[i%len(order)]]++
+	}
+	return sizes
+}
+
+// uniqueInts returns sorted ints without repeats.
+func uniqueInts(sorted []int) []int {
+	var unique []int
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,149 @@
+package dataframe
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sampleItems returns a frame of n items whose names are strata of very
+// different sizes, with the ids of every name in the order the names appear.
+func sampleItems(t *testing.T, n int) (*DataFrame, map[string][]int, []string) {
+	t.Helper()
+	items := make([]durableItem, n)
+	byName := make(map[string][]int)
+	var names []string
+	for i := range items {
+		name := []string{"pen", "ink"}[i%2]
+		if i%1500 == 3 {
+			name = "rare"
+		}
+		items[i] = durableItem{name, i}
+		if byName[name] == nil {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], i)
+	}
+	df, err := NewDataFrame(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(df.Close)
+	return df, byName, names
+}
+
+func TestSampleSeed(t *testing.T) {
+	df, _, _ := sampleItems(t, 6000)
+	for _, opts := range [][]SampleOption{
+		{WithSeed(7)},
+		{WithSeed(7), WithReplacement()},
+		{WithSeed(7), WithStratify("Name")},
+		{WithSeed(7), WithStratify("Name"), WithReplacement()},
+	} {
+		first, err := df.Sample(50, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := df.Sample(50, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(concatRows(t, first), concatRows(t, second)) {
+			t.Errorf("samples with the same seed differ")
+		}
+		other, err := df.Sample(50, append(opts, WithSeed(8))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(concatRows(t, first), concatRows(t, other)) {
+			t.Errorf("samples with different seeds are the same")
+		}
+		for _, out := range []*DataFrame{first, second, other} {
+			if got := len(out.rowIDs()); got != 50 {
+				t.Errorf("sample has %d rows, want 50", got)
+			}
+			out.Close()
+		}
+	}
+}
+
+func TestSampleStratified(t *testing.T) {
+	df, byName, names := sampleItems(t, 6000)
+	counts := make([]int, len(names))
+	for i, name := range names {
+		counts[i] = len(byName[name])
+	}
+
+	for _, n := range []int{0, 7, 300, 6000} {
+		const seed = 11
+		out, err := df.Sample(n, WithSeed(seed), WithStratify("Name"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer out.Close()
+
+		// The ranks drawn within every stratum, in the order the names appear
+		rng := rand.New(rand.NewSource(seed))
+		var want []int
+		for i, size := range allocate(n, counts) {
+			for _, rank := range distinctRanks(counts[i], size, rng) {
+				want = append(want, byName[names[i]][rank])
+			}
+		}
+		sort.Ints(want)
+		if got := out.rowIDs(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Sample(%d) = %d ids, want %d: %v", n, len(got), len(want), got)
+		}
+	}
+
+	if _, err := df.SampleFrac(1.5, WithStratify("Name")); err == nil {
+		t.Error("sampling more rows than a stratum holds without replacement succeeded")
+	}
+}
+
+func TestSampleStratifiedReplacement(t *testing.T) {
+	df, byName, _ := sampleItems(t, 3000)
+	out, err := df.SampleFrac(2, WithSeed(3), WithStratify("Name"), WithReplacement())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	got := make(map[interface{}]int)
+	for _, row := range concatRows(t, out) {
+		got[row["Name"]]++
+	}
+	for name, ids := range byName {
+		if got[name] != 2*len(ids) {
+			t.Errorf("stratum %s has %d rows, want %d", name, got[name], 2*len(ids))
+		}
+	}
+}
+
+func TestTrainTestSplitStratified(t *testing.T) {
+	df, byName, _ := sampleItems(t, 3000)
+	train, test, err := df.TrainTestSplit(0.5, WithSeed(5), WithStratify("Name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer train.Close()
+	defer test.Close()
+
+	trainRows, testRows := concatRows(t, train), concatRows(t, test)
+	if len(trainRows)+len(testRows) != 3000 {
+		t.Errorf("split holds %d and %d rows, want 3000 in all", len(trainRows), len(testRows))
+	}
+	inTest := make(map[interface{}]int)
+	for id, row := range testRows {
+		if _, ok := trainRows[id]; ok {
+			t.Fatalf("row %d is in both frames", id)
+		}
+		inTest[row["Name"]]++
+	}
+	for name, ids := range byName {
+		if want := (len(ids) + 1) / 2; inTest[name] != want {
+			t.Errorf("test frame holds %d rows of %s, want %d", inTest[name], name, want)
+		}
+	}
+}
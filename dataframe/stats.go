@@ -0,0 +1,442 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+// distinctBits is the size of the bitmap used to estimate distinct values by
+// linear counting. It is several times the rows of a chunk, so estimates are close.
+const distinctBits = 4096
+
+// ColumnStats summarizes the values of one column in a chunk file.
+type ColumnStats struct {
+	Count    int         // Non-null values
+	Nulls    int         // Null values
+	Distinct int         // Estimated number of distinct non-null values
+	Min, Max interface{} // Smallest and largest values, nil unless all values are mutually ordered
+}
+
+// ChunkStats summarizes a chunk file. Scans use them as a zone map to skip
+// chunks that cannot hold a matching row.
+type ChunkStats struct {
+	ID      int // Chunk ID
+	Rows    int
+	Columns map[string]ColumnStats
+
+	blooms map[string]*bloomFilter // Bloom filters of the columns chosen with WithBloomFilters
+}
+
+// ChunkStats returns the statistics of the chunk files of the DataFrame in chunk
+// order. Rows not yet flushed to their chunk files are not covered.
+func (df *DataFrame) ChunkStats() []ChunkStats {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	stats := make([]ChunkStats, 0, len(df.stats))
+	for _, s := range df.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
+// columnAcc accumulates the statistics of a column.
+type columnAcc struct {
+	stats   ColumnStats
+	class   int // Ordering class of the values, see orderClass
+	ordered bool
+	seen    [distinctBits / 64]uint64
+	bloom   *bloomFilter // Nil unless the column has a Bloom filter
+}
+
+// Ordering classes: values of one class are ordered by compareValues.
+const (
+	classNone = iota
+	classNumber
+	classString
+	classTime
+	classBool
+)
+
+// orderClass returns the ordering class of a non-null value, or classNone.
+func orderClass(val interface{}) int {
+	if f, ok := ToFloat64(val); ok {
+		if math.IsNaN(f) {
+			return classNone
+		}
+		return classNumber
+	}
+	switch val.(type) {
+	case string:
+		return classString
+	case time.Time:
+		return classTime
+	case bool:
+		return classBool
+	}
+	return classNone
+}
+
+func (acc *columnAcc) add(val interface{}) {
+	val, ok := statValue(val)
+	if !ok {
+		acc.stats.Nulls++
+		return
+	}
+
+	class := orderClass(val)
+	if acc.stats.Count == 0 {
+		acc.class, acc.ordered = class, class != classNone
+		acc.stats.Min, acc.stats.Max = val, val
+	} else if acc.ordered {
+		if class != acc.class {
+			acc.ordered = false
+		} else {
+			if cmp, _ := compareValues(val, acc.stats.Min); cmp < 0 {
+				acc.stats.Min = val
+			}
+			if cmp, _ := compareValues(val, acc.stats.Max); cmp > 0 {
+				acc.stats.Max = val
+			}
+		}
+	}
+	acc.stats.Count++
+	if acc.bloom != nil {
+		if h, ok := bloomHash(val); ok {
+			acc.bloom.add(h)
+		}
+	}
+
+	h := hashValue(val) % distinctBits
+	acc.seen[h/64] |= 1 << (h % 64)
+}
+
+func (acc *columnAcc) result() ColumnStats {
+	stats := acc.stats
+	if !acc.ordered {
+		stats.Min, stats.Max = nil, nil
+	}
+
+	set := 0
+	for _, word := range acc.seen {
+		set += bits.OnesCount64(word)
+	}
+	if zeros := distinctBits - set; zeros > 0 {
+		stats.Distinct = int(math.Round(-distinctBits * math.Log(float64(zeros)/distinctBits)))
+	} else {
+		stats.Distinct = stats.Count
+	}
+	if stats.Distinct > stats.Count {
+		stats.Distinct = stats.Count
+	}
+	if stats.Distinct == 0 && stats.Count > 0 {
+		stats.Distinct = 1
+	}
+	return stats
+}
+
+// statValue dereferences a value, reporting false for nulls.
+func statValue(val interface{}) (interface{}, bool) {
+	switch val.(type) {
+	case nil:
+		return nil, false
+	case string, int, int64, float64, bool, time.Time:
+		return val, true
+	}
+	return deref(val)
+}
+
+// hashValue hashes a value by its binary encoding, so equal values of the same
+// type hash alike.
+func hashValue(val interface{}) uint64 {
+	var scratch [64]byte
+	buf, err := appendBinaryValue(scratch[:0], val)
+	if err != nil {
+		buf = append(scratch[:0], fmt.Sprintf("%T:%v", val, val)...)
+	}
+	h := fnv.New64a()
+	h.Write(buf)
+	return h.Sum64()
+}
+
+// computeChunkStats computes the statistics of the rows of a chunk, with Bloom
+// filters over the given columns.
+func computeChunkStats(chunkID int, chunk map[int]interface{}, bloomColumns []string) *ChunkStats {
+	columns := make(map[string]*columnAcc)
+	for _, col := range bloomColumns {
+		columns[col] = &columnAcc{bloom: newBloomFilter(len(chunk))}
+	}
+	for _, row := range chunk {
+		for col, val := range toRowMap(row) {
+			acc, ok := columns[col]
+			if !ok {
+				acc = &columnAcc{}
+				columns[col] = acc
+			}
+			acc.add(val)
+		}
+	}
+
+	stats := &ChunkStats{ID: chunkID, Rows: len(chunk), Columns: make(map[string]ColumnStats, len(columns))}
+	for col, acc := range columns {
+		if acc.bloom != nil {
+			if stats.blooms == nil {
+				stats.blooms = make(map[string]*bloomFilter)
+			}
+			stats.blooms[col] = acc.bloom
+		}
+		if acc.stats.Count+acc.stats.Nulls > 0 {
+			stats.Columns[col] = acc.result()
+		}
+	}
+	return stats
+}
+
+// encodeChunkStats encodes statistics for a chunk header with the binary value
+// encoding. Bloom filters follow the columns.
+func encodeChunkStats(stats *ChunkStats) ([]byte, error) {
+	names := make([]string, 0, len(stats.Columns))
+	for col := range stats.Columns {
+		names = append(names, col)
+	}
+	sort.Strings(names)
+
+	buf := binary.AppendUvarint(nil, uint64(stats.Rows))
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, col := range names {
+		cs := stats.Columns[col]
+		buf = appendBinaryString(buf, col)
+		buf = binary.AppendUvarint(buf, uint64(cs.Count))
+		buf = binary.AppendUvarint(buf, uint64(cs.Nulls))
+		buf = binary.AppendUvarint(buf, uint64(cs.Distinct))
+		for _, val := range []interface{}{cs.Min, cs.Max} {
+			if val == nil {
+				buf = append(buf, tagAbsent)
+				continue
+			}
+			var err error
+			if buf, err = appendBinaryValue(buf, val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return appendBloomFilters(buf, stats.blooms, names), nil
+}
+
+// decodeChunkStats decodes statistics written by encodeChunkStats.
+func decodeChunkStats(chunkID int, data []byte) (*ChunkStats, error) {
+	r := bytes.NewReader(data)
+	var counts [2]uint64
+	for i := range counts {
+		var err error
+		if counts[i], err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+	}
+	if counts[1] > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid chunk statistics")
+	}
+
+	stats := &ChunkStats{ID: chunkID, Rows: int(counts[0]), Columns: make(map[string]ColumnStats, counts[1])}
+	for i := uint64(0); i < counts[1]; i++ {
+		col, err := readBinaryString(r)
+		if err != nil {
+			return nil, err
+		}
+		var cs ColumnStats
+		for _, n := range []*int{&cs.Count, &cs.Nulls, &cs.Distinct} {
+			v, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			*n = int(v)
+		}
+		if cs.Min, _, err = readBinaryValue(r); err != nil {
+			return nil, err
+		}
+		if cs.Max, _, err = readBinaryValue(r); err != nil {
+			return nil, err
+		}
+		stats.Columns[col] = cs
+	}
+	if r.Len() > 0 {
+		var err error
+		if stats.blooms, err = readBloomFilters(r, len(data)); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// chunkFileStats returns the statistics in the header of a chunk file, computing
+// them from its rows for files written before headers held them.
+func chunkFileStats(chunkID int, data []byte, chunk map[int]interface{}) (*ChunkStats, error) {
+	header, ok, err := parseChunkHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || header.stats == nil {
+		return computeChunkStats(chunkID, chunk, nil), nil
+	}
+	return decodeChunkStats(chunkID, header.stats)
+}
+
+// pruneChunks drops the ids of chunks whose zone maps show that no row can
+// satisfy all of preds. The caller must hold df.mutex.
+func (df *DataFrame) pruneChunks(ids []int, preds []Expr) []int {
+	if len(preds) == 0 {
+		return ids
+	}
+	return df.skipChunks(ids, func(stats *ChunkStats) bool {
+		for _, pred := range preds {
+			if !stats.mayMatch(pred) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// skipChunks drops the ids of chunks for which skip returns true. Chunks with
+// rows not yet flushed are always kept, as their statistics do not cover those
+// rows. The caller must hold df.mutex.
+func (df *DataFrame) skipChunks(ids []int, skip func(stats *ChunkStats) bool) []int {
+	if len(df.stats) == 0 {
+		return ids
+	}
+
+	var kept []int
+	for start := 0; start < len(ids); {
+		chunkID := ids[start] / chunkSize
+		end := start
+		for end < len(ids) && ids[end]/chunkSize == chunkID {
+			end++
+		}
+		stats, ok := df.stats[chunkID]
+		if !ok || len(df.cache[chunkID]) > 0 || len(df.flushing[chunkID]) > 0 || !skip(stats) {
+			kept = append(kept, ids[start:end]...)
+		}
+		start = end
+	}
+	return kept
+}
+
+// flippedOps mirror comparisons, so that lit < col can be checked as col > lit.
+var flippedOps = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+// mayMatch reports whether a row of the chunk might satisfy pred. It is
+// conservative: false means that no row can.
+func (s *ChunkStats) mayMatch(pred Expr) bool {
+	switch pred.op {
+	case "and":
+		for _, arg := range pred.args {
+			if !s.mayMatch(arg) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, arg := range pred.args {
+			if s.mayMatch(arg) {
+				return true
+			}
+		}
+		return false
+	case "isnull":
+		cs, ok := s.column(pred.args[0])
+		return !ok || cs.Nulls > 0
+	case "in":
+		if _, ok := s.column(pred.args[0]); !ok {
+			return true
+		}
+		for _, arg := range pred.args[1:] {
+			if arg.op != "lit" || s.mayCompare(pred.args[0], "==", arg.value) {
+				return true
+			}
+		}
+		return false
+	case "==", "!=", "<", "<=", ">", ">=":
+		left, right := pred.args[0], pred.args[1]
+		switch {
+		case left.op == "col" && right.op == "lit":
+			return s.mayCompare(left, pred.op, right.value)
+		case left.op == "lit" && right.op == "col":
+			return s.mayCompare(right, flippedOps[pred.op], left.value)
+		}
+	}
+	return true
+}
+
+// column returns the statistics of a column expression if every row of the chunk
+// has the column. Rows without it make predicates fail, which must not be skipped.
+func (s *ChunkStats) column(e Expr) (ColumnStats, bool) {
+	if e.op != "col" {
+		return ColumnStats{}, false
+	}
+	cs, ok := s.Columns[e.name]
+	if !ok || cs.Count+cs.Nulls < s.Rows {
+		return ColumnStats{}, false
+	}
+	return cs, true
+}
+
+// mayCompare reports whether col op value might hold for a row of the chunk,
+// following the semantics of Eval: nulls and values that do not compare with
+// value only satisfy !=.
+func (s *ChunkStats) mayCompare(col Expr, op string, value interface{}) bool {
+	cs, ok := s.column(col)
+	if !ok {
+		return true
+	}
+	value, notNull := statValue(value)
+	if !notNull || cs.Count == 0 {
+		return op == "!="
+	}
+	if op == "==" {
+		if h, ok := bloomHash(value); ok && !s.mayContainAny(col.name, []uint64{h}) {
+			return false
+		}
+	}
+	if cs.Min == nil {
+		return true
+	}
+
+	cmpMin, ok := compareValues(value, cs.Min)
+	if !ok {
+		return op == "!="
+	}
+	cmpMax, _ := compareValues(value, cs.Max)
+	switch op {
+	case "==":
+		return cmpMin >= 0 && cmpMax <= 0
+	case "!=":
+		return cs.Nulls > 0 || cmpMin != 0 || cmpMax != 0
+	case "<":
+		return cmpMin > 0
+	case "<=":
+		return cmpMin >= 0
+	case ">":
+		return cmpMax < 0
+	case ">=":
+		return cmpMax <= 0
+	}
+	return true
+}
+
+// String summarizes the statistics of a column.
+func (cs ColumnStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "count=%d nulls=%d distinct~%d", cs.Count, cs.Nulls, cs.Distinct)
+	if cs.Min != nil {
+		fmt.Fprintf(&b, " min=%v max=%v", cs.Min, cs.Max)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type statItem struct {
+	Name  string
+	Price float64
+	At    time.Time
+	Note  *string
+}
+
+// statItems creates a durable frame of n items, whose prices and times rise
+// with their ids and whose notes are null for even ids. It is reopened, so
+// that its statistics are read back from the chunk files.
+func statItems(t *testing.T, n int, opts ...FrameOption) *DataFrame {
+	t.Helper()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := make([]statItem, n)
+	for i := range items {
+		items[i] = statItem{Name: fmt.Sprintf("item%d", i), Price: float64(i) / 2, At: base.Add(time.Duration(i) * time.Hour)}
+		if i%2 == 1 {
+			note := "odd"
+			items[i].Note = &note
+		}
+	}
+	dir := filepath.Join(t.TempDir(), "items")
+	df, err := CreateDataFrame(dir, items, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	df.Close()
+	if df, err = OpenDataFrame(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(df.Close)
+	return df
+}
+
+// keptChunks returns the chunks of the ids kept.
+func keptChunks(ids []int) []int {
+	var chunks []int
+	for _, id := range ids {
+		if chunk := id / chunkSize; len(chunks) == 0 || chunks[len(chunks)-1] != chunk {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// checkPruned checks that pruning df by pred keeps the given chunks, and every
+// row that matches pred.
+func checkPruned(t *testing.T, df *DataFrame, pred Expr, chunks []int) {
+	t.Helper()
+	ids := df.rowIDs()
+	kept := df.pruneChunks(ids, []Expr{pred})
+	if got := keptChunks(kept); !reflect.DeepEqual(got, chunks) {
+		t.Errorf("%v kept chunks %v, want %v", pred, got, chunks)
+	}
+	inKept := make(map[int]bool, len(kept))
+	for _, id := range kept {
+		inKept[id] = true
+	}
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		if ok, err := pred.Match(id, row); err == nil && ok && !inKept[id] {
+			return fmt.Errorf("row %d matches %v but its chunk was skipped", id, pred)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChunkStats(t *testing.T) {
+	df := statItems(t, 4000)
+
+	stats := df.ChunkStats()
+	if len(stats) != 4 {
+		t.Fatalf("%d chunk statistics, want 4", len(stats))
+	}
+	s := stats[1]
+	if s.ID != 1 || s.Rows != chunkSize {
+		t.Errorf("chunk %d has %d rows, want chunk 1 with %d", s.ID, s.Rows, chunkSize)
+	}
+	price := s.Columns["Price"]
+	if price.Count != chunkSize || price.Nulls != 0 || price.Min != 500.0 || price.Max != 999.5 {
+		t.Errorf("Price statistics = %v, want count=1000 nulls=0 min=500 max=999.5", price)
+	}
+	if math.Abs(float64(price.Distinct-chunkSize)) > chunkSize/20 {
+		t.Errorf("Price has an estimated %d distinct values, want about %d", price.Distinct, chunkSize)
+	}
+	note := s.Columns["Note"]
+	if note.Count != chunkSize/2 || note.Nulls != chunkSize/2 || note.Min != "odd" || note.Max != "odd" || note.Distinct != 1 {
+		t.Errorf("Note statistics = %v, want count=500 nulls=500 distinct~1 min=odd max=odd", note)
+	}
+	at := s.Columns["At"].Min.(time.Time)
+	if want := time.Date(2024, 2, 11, 16, 0, 0, 0, time.UTC); !at.Equal(want) {
+		t.Errorf("At minimum = %v, want %v", at, want)
+	}
+}
+
+func TestZoneMapSkipping(t *testing.T) {
+	df := statItems(t, 4000)
+	if err := df.UpdateRow(3999, map[string]interface{}{"Price": 5.0}); err != nil {
+		t.Fatal(err) // Left unflushed, so chunk 3 is never skipped
+	}
+
+	tests := []struct {
+		name   string
+		pred   Expr
+		chunks []int
+	}{
+		{"equality", Col("Price").Eq(617), []int{1, 3}},
+		{"time in another zone", Col("At").Eq(time.Date(2024, 3, 25, 5, 0, 0, 0, time.FixedZone("X", 3600))), []int{2, 3}},
+		{"in list", Col("Price").In(2, 1250, "none"), []int{0, 2, 3}},
+		{"range", Col("Price").Ge(400).And(Col("Price").Lt(600)), []int{0, 1, 3}},
+		{"flipped literal", Lit(1200).Lt(Col("Price")), []int{2, 3}},
+		{"disjunction", Col("Price").Lt(10).Or(Col("Price").Eq(1050)), []int{0, 2, 3}},
+		{"not equal", Col("Note").Ne("odd"), []int{0, 1, 2, 3}},
+		{"is null", Col("Note").IsNull(), []int{0, 1, 2, 3}},
+		{"incomparable", Col("Price").Eq("cheap"), []int{3}},
+		{"missing column", Col("Size").Eq(1), []int{0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkPruned(t, df, tt.pred, tt.chunks)
+		})
+	}
+}
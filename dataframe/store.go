@@ -0,0 +1,201 @@
+package dataframe
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChunkStore holds the chunk files and manifest of one DataFrame as named
+// blobs. Get and Delete report missing blobs with an error wrapping
+// fs.ErrNotExist, and Put replaces a blob atomically, so a reader sees either
+// the old or the new contents.
+type ChunkStore interface {
+	Get(name string) ([]byte, error)
+	Put(name string, data []byte) error
+	Delete(name string) error
+	List() ([]string, error) // Names of all blobs, sorted
+}
+
+// ErrReadOnly is returned when writing to a read-only store.
+var ErrReadOnly = errors.New("chunk store is read-only")
+
+// WithChunkStore stores the chunks and, for durable frames, the manifest of the
+// DataFrame in store instead of on local disk. Durable frames keep their
+// write-ahead log in their local directory.
+func WithChunkStore(store ChunkStore) FrameOption {
+	return func(df *DataFrame) {
+		df.store = store
+	}
+}
+
+// DiskStore stores blobs as files in a local directory.
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore returns a store for the files in dir, creating it if needed.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create chunk directory: %v", err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+func (s *DiskStore) Get(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+// Put writes data to a temporary file, syncs it and renames it over the blob.
+func (s *DiskStore) Put(name string, data []byte) error {
+	filename := filepath.Join(s.dir, name)
+	tmpFile := filename + ".tmp"
+	file, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return os.Rename(tmpFile, filename)
+}
+
+// Map memory-maps a blob read-only. Blobs are replaced by renaming, never
+// modified in place, so a mapping keeps the contents it was made with.
+func (s *DiskStore) Map(name string) ([]byte, func() error, error) {
+	return mmapFile(filepath.Join(s.dir, name))
+}
+
+func (s *DiskStore) Delete(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// List returns the blobs in the directory, skipping temporary files.
+func (s *DiskStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasSuffix(entry.Name(), ".tmp") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// removeTemp deletes the leftovers of writes interrupted by a crash. The blobs
+// they were meant to replace are intact.
+func (s *DiskStore) removeTemp() {
+	tmpFiles, _ := filepath.Glob(filepath.Join(s.dir, "*.tmp"))
+	for _, tmpFile := range tmpFiles {
+		os.Remove(tmpFile)
+	}
+}
+
+// MemoryStore keeps blobs in memory. It is mostly useful for small frames and testing.
+type MemoryStore struct {
+	mutex sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(name string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	data, ok := s.blobs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+// Put stores a copy of data.
+func (s *MemoryStore) Put(name string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.blobs[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.blobs[name]; !ok {
+		return &fs.PathError{Op: "delete", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.blobs, name)
+	return nil
+}
+
+func (s *MemoryStore) List() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := make([]string, 0, len(s.blobs))
+	for name := range s.blobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// FSStore reads blobs from a directory of an fs.FS, such as an embed.FS or
+// os.DirFS. It is read-only: Put and Delete return ErrReadOnly.
+type FSStore struct {
+	fsys fs.FS
+	dir  string
+}
+
+// NewFSStore returns a read-only store for the files in dir of fsys.
+func NewFSStore(fsys fs.FS, dir string) *FSStore {
+	return &FSStore{fsys: fsys, dir: dir}
+}
+
+func (s *FSStore) Get(name string) ([]byte, error) {
+	return fs.ReadFile(s.fsys, path.Join(s.dir, name))
+}
+
+func (s *FSStore) Put(name string, data []byte) error {
+	return ErrReadOnly
+}
+
+func (s *FSStore) Delete(name string) error {
+	return ErrReadOnly
+}
+
+func (s *FSStore) List() ([]string, error) {
+	entries, err := fs.ReadDir(s.fsys, s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
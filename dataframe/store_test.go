@@ -0,0 +1,187 @@
+package dataframe
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeBucket serves the objects of one S3 bucket, listing at most two keys per page.
+type fakeBucket struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/bucket" && r.URL.Query().Get("list-type") == "2":
+		var keys []string
+		for k := range b.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		start, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+		var result listBucketResult
+		for i := start; i < len(keys) && i < start+2; i++ {
+			result.Contents = append(result.Contents, struct{ Key string }{keys[i]})
+		}
+		if start+2 < len(keys) {
+			result.IsTruncated, result.NextContinuationToken = true, strconv.Itoa(start+2)
+		}
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet:
+		data, ok := b.objects[key]
+		if !ok {
+			http.Error(w, "no such key", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		b.objects[key] = data
+	case r.Method == http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported", http.StatusBadRequest)
+	}
+}
+
+func TestChunkStores(t *testing.T) {
+	bucket := &fakeBucket{objects: map[string][]byte{"other/chunk_0": nil, "frames/items/nested/chunk_0": nil}}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+
+	disk, err := NewDiskStore(filepath.Join(t.TempDir(), "items"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "bucket", Prefix: "frames/items/", AccessKey: "key", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name          string
+		store         ChunkStore
+		deleteMissing bool // Deleting a missing blob reports it
+	}{
+		{"disk", disk, true},
+		{"memory", NewMemoryStore(), true},
+		{"s3", s3, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store
+			if _, err := store.Get("chunk_0"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Get of a missing blob = %v, want fs.ErrNotExist", err)
+			}
+			for _, name := range []string{"chunk_2", "manifest", "chunk_0", "chunk_1"} {
+				if err := store.Put(name, []byte("old "+name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.Put("chunk_0", []byte("new")); err != nil {
+				t.Fatal(err)
+			}
+			if data, err := store.Get("chunk_0"); err != nil || string(data) != "new" {
+				t.Errorf("Get after a replacing Put = %q, %v, want new", data, err)
+			}
+			if err := store.Delete("chunk_1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get("chunk_1"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Get of a deleted blob = %v, want fs.ErrNotExist", err)
+			}
+			if err := store.Delete("chunk_1"); tt.deleteMissing && !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Delete of a missing blob = %v, want fs.ErrNotExist", err)
+			}
+			if names, err := store.List(); err != nil || !reflect.DeepEqual(names, []string{"chunk_0", "chunk_2", "manifest"}) {
+				t.Errorf("List = %v, %v, want [chunk_0 chunk_2 manifest]", names, err)
+			}
+		})
+	}
+
+	unsigned, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "bucket", AccessKey: "other", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unsigned.Get("chunk_0"); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get with refused credentials = %v, want an error other than fs.ErrNotExist", err)
+	}
+	if _, err := NewS3Store(S3Config{Endpoint: "localhost", Bucket: "bucket"}); err == nil {
+		t.Error("NewS3Store without a URL scheme succeeded")
+	}
+}
+
+func TestFSStore(t *testing.T) {
+	store := NewFSStore(fstest.MapFS{
+		"frames/items/chunk_0":  {Data: []byte("rows")},
+		"frames/items/manifest": {Data: []byte("ids")},
+		"frames/items/dir/file": {Data: []byte("nested")},
+	}, "frames/items")
+	if data, err := store.Get("chunk_0"); err != nil || string(data) != "rows" {
+		t.Errorf("Get = %q, %v, want rows", data, err)
+	}
+	if _, err := store.Get("chunk_1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get of a missing blob = %v, want fs.ErrNotExist", err)
+	}
+	if names, err := store.List(); err != nil || !reflect.DeepEqual(names, []string{"chunk_0", "manifest"}) {
+		t.Errorf("List = %v, %v, want [chunk_0 manifest]", names, err)
+	}
+	if err := store.Put("chunk_0", nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Put = %v, want ErrReadOnly", err)
+	}
+	if err := store.Delete("chunk_0"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestDurableFrameInStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "items")
+	store := NewMemoryStore()
+	df, want := durableItems(t, dir, 2500, WithChunkStore(store))
+	mutateItems(t, df, want)
+	df.Close()
+
+	names, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{chunkName(0), chunkName(1), chunkName(2)} {
+		if i := sort.SearchStrings(names, name); i == len(names) || names[i] != name {
+			t.Errorf("store holds %v, missing %s", names, name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s was written to the local directory", name)
+		}
+	}
+
+	df, err = OpenDataFrame(dir, WithChunkStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	checkItems(t, df, want)
+}
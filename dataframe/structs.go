@@ -0,0 +1,190 @@
+package dataframe
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// structColumn is a column read from a field of a struct, possibly nested.
+type structColumn struct {
+	name        string
+	index       []int // Field indexes from the outer struct, as for reflect.Value.FieldByIndex
+	typ         reflect.Type
+	depth       int  // Number of structs the field is nested in
+	tagged      bool // The column is named by a tag
+	omitEmpty   bool // Zero values are stored as nil
+	indexed     bool // The column gets a Bloom filter in every chunk file
+	categorical bool // The column is dictionary-encoded
+}
+
+// structColumnsCache holds the columns of every struct type seen, by type.
+var structColumnsCache sync.Map
+
+// structColumns returns the columns of a struct type in field order. A field
+// is named by its bluejay tag, or by its json tag if it has none, with the
+// options omitempty, index and categorical; a name of "-" skips it.
+// Unexported fields are skipped. The fields of nested structs become columns
+// named after the outer field and the inner column, joined by a dot, and those
+// of embedded structs without a tag name are promoted as encoding/json does:
+// of several fields with the same name, the least nested wins, a tagged one
+// winning a tie, and the others are dropped.
+func structColumns(t reflect.Type) []structColumn {
+	if cols, ok := structColumnsCache.Load(t); ok {
+		return cols.([]structColumn)
+	}
+	all := walkStructColumns(t, "", nil, 0, map[reflect.Type]bool{t: true})
+
+	byName := make(map[string][]structColumn)
+	for _, col := range all {
+		byName[col.name] = append(byName[col.name], col)
+	}
+	var cols []structColumn
+	for _, col := range all {
+		if slices.Equal(dominant(byName[col.name]), col.index) {
+			cols = append(cols, col)
+		}
+		t := col.typ
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if basicType(t) != nil {
+			registerNamedType(t) // So that codecs decode its values back to it
+		}
+	}
+	structColumnsCache.Store(t, cols)
+	return cols
+}
+
+// walkStructColumns lists the columns of the fields of t, including every
+// candidate for a name. visiting holds the struct types being walked, which
+// are not flattened again so that recursive types terminate.
+func walkStructColumns(t reflect.Type, prefix string, index []int, depth int, visiting map[reflect.Type]bool) []structColumn {
+	var cols []structColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		col, skip := parseStructTag(field)
+		if skip {
+			continue
+		}
+		col.index = append(append([]int(nil), index...), i)
+		col.typ = field.Type
+		col.depth = depth
+
+		inner := field.Type
+		if inner.Kind() == reflect.Ptr {
+			inner = inner.Elem()
+		}
+		flatten := isFlattenable(inner) && !visiting[inner]
+		if field.Anonymous && col.name == "" {
+			// Exported fields of an unexported embedded struct are still
+			// readable, but not through a pointer reflect cannot allocate
+			if flatten && (field.IsExported() || field.Type.Kind() != reflect.Ptr) {
+				visiting[inner] = true
+				cols = append(cols, walkStructColumns(inner, prefix, col.index, depth+1, visiting)...)
+				delete(visiting, inner)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if col.name == "" {
+			col.name = field.Name
+		}
+		col.name = prefix + col.name
+		if flatten {
+			visiting[inner] = true
+			cols = append(cols, walkStructColumns(inner, col.name+".", col.index, depth+1, visiting)...)
+			delete(visiting, inner)
+			continue
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// dominant returns the index of the field that wins a column name, or nil if
+// none does.
+func dominant(cols []structColumn) []int {
+	best := cols[0]
+	tie := false
+	for _, col := range cols[1:] {
+		switch {
+		case col.depth < best.depth, col.depth == best.depth && col.tagged && !best.tagged:
+			best, tie = col, false
+		case col.depth == best.depth && col.tagged == best.tagged:
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return best.index
+}
+
+// parseStructTag reads the bluejay tag of a field, or its json tag if it has
+// none, reporting whether the field is skipped.
+func parseStructTag(field reflect.StructField) (structColumn, bool) {
+	tag, ok := field.Tag.Lookup("bluejay")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return structColumn{}, true
+	}
+	parts := strings.Split(tag, ",")
+	col := structColumn{name: parts[0], tagged: parts[0] != ""}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			col.omitEmpty = true
+		case "index":
+			col.indexed = true
+		case "categorical":
+			col.categorical = true
+		}
+	}
+	return col, false
+}
+
+// isFlattenable reports whether the fields of a type become columns of their
+// own. Times are kept whole.
+func isFlattenable(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+// structRow converts a struct to a column map. Columns nested under a nil
+// pointer are nil.
+func structRow(v reflect.Value, cols []structColumn) map[string]interface{} {
+	values := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		field, ok := structField(v, col.index, false)
+		if !ok || col.omitEmpty && field.IsZero() {
+			values[col.name] = nil
+			continue
+		}
+		values[col.name] = field.Interface()
+	}
+	return values
+}
+
+// structField returns the field of v at index. Nil pointers to nested structs
+// are allocated if alloc is set, and otherwise reported as missing.
+func structField(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
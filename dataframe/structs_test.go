@@ -0,0 +1,178 @@
+package dataframe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type taggedItem struct {
+	Name     string `bluejay:"name"`
+	Note     string `bluejay:"note,omitempty"`
+	City     string `bluejay:"city,categorical"`
+	Email    string `bluejay:",index" json:"mail"`
+	JSONOnly string `json:"json_only,omitempty"`
+	Both     string `bluejay:"bj" json:"js"`
+	Token    string `bluejay:"-"`
+	Secret   string `json:"-"`
+	hidden   int
+}
+
+type homeAddress struct {
+	City string
+	Zip  *int
+}
+
+type resident struct {
+	Name    string
+	Address homeAddress
+	Home    *homeAddress `bluejay:"home"`
+	Born    time.Time
+}
+
+type innerBase struct {
+	ID   int
+	Name string
+}
+
+type OuterBase struct {
+	ID   int
+	Name string
+}
+
+type leftX struct{ X int }
+
+type rightX struct{ X int }
+
+type taggedX struct {
+	X int `bluejay:"X"`
+}
+
+type listNode struct {
+	Val  int
+	Next *listNode
+}
+
+func TestStructColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+		index [][]int // Field indexes of the columns, if checked
+	}{
+		{"tags", taggedItem{}, []string{"name", "note", "city", "Email", "json_only", "bj"}, nil},
+		{"nested", resident{}, []string{"Name", "Address.City", "Address.Zip", "home.City", "home.Zip", "Born"}, nil},
+		{"outer field wins", struct {
+			OuterBase
+			Name string
+		}{}, []string{"ID", "Name"}, [][]int{{0, 0}, {1}}},
+		{"tie dropped", struct {
+			leftX
+			rightX
+			Y int
+		}{}, []string{"Y"}, nil},
+		{"tagged field wins tie", struct {
+			leftX
+			taggedX
+		}{}, []string{"X"}, [][]int{{1, 0}}},
+		{"embedded with tag name", struct {
+			OuterBase `bluejay:"b"`
+		}{}, []string{"b.ID", "b.Name"}, nil},
+		{"unexported embedded", struct {
+			innerBase
+			Qty int
+		}{}, []string{"ID", "Name", "Qty"}, nil},
+		{"unexported embedded pointer", struct {
+			*innerBase
+			Qty int
+		}{}, []string{"Qty"}, nil},
+		{"recursive", listNode{}, []string{"Val", "Next"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols := structColumns(reflect.TypeOf(tt.value))
+			var names []string
+			var index [][]int
+			for _, col := range cols {
+				names = append(names, col.name)
+				index = append(index, col.index)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("columns = %v, want %v", names, tt.want)
+			}
+			if tt.index != nil && !reflect.DeepEqual(index, tt.index) {
+				t.Errorf("field indexes = %v, want %v", index, tt.index)
+			}
+		})
+	}
+
+	cols := structColumns(reflect.TypeOf(taggedItem{}))
+	if !cols[1].omitEmpty || !cols[2].categorical || !cols[3].indexed || cols[0].omitEmpty || cols[0].categorical || cols[0].indexed {
+		t.Errorf("tag options = %+v", cols)
+	}
+}
+
+func TestFromStructsTags(t *testing.T) {
+	df, err := NewDataFrame([]taggedItem{
+		{Name: "pen", City: "Oslo", Email: "a@x", JSONOnly: "j", Both: "b", Token: "t", Secret: "s", hidden: 1},
+		{Name: "ink", Note: "dry", City: "Rome"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	row, err := df.ReadRow(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"name": "pen", "note": nil, "city": "Oslo", "Email": "a@x", "json_only": "j", "bj": "b"}
+	if got := toRowMap(row); !reflect.DeepEqual(got, want) {
+		t.Errorf("row 0 = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(df.bloomColumns, []string{"Email"}) {
+		t.Errorf("Bloom filter columns = %v, want [Email]", df.bloomColumns)
+	}
+	if values, err := df.Categories("city"); err != nil || !reflect.DeepEqual(values, []string{"Oslo", "Rome"}) {
+		t.Errorf("Categories(city) = %v, %v", values, err)
+	}
+
+	got, err := Get[taggedItem](df, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantRow := (taggedItem{Name: "ink", Note: "dry", City: "Rome"}); got != wantRow {
+		t.Errorf("Get = %+v, want %+v", got, wantRow)
+	}
+}
+
+func TestFromStructsNested(t *testing.T) {
+	zip := 1234
+	born := time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)
+	people := []resident{
+		{Name: "Ann", Address: homeAddress{City: "Oslo", Zip: &zip}, Home: &homeAddress{City: "Bergen"}, Born: born},
+		{Name: "Bob", Address: homeAddress{City: "Rome"}},
+	}
+	df, err := NewDataFrame(people)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	row, err := df.ReadRow(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := toRowMap(row)
+	if values["Address.City"] != "Rome" || values["home.City"] != nil || values["home.Zip"] != nil {
+		t.Errorf("row 1 = %v, want Address.City Rome and nil home columns", values)
+	}
+
+	got, err := ToStructs[resident](df)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, people) {
+		t.Errorf("ToStructs = %+v, want %+v", got, people)
+	}
+}
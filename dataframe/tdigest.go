@@ -0,0 +1,117 @@
+package dataframe
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	digestCompression = 100 // Bounds a digest to about this many centroids
+	digestBufferSize  = 500 // Values buffered before they are merged into the centroids
+)
+
+// centroid is a cluster of values of a t-digest.
+type centroid struct {
+	mean, weight float64
+}
+
+// tdigest is a merging t-digest, a sketch of a distribution that answers
+// quantile queries with an error that shrinks towards the tails. Digests built
+// over separate chunks merge into a digest of all of them.
+type tdigest struct {
+	centroids []centroid // Sorted by mean
+	buffer    []centroid // Not yet merged
+	total     float64
+	min, max  float64
+}
+
+func newTDigest() *tdigest {
+	return &tdigest{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (t *tdigest) add(x float64) {
+	t.addCentroid(centroid{mean: x, weight: 1})
+}
+
+func (t *tdigest) addCentroid(c centroid) {
+	t.buffer = append(t.buffer, c)
+	t.total += c.weight
+	t.min = math.Min(t.min, c.mean)
+	t.max = math.Max(t.max, c.mean)
+	if len(t.buffer) >= digestBufferSize {
+		t.compress()
+	}
+}
+
+// merge adds the values summarized by another digest.
+func (t *tdigest) merge(other *tdigest) {
+	for _, list := range [][]centroid{other.centroids, other.buffer} {
+		for _, c := range list {
+			t.addCentroid(c)
+		}
+	}
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+}
+
+// compress merges the buffer into the centroids. A centroid covering the
+// quantiles around q may hold at most 4·n·q·(1-q)/compression values, so
+// centroids stay small near the tails where precision matters most.
+func (t *tdigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, digestCompression)
+	cur := all[0]
+	var before float64
+	for _, c := range all[1:] {
+		q0 := before / t.total
+		q2 := (before + cur.weight + c.weight) / t.total
+		limit := 4 * t.total * math.Min(q0*(1-q0), q2*(1-q2)) / digestCompression
+		if cur.weight+c.weight <= limit {
+			cur.mean += (c.mean - cur.mean) * c.weight / (cur.weight + c.weight)
+			cur.weight += c.weight
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.weight
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = t.buffer[:0]
+}
+
+// quantile estimates the value at quantile q in [0, 1] by interpolating between
+// the centroids, whose values are assumed spread around their means.
+func (t *tdigest) quantile(q float64) float64 {
+	t.compress()
+	c := t.centroids
+	switch {
+	case len(c) == 0:
+		return math.NaN()
+	case q <= 0:
+		return t.min
+	case q >= 1:
+		return t.max
+	case len(c) == 1:
+		return c[0].mean
+	}
+
+	index := q * t.total
+	if first := c[0].weight / 2; index < first {
+		return t.min + (c[0].mean-t.min)*index/first
+	}
+	cumulative := c[0].weight / 2
+	for i := 0; i < len(c)-1; i++ {
+		step := (c[i].weight + c[i+1].weight) / 2
+		if cumulative+step > index {
+			return c[i].mean + (c[i+1].mean-c[i].mean)*(index-cumulative)/step
+		}
+		cumulative += step
+	}
+	last := c[len(c)-1]
+	return math.Min(t.max, last.mean+(t.max-last.mean)*(index-cumulative)/(last.weight/2))
+}
@@ -0,0 +1,446 @@
+package dataframe
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aggnr/bluejay/db"
+)
+
+// timeIndex is an ordered index of a time.Time column: a B+Tree of the
+// distinct times as Unix nanoseconds, and the ids of the rows at each time.
+type timeIndex struct {
+	column string
+	tree   *db.BPlusTree
+	ids    map[int][]int // Ids of the rows at each time, ascending
+	times  map[int]int   // Time of each indexed row, by id
+}
+
+// add indexes a row, replacing its previous time. Rows whose value is not a
+// time are left out.
+func (ix *timeIndex) add(id int, row interface{}) {
+	ix.remove(id)
+	t, ok := timeValue(toRowMap(row)[ix.column])
+	if !ok {
+		return
+	}
+	key := int(t.UnixNano())
+	ids := ix.ids[key]
+	i := sort.SearchInts(ids, id)
+	ix.ids[key] = append(ids[:i], append([]int{id}, ids[i:]...)...)
+	ix.times[id] = key
+	if len(ix.ids[key]) == 1 {
+		ix.tree.Insert(key)
+	}
+}
+
+func (ix *timeIndex) remove(id int) {
+	key, ok := ix.times[id]
+	if !ok {
+		return
+	}
+	delete(ix.times, id)
+	ids := ix.ids[key]
+	i := sort.SearchInts(ids, id)
+	ids = append(ids[:i], ids[i+1:]...)
+	if len(ids) == 0 {
+		delete(ix.ids, key)
+		ix.tree.Delete(key)
+		return
+	}
+	ix.ids[key] = ids
+}
+
+// rangeIDs returns the ids of the rows with times between lo and hi inclusive,
+// in time order.
+func (ix *timeIndex) rangeIDs(lo, hi int64) []int {
+	var ids []int
+	for _, key := range ix.tree.Range(int(lo), int(hi)) {
+		ids = append(ids, ix.ids[key]...)
+	}
+	return ids
+}
+
+// timeValue returns the time.Time held by a value, if any.
+func timeValue(val interface{}) (time.Time, bool) {
+	val, ok := deref(val)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, ok := val.(time.Time)
+	return t, ok
+}
+
+// SetTimeIndex builds an ordered index on a time.Time column, kept up to date
+// as rows are written, for Between, Resample and AsOfJoin. Rows with a null
+// time are not indexed. The index is held in memory and is not persisted by
+// durable frames.
+func (df *DataFrame) SetTimeIndex(column string) error {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if !hasField(df.fields, column) {
+		return fmt.Errorf("column %s not found", column)
+	}
+	ix := &timeIndex{column: column, ids: make(map[int][]int), times: make(map[int]int)}
+	err := df.scanRows(func(id int, row map[string]interface{}) error {
+		val, ok := deref(row[column])
+		if !ok {
+			return nil
+		}
+		t, ok := val.(time.Time)
+		if !ok {
+			return fmt.Errorf("column %s holds %T in row %d, not time.Time", column, val, id)
+		}
+		key := int(t.UnixNano())
+		ix.ids[key] = append(ix.ids[key], id)
+		ix.times[id] = key
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]int, 0, len(ix.ids))
+	for key := range ix.ids {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	ix.tree = db.NewBPlusTree(len(keys))
+	if len(keys) > 0 {
+		if ix.tree, err = db.BulkLoad(keys, indexFillFactor); err != nil {
+			return err
+		}
+	}
+	df.timeIndex = ix
+	return nil
+}
+
+// TimeIndex returns the column of the time index, or "" if none is set.
+func (df *DataFrame) TimeIndex() string {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if df.timeIndex == nil {
+		return ""
+	}
+	return df.timeIndex.column
+}
+
+// requireTimeIndex returns the time index, or an error if none is set. The
+// caller must hold df.mutex.
+func (df *DataFrame) requireTimeIndex() (*timeIndex, error) {
+	if df.timeIndex == nil {
+		return nil, fmt.Errorf("DataFrame %s has no time index, set one with SetTimeIndex", df.Name)
+	}
+	return df.timeIndex, nil
+}
+
+// copyFrame returns a new DataFrame with the given rows of df, each passed to
+// transform if it is not nil, and the same time index, codec, Bloom filters and
+// dictionaries. The caller must hold df.mutex.
+func (df *DataFrame) copyFrame(ids []int, transform func(row Row)) (*DataFrame, error) {
+	var rows []Row
+	err := df.scanIDs(ids, func(id int, row map[string]interface{}) error {
+		copied := make(Row, len(row))
+		for col, val := range row {
+			copied[col] = val
+		}
+		if transform != nil {
+			transform(copied)
+		}
+		rows = append(rows, copied)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := df.derivedFrameFromRows(df.fields, fieldNames(df.fields), ids, rows)
+	if err != nil {
+		return nil, err
+	}
+	if df.timeIndex != nil {
+		if err := out.SetTimeIndex(df.timeIndex.column); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Between returns the rows whose time index lies between start and end
+// inclusive, as a new DataFrame with the same ids and time index.
+func (df *DataFrame) Between(start, end time.Time) (*DataFrame, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	ix, err := df.requireTimeIndex()
+	if err != nil {
+		return nil, err
+	}
+	ids := ix.rangeIDs(start.UnixNano(), end.UnixNano())
+	sort.Ints(ids)
+	return df.copyFrame(ids, nil)
+}
+
+// ConvertTimezone returns a copy of the DataFrame with the times of the given
+// columns in loc. Without columns, the time index column is converted, or
+// every time.Time column if there is no time index. The instants are unchanged,
+// so the time index still applies.
+func (df *DataFrame) ConvertTimezone(loc *time.Location, columns ...string) (*DataFrame, error) {
+	if loc == nil {
+		return nil, fmt.Errorf("no location to convert to")
+	}
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if len(columns) == 0 {
+		if df.timeIndex != nil {
+			columns = []string{df.timeIndex.column}
+		} else {
+			for _, field := range df.fields {
+				if field.Type == reflect.TypeOf(time.Time{}) {
+					columns = append(columns, field.Name)
+				}
+			}
+		}
+	}
+	for _, col := range columns {
+		if !hasField(df.fields, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+	}
+	return df.copyFrame(df.rowIDs(), func(row Row) {
+		for _, col := range columns {
+			if t, ok := timeValue(row[col]); ok {
+				row[col] = t.In(loc)
+			}
+		}
+	})
+}
+
+// Resampler groups the rows of a DataFrame into fixed time bins. It is built
+// by Resample.
+type Resampler struct {
+	df    *DataFrame
+	every time.Duration
+	err   error
+}
+
+// Resample groups the rows into bins of the given duration, such as "1h" or
+// "15m", by their time index. Bins are aligned to multiples of the duration
+// since the zero time, as by time.Time.Truncate.
+func (df *DataFrame) Resample(every string) *Resampler {
+	d, err := time.ParseDuration(every)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("bin duration %v is not positive", d)
+	} else if err != nil {
+		err = fmt.Errorf("invalid bin duration %q: %v", every, err)
+	}
+	return &Resampler{df: df, every: d, err: err}
+}
+
+// Agg computes the aggregations for every bin from the first to the last row,
+// including empty bins in between. The result has one row per bin, in time
+// order, holding the start of the bin in the time index column, which is
+// also its time index.
+func (r *Resampler) Agg(aggs ...Agg) (*DataFrame, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(aggs) == 0 {
+		return nil, fmt.Errorf("no aggregations to compute")
+	}
+	df := r.df
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	ix, err := df.requireTimeIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, agg := range aggs {
+		if !hasField(df.fields, agg.column) {
+			return nil, fmt.Errorf("column %s not found", agg.column)
+		}
+		if agg.Name() == ix.column {
+			return nil, fmt.Errorf("aggregation %s clashes with the time index column", agg.Name())
+		}
+	}
+
+	keys := ix.tree.Keys()
+	if len(keys) == 0 {
+		return newDataFrameFromRows(df.Name+"_resample", resampleFields(df.fields, ix.column, aggs), nil, nil)
+	}
+	// Bins are numbered from the one holding the earliest row, whose location
+	// the bin starts take
+	first := time.Unix(0, int64(keys[0])).Truncate(r.every)
+	err = df.scanIDs(ix.rangeIDs(int64(keys[0]), int64(keys[0])), func(id int, row map[string]interface{}) error {
+		if t, ok := timeValue(row[ix.column]); ok {
+			first = t.Truncate(r.every)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	numBins := int(time.Unix(0, int64(keys[len(keys)-1])).Sub(first)/r.every) + 1
+
+	bins := make([][]aggState, numBins)
+	err = df.scanIDs(df.rowIDs(), func(id int, row map[string]interface{}) error {
+		key, ok := ix.times[id]
+		if !ok {
+			return nil
+		}
+		bin := int(time.Unix(0, int64(key)).Sub(first) / r.every)
+		if bins[bin] == nil {
+			bins[bin] = make([]aggState, len(aggs))
+		}
+		for j, agg := range aggs {
+			if err := bins[bin][j].add(agg.fn, row[agg.column]); err != nil {
+				return fmt.Errorf("error aggregating column %s: %v", agg.column, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, numBins)
+	rows := make([]Row, numBins)
+	empty := make([]aggState, len(aggs))
+	for i, states := range bins {
+		if states == nil {
+			states = empty
+		}
+		ids[i] = i
+		rows[i] = Row{ix.column: first.Add(time.Duration(i) * r.every)}
+		for j, agg := range aggs {
+			rows[i][agg.Name()] = states[j].value(agg.fn)
+		}
+	}
+	out, err := newDataFrameFromRows(df.Name+"_resample", resampleFields(df.fields, ix.column, aggs), ids, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.SetTimeIndex(ix.column); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+// resampleFields returns the schema of a resampled DataFrame.
+func resampleFields(input []Field, column string, aggs []Agg) []Field {
+	fields := []Field{{Name: column, Type: reflect.TypeOf(time.Time{})}}
+	for _, agg := range aggs {
+		fields = append(fields, aggField(input, agg))
+	}
+	return fields
+}
+
+// asOfRow is a row of the right side of an as-of join.
+type asOfRow struct {
+	time int
+	id   int
+	row  Row
+}
+
+// AsOfJoin matches every row of df with the last row of right at or before
+// its time, by the time indexes of both. With by columns, only rows with equal
+// values in them match. A positive tolerance leaves out matches further back
+// than it. Every row of df is kept with its id, and the columns of right other
+// than the by columns are added, suffixed with _other where the names clash,
+// and null where there is no match.
+func (df *DataFrame) AsOfJoin(right *DataFrame, tolerance time.Duration, by ...string) (*DataFrame, error) {
+	// Each side is read under its own lock, so that a frame can be joined with itself
+	right.mutex.RLock()
+	rix, err := right.requireTimeIndex()
+	if err != nil {
+		right.mutex.RUnlock()
+		return nil, err
+	}
+	rightFields := right.fields
+	groups := make(map[string][]asOfRow)
+	err = right.scanRows(func(id int, row map[string]interface{}) error {
+		if key, ok := rix.times[id]; ok {
+			group := asOfKey(row, by)
+			groups[group] = append(groups[group], asOfRow{time: key, id: id, row: row})
+		}
+		return nil
+	})
+	right.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, rows := range groups {
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i].time < rows[j].time || rows[i].time == rows[j].time && rows[i].id < rows[j].id
+		})
+	}
+
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	ix, err := df.requireTimeIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range by {
+		if !hasField(df.fields, col) || !hasField(rightFields, col) {
+			return nil, fmt.Errorf("column %s not found on both sides", col)
+		}
+	}
+	on := by
+	if rix.column == ix.column {
+		on = append(append([]string(nil), by...), ix.column)
+	}
+	fields, rename := joinFields(df.fields, rightFields, on)
+
+	var ids []int
+	var rows []Row
+	err = df.scanRows(func(id int, row map[string]interface{}) error {
+		out := make(Row, len(fields))
+		for _, field := range fields {
+			out[field.Name] = nil
+		}
+		for col, val := range row {
+			out[col] = val
+		}
+		if key, ok := ix.times[id]; ok {
+			candidates := groups[asOfKey(row, by)]
+			i := sort.Search(len(candidates), func(i int) bool { return candidates[i].time > key }) - 1
+			if i >= 0 && (tolerance <= 0 || time.Duration(key-candidates[i].time) <= tolerance) {
+				for name, col := range rename {
+					out[name] = candidates[i].row[col]
+				}
+			}
+		}
+		ids = append(ids, id)
+		rows = append(rows, out)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := newDataFrameFromRows(df.Name+"_asof", fields, ids, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.SetTimeIndex(ix.column); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+// asOfKey returns the key of the group of a row in an as-of join.
+func asOfKey(row Row, by []string) string {
+	if len(by) == 0 {
+		return ""
+	}
+	return groupKey(0, row, by)
+}
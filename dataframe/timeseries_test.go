@@ -0,0 +1,211 @@
+package dataframe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type trade struct {
+	At    time.Time
+	Sym   string
+	Price float64
+	Qty   int
+}
+
+type quote struct {
+	At  time.Time
+	Sym string
+	Bid float64
+}
+
+// tradeTime returns the time at hour:minute on a fixed day, in a zone an hour ahead of UTC.
+func tradeTime(hour, minute int) time.Time {
+	return time.Date(2024, 3, 1, hour, minute, 0, 0, time.FixedZone("X", 3600))
+}
+
+// timeFrame creates a frame with a time index on At.
+func timeFrame(t *testing.T, data interface{}) *DataFrame {
+	t.Helper()
+	df, err := NewDataFrame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(df.Close)
+	if err := df.SetTimeIndex("At"); err != nil {
+		t.Fatal(err)
+	}
+	return df
+}
+
+func trades(t *testing.T) *DataFrame {
+	return timeFrame(t, []trade{
+		{tradeTime(10, 5), "A", 10, 1},
+		{tradeTime(10, 20), "B", 20, 2},
+		{tradeTime(10, 12), "A", 11, 3},
+		{tradeTime(10, 50), "A", 12, 4},
+		{tradeTime(11, 40), "B", 21, 5},
+		{tradeTime(10, 14), "B", 19, 6},
+	})
+}
+
+func TestBetween(t *testing.T) {
+	df := trades(t)
+	df.InsertRow(6, trade{tradeTime(10, 30), "A", 13, 7})
+	if err := df.UpdateRow(4, map[string]interface{}{"At": tradeTime(10, 40)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := df.DeleteRow(2); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := df.Between(tradeTime(10, 12), tradeTime(10, 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if got := out.rowIDs(); !reflect.DeepEqual(got, []int{1, 3, 4, 5, 6}) {
+		t.Errorf("Between kept ids %v, want [1 3 4 5 6]", got)
+	}
+	if out.TimeIndex() != "At" {
+		t.Errorf("result has time index %q, want At", out.TimeIndex())
+	}
+
+	plain, err := NewDataFrame([]trade{{tradeTime(10, 5), "A", 10, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if _, err := plain.Between(tradeTime(10, 0), tradeTime(11, 0)); err == nil {
+		t.Error("Between without a time index succeeded")
+	}
+}
+
+func TestResample(t *testing.T) {
+	df := trades(t)
+	out, err := df.Resample("15m").Agg(Sum("Qty"), Mean("Price").As("avg"), Count("Qty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	type bin struct {
+		start time.Time
+		qty   float64
+		avg   interface{}
+		count int
+	}
+	want := []bin{
+		{tradeTime(10, 0), 10, 40.0 / 3, 3},
+		{tradeTime(10, 15), 2, 20.0, 1},
+		{tradeTime(10, 30), 0, nil, 0},
+		{tradeTime(10, 45), 4, 12.0, 1},
+		{tradeTime(11, 0), 0, nil, 0},
+		{tradeTime(11, 15), 0, nil, 0},
+		{tradeTime(11, 30), 5, 21.0, 1},
+	}
+	var got []bin
+	err = out.scanRows(func(id int, row map[string]interface{}) error {
+		got = append(got, bin{row["At"].(time.Time), row["Qty_sum"].(float64), row["avg"], row["Qty_count"].(int)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bins = %v, want %v", got, want)
+	}
+	if out.TimeIndex() != "At" {
+		t.Errorf("result has time index %q, want At", out.TimeIndex())
+	}
+
+	for _, every := range []string{"0s", "-1m", "soon"} {
+		if _, err := df.Resample(every).Agg(Sum("Qty")); err == nil {
+			t.Errorf("Resample(%q) succeeded", every)
+		}
+	}
+	if _, err := df.Resample("1h").Agg(Max("Qty").As("At")); err == nil {
+		t.Error("aggregation named after the time index succeeded")
+	}
+}
+
+func TestAsOfJoin(t *testing.T) {
+	df := trades(t)
+	quotes := timeFrame(t, []quote{
+		{tradeTime(10, 0), "A", 9.5},
+		{tradeTime(10, 10), "B", 19.5},
+		{tradeTime(10, 13), "A", 10.5},
+		{tradeTime(10, 45), "A", 11.5},
+	})
+
+	tests := []struct {
+		name      string
+		tolerance time.Duration
+		by        []string
+		bids      []interface{}
+	}{
+		{"by symbol", 0, []string{"Sym"}, []interface{}{9.5, 19.5, 9.5, 11.5, 19.5, 19.5}},
+		{"tolerance", 5 * time.Minute, []string{"Sym"}, []interface{}{9.5, nil, nil, 11.5, nil, 19.5}},
+		{"any symbol", 0, nil, []interface{}{9.5, 10.5, 19.5, 11.5, 11.5, 10.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := df.AsOfJoin(quotes, tt.tolerance, tt.by...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			var bids []interface{}
+			err = out.scanRows(func(id int, row map[string]interface{}) error {
+				bids = append(bids, row["Bid"])
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(bids, tt.bids) {
+				t.Errorf("bids = %v, want %v", bids, tt.bids)
+			}
+		})
+	}
+
+	out, err := df.AsOfJoin(quotes, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	row, err := out.ReadRow(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := toRowMap(row); got["Sym"] != "B" || got["Sym_other"] != "A" {
+		t.Errorf("row 1 = %v, want Sym B and Sym_other A", got)
+	}
+}
+
+func TestConvertTimezone(t *testing.T) {
+	df := trades(t)
+	loc := time.FixedZone("Y", -5*3600)
+	out, err := df.ConvertTimezone(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	row, err := out.ReadRow(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := toRowMap(row)["At"].(time.Time)
+	if got.Location() != loc || !got.Equal(tradeTime(10, 5)) {
+		t.Errorf("At = %v, want %v in %v", got, tradeTime(10, 5), loc)
+	}
+	between, err := out.Between(tradeTime(10, 0), tradeTime(10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer between.Close()
+	if ids := between.rowIDs(); !reflect.DeepEqual(ids, []int{0}) {
+		t.Errorf("Between after conversion kept %v, want [0]", ids)
+	}
+}
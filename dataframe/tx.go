@@ -0,0 +1,315 @@
+package dataframe
+
+import (
+	"fmt"
+	"sort"
+)
+
+// rowVersion is the state of a row before the write with the given version
+// changed it. Versions are kept only while snapshots that may need them are open.
+type rowVersion struct {
+	version uint64
+	row     interface{}
+	existed bool
+}
+
+// Snapshot is a read-only view of a DataFrame as of the moment it was taken.
+// Writes made afterwards are not visible through it, and reading from it never
+// waits for flushes or for other readers. Release it once done, since the
+// DataFrame keeps the prior versions of rows written while it is open.
+type Snapshot struct {
+	df      *DataFrame
+	version uint64
+}
+
+// Snapshot returns a point-in-time read view of the DataFrame.
+func (df *DataFrame) Snapshot() *Snapshot {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	s := &Snapshot{df: df, version: df.version}
+	df.snapshots[s] = true
+	return s
+}
+
+// Release closes the snapshot and drops row versions no open snapshot needs.
+func (s *Snapshot) Release() {
+	df := s.df
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if !df.snapshots[s] {
+		return
+	}
+	delete(df.snapshots, s)
+
+	if len(df.snapshots) == 0 {
+		df.history = make(map[int][]rowVersion)
+		return
+	}
+	oldest := s.version
+	for open := range df.snapshots {
+		oldest = open.version
+		break
+	}
+	for open := range df.snapshots {
+		if open.version < oldest {
+			oldest = open.version
+		}
+	}
+	// Only versions written after the oldest open snapshot can still be read
+	for id, versions := range df.history {
+		i := sort.Search(len(versions), func(i int) bool { return versions[i].version > oldest })
+		if i == len(versions) {
+			delete(df.history, id)
+		} else {
+			df.history[id] = versions[i:]
+		}
+	}
+}
+
+// saveHistory records the current state of the given rows before the next write
+// changes them, if any snapshot is open. The caller must hold the write lock.
+func (df *DataFrame) saveHistory(ids []int) error {
+	if len(df.snapshots) == 0 {
+		return nil
+	}
+	version := df.version + 1
+	for _, id := range ids {
+		versions := df.history[id]
+		if len(versions) > 0 && versions[len(versions)-1].version == version {
+			continue // Already saved by an earlier record of the same write
+		}
+		prior := rowVersion{version: version, existed: df.hasRow(id)}
+		if prior.existed {
+			row, err := df.readRow(id)
+			if err != nil {
+				return err
+			}
+			prior.row = row
+		}
+		df.history[id] = append(versions, prior)
+	}
+	return nil
+}
+
+// rowAt returns the state of a row as of the snapshot if it has been written
+// since. changed is false if the current state is also the snapshot's. The
+// caller must hold df.mutex.
+func (s *Snapshot) rowAt(id int) (row interface{}, existed, changed bool) {
+	for _, prior := range s.df.history[id] {
+		if prior.version > s.version {
+			return prior.row, prior.existed, true
+		}
+	}
+	return nil, false, false
+}
+
+// ReadRow looks up a row by id as of the snapshot.
+func (s *Snapshot) ReadRow(id int) (interface{}, error) {
+	s.df.mutex.RLock()
+	defer s.df.mutex.RUnlock()
+	return s.readRow(id)
+}
+
+func (s *Snapshot) readRow(id int) (interface{}, error) {
+	if row, existed, changed := s.rowAt(id); changed {
+		if !existed {
+			return nil, fmt.Errorf("row with id %d not found", id)
+		}
+		return row, nil
+	}
+	return s.df.readRow(id)
+}
+
+// IDs returns the ids of all rows in the snapshot in ascending order.
+func (s *Snapshot) IDs() []int {
+	s.df.mutex.RLock()
+	defer s.df.mutex.RUnlock()
+	return s.rowIDs()
+}
+
+// rowIDs is IDs without locking. The caller must hold df.mutex.
+func (s *Snapshot) rowIDs() []int {
+	var ids []int
+	for _, id := range s.df.rowIDs() {
+		if _, _, changed := s.rowAt(id); !changed {
+			ids = append(ids, id)
+		}
+	}
+	for id := range s.df.history {
+		if _, existed, changed := s.rowAt(id); changed && existed {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Scan calls fn for every row of the snapshot in id order. The read lock is
+// only held while a window of chunks is loaded, so writers can proceed while
+// fn runs; the rows passed to fn still all belong to the snapshot.
+func (s *Snapshot) Scan(fn func(id int, row Row) error) error {
+	ids := s.IDs()
+	tasks := groupByChunk(ids)
+	window := 2 * workerCount()
+	for start := 0; start < len(tasks); start += window {
+		end := start + window
+		if end > len(tasks) {
+			end = len(tasks)
+		}
+		chunks := make([]Chunk, end-start)
+
+		s.df.mutex.RLock()
+		err := s.df.runChunks(tasks[start:end], func(i int, task chunkTask) error {
+			var err error
+			chunks[i], err = s.loadChunk(task)
+			return err
+		})
+		s.df.mutex.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		for _, chunk := range chunks {
+			for i, id := range chunk.IDs {
+				if err := fn(id, chunk.Rows[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loadChunk reads the rows of a task as of the snapshot. The caller must hold df.mutex.
+func (s *Snapshot) loadChunk(task chunkTask) (Chunk, error) {
+	current := chunkTask{chunkID: task.chunkID}
+	for _, id := range task.ids {
+		if _, _, changed := s.rowAt(id); !changed {
+			current.ids = append(current.ids, id)
+		}
+	}
+	loaded, err := s.df.loadChunk(current)
+	if err != nil {
+		return loaded, err
+	}
+	rows := make(map[int]Row, len(loaded.IDs))
+	for i, id := range loaded.IDs {
+		rows[id] = loaded.Rows[i]
+	}
+
+	chunk := Chunk{ID: task.chunkID}
+	for _, id := range task.ids {
+		row, ok := rows[id]
+		if prior, existed, changed := s.rowAt(id); changed {
+			row, ok = toRowMap(prior), existed
+		}
+		if ok {
+			chunk.IDs = append(chunk.IDs, id)
+			chunk.Rows = append(chunk.Rows, row)
+		}
+	}
+	return chunk, nil
+}
+
+// Tx is a batch of writes to a DataFrame that is applied atomically on Commit.
+// Reads through the transaction see the DataFrame as of Begin plus the
+// transaction's own writes. Commit fails if another write changed a row the
+// transaction writes after the transaction began.
+type Tx struct {
+	df       *DataFrame
+	snapshot *Snapshot
+	records  []walRecord
+	pending  map[int]interface{} // Rows written by the transaction, nil when deleted
+	done     bool
+}
+
+// Begin starts a transaction.
+func (df *DataFrame) Begin() *Tx {
+	return &Tx{df: df, snapshot: df.Snapshot(), pending: make(map[int]interface{})}
+}
+
+// ReadRow looks up a row by id as seen by the transaction.
+func (tx *Tx) ReadRow(id int) (interface{}, error) {
+	if tx.done {
+		return nil, fmt.Errorf("transaction already finished")
+	}
+	if row, ok := tx.pending[id]; ok {
+		if row == nil {
+			return nil, fmt.Errorf("row with id %d not found", id)
+		}
+		return row, nil
+	}
+	return tx.snapshot.ReadRow(id)
+}
+
+// InsertRow adds a row to the transaction.
+func (tx *Tx) InsertRow(id int, row interface{}) error {
+	if tx.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	values := toRowMap(row)
+	if values == nil {
+		return fmt.Errorf("unsupported row type %T", row)
+	}
+	tx.records = append(tx.records, walRecord{Op: walInsert, ID: id, Row: values})
+	tx.pending[id] = values
+	return nil
+}
+
+// UpdateRow adds an update of the given columns of an existing row to the transaction.
+func (tx *Tx) UpdateRow(id int, newValues map[string]interface{}) error {
+	row, err := tx.ReadRow(id)
+	if err != nil {
+		return err
+	}
+	updated := mergeRow(row, newValues)
+	tx.records = append(tx.records, walRecord{Op: walUpdate, ID: id, Row: updated})
+	tx.pending[id] = updated
+	return nil
+}
+
+// DeleteRow adds the removal of a row to the transaction.
+func (tx *Tx) DeleteRow(id int) error {
+	if _, err := tx.ReadRow(id); err != nil {
+		return err
+	}
+	tx.records = append(tx.records, walRecord{Op: walDelete, ID: id})
+	tx.pending[id] = nil
+	return nil
+}
+
+// Commit applies the writes of the transaction as one batch. Readers and
+// recovery after a crash see either all of them or none.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	defer tx.Rollback()
+	if len(tx.records) == 0 {
+		return nil
+	}
+
+	df := tx.df
+	defer df.flushIfFull()
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	for id := range tx.pending {
+		if _, _, changed := tx.snapshot.rowAt(id); changed {
+			return fmt.Errorf("write conflict on row %d", id)
+		}
+	}
+	return df.commit(tx.records)
+}
+
+// Rollback discards the writes of the transaction. It is a no-op after Commit.
+func (tx *Tx) Rollback() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+	tx.records, tx.pending = nil, nil
+	tx.snapshot.Release()
+}
@@ -0,0 +1,170 @@
+package dataframe
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTxCommitAndRollback(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "items")
+	df, want := durableItems(t, dir, 1500)
+
+	tx := df.Begin()
+	if err := tx.InsertRow(2000, durableItem{"pad", 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.UpdateRow(1, map[string]interface{}{"Qty": 30}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DeleteRow(2); err != nil {
+		t.Fatal(err)
+	}
+	if row, err := tx.ReadRow(1); err != nil || toRowMap(row)["Qty"] != 30 {
+		t.Errorf("transaction reads row 1 as %v, %v, want its own update", row, err)
+	}
+	if _, err := tx.ReadRow(2); err == nil {
+		t.Error("transaction reads the row it deleted")
+	}
+	if _, err := df.ReadRow(2000); err == nil {
+		t.Error("row inserted by an open transaction is visible")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("second commit of a transaction succeeded")
+	}
+	want[2000] = map[string]interface{}{"Name": "pad", "Qty": 7}
+	want[1]["Qty"] = 30
+	delete(want, 2)
+
+	rolledBack := df.Begin()
+	if err := rolledBack.UpdateRow(3, map[string]interface{}{"Qty": 99}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rolledBack.DeleteRow(4); err != nil {
+		t.Fatal(err)
+	}
+	rolledBack.Rollback()
+	if err := rolledBack.InsertRow(5000, durableItem{"cap", 1}); err == nil {
+		t.Error("write to a rolled back transaction succeeded")
+	}
+	if len(df.history) != 0 {
+		t.Errorf("%d row histories kept with no transaction open", len(df.history))
+	}
+	checkItems(t, df, want)
+	df.Close()
+
+	df, err := OpenDataFrame(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	checkItems(t, df, want)
+}
+
+func TestTxWriteConflict(t *testing.T) {
+	df, _ := durableItems(t, filepath.Join(t.TempDir(), "items"), 100)
+	defer df.Close()
+
+	tests := []struct {
+		name     string
+		tx       func(tx *Tx) error
+		other    func() error
+		conflict bool
+	}{
+		{"same row updated", func(tx *Tx) error {
+			return tx.UpdateRow(10, map[string]interface{}{"Qty": 1})
+		}, func() error {
+			return df.UpdateRow(10, map[string]interface{}{"Qty": 2})
+		}, true},
+		{"updated row deleted", func(tx *Tx) error {
+			return tx.UpdateRow(11, map[string]interface{}{"Qty": 1})
+		}, func() error {
+			return df.DeleteRow(11)
+		}, true},
+		{"same row inserted", func(tx *Tx) error {
+			return tx.InsertRow(500, durableItem{"pen", 1})
+		}, func() error {
+			df.InsertRow(500, durableItem{"ink", 2})
+			return nil
+		}, true},
+		{"other row updated", func(tx *Tx) error {
+			return tx.UpdateRow(12, map[string]interface{}{"Qty": 1})
+		}, func() error {
+			return df.UpdateRow(13, map[string]interface{}{"Qty": 2})
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := df.Begin()
+			if err := tt.tx(tx); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.other(); err != nil {
+				t.Fatal(err)
+			}
+			before := df.version
+			err := tx.Commit()
+			if tt.conflict {
+				if err == nil {
+					t.Error("commit over a conflicting write succeeded")
+				}
+				if df.version != before {
+					t.Error("conflicting commit applied its writes")
+				}
+			} else if err != nil {
+				t.Errorf("commit failed: %v", err)
+			}
+		})
+	}
+	if row, err := df.ReadRow(10); err != nil || toRowMap(row)["Qty"] != 2 {
+		t.Errorf("row 10 = %v, %v, want the write that won", row, err)
+	}
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	df, want := durableItems(t, filepath.Join(t.TempDir(), "items"), 2500)
+	defer df.Close()
+	if err := df.UpdateRow(0, map[string]interface{}{"Qty": 100}); err != nil {
+		t.Fatal(err) // Left unflushed
+	}
+	want[0]["Qty"] = 100
+	wantIDs := df.rowIDs()
+
+	s := df.Snapshot()
+	defer s.Release()
+	mutateItems(t, df, map[int]map[string]interface{}{1: {}})
+	if err := df.UpdateRow(0, map[string]interface{}{"Qty": 200}); err != nil {
+		t.Fatal(err)
+	}
+	df.InsertRow(3000, durableItem{"cap", 3})
+	if err := df.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.IDs(); !reflect.DeepEqual(got, wantIDs) {
+		t.Errorf("snapshot has %d ids, want %d", len(got), len(wantIDs))
+	}
+	for _, id := range []int{0, 1, 2} {
+		row, err := s.ReadRow(id)
+		if err != nil || !reflect.DeepEqual(toRowMap(row), want[id]) {
+			t.Errorf("snapshot reads row %d as %v, %v, want %v", id, row, err, want[id])
+		}
+	}
+	if _, err := s.ReadRow(3000); err == nil {
+		t.Error("snapshot reads a row inserted after it was taken")
+	}
+	got := make(map[int]map[string]interface{})
+	err := s.Scan(func(id int, row Row) error {
+		got[id] = map[string]interface{}(row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot scan gave %d rows, want %d", len(got), len(want))
+	}
+}
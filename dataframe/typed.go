@@ -0,0 +1,159 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Get reads the row with the given id into a struct of type T, usually the
+// struct type the DataFrame was created from. Columns are matched to fields as
+// FromStructs maps them; fields without a column are left zero, and numbers
+// convert between numeric types.
+func Get[T any](df *DataFrame, id int) (T, error) {
+	var out T
+	row, err := df.ReadRow(id)
+	if err != nil {
+		return out, err
+	}
+	err = decodeStruct(toRowMap(row), reflect.ValueOf(&out).Elem())
+	return out, err
+}
+
+// ToStructs reads every row into a struct of type T as Get does, in id order.
+// Chunks are decoded on the worker pool.
+func ToStructs[T any](df *DataFrame) ([]T, error) {
+	parts, err := MapChunks(df, func(chunk Chunk) ([]T, error) {
+		structs := make([]T, len(chunk.Rows))
+		for i, row := range chunk.Rows {
+			if err := decodeStruct(row, reflect.ValueOf(&structs[i]).Elem()); err != nil {
+				return nil, fmt.Errorf("error decoding row %d: %v", chunk.IDs[i], err)
+			}
+		}
+		return structs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var structs []T
+	for _, part := range parts {
+		structs = append(structs, part...)
+	}
+	return structs, nil
+}
+
+// Float64s returns the values of a numeric column in id order, with NaN for nulls.
+func (df *DataFrame) Float64s(column string) ([]float64, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if !hasField(df.fields, column) {
+		return nil, fmt.Errorf("column %s not found", column)
+	}
+	parts, err := mapChunks(df, df.rowIDs(), func(chunk Chunk) ([]float64, error) {
+		values := make([]float64, len(chunk.Rows))
+		for i, row := range chunk.Rows {
+			val, ok := deref(row[column])
+			if !ok {
+				values[i] = math.NaN()
+				continue
+			}
+			if values[i], ok = numericValue(val); !ok {
+				return nil, fmt.Errorf("column %s holds %T in row %d, not a number", column, val, chunk.IDs[i])
+			}
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var values []float64
+	for _, part := range parts {
+		values = append(values, part...)
+	}
+	return values, nil
+}
+
+// Strings returns the values of a string column in id order, with "" for nulls.
+func (df *DataFrame) Strings(column string) ([]string, error) {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	if !hasField(df.fields, column) {
+		return nil, fmt.Errorf("column %s not found", column)
+	}
+	parts, err := mapChunks(df, df.rowIDs(), func(chunk Chunk) ([]string, error) {
+		values := make([]string, len(chunk.Rows))
+		for i, row := range chunk.Rows {
+			val, ok := deref(row[column])
+			if !ok {
+				continue
+			}
+			if values[i], ok = val.(string); !ok {
+				return nil, fmt.Errorf("column %s holds %T in row %d, not a string", column, val, chunk.IDs[i])
+			}
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, part := range parts {
+		values = append(values, part...)
+	}
+	return values, nil
+}
+
+// decodeStruct sets the fields of the struct v from the columns of row they map
+// to, as FromStructs maps them. Nested pointers to structs are allocated for
+// columns that are not nil.
+func decodeStruct(row map[string]interface{}, v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode a row into %s, which is not a struct", v.Type())
+	}
+	for _, col := range structColumns(v.Type()) {
+		val, ok := row[col.name]
+		if !ok {
+			continue
+		}
+		_, notNil := deref(val)
+		field, ok := structField(v, col.index, notNil)
+		if !ok {
+			continue
+		}
+		if err := setField(field, val); err != nil {
+			return fmt.Errorf("column %s: %v", col.name, err)
+		}
+	}
+	return nil
+}
+
+// setField sets a struct field to a column value, following pointers on either
+// side and converting between numeric types. nil sets the zero value.
+func setField(field reflect.Value, val interface{}) error {
+	val, ok := deref(val)
+	if !ok {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), val); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case isNumericKind(rv.Kind()) && isNumericKind(field.Kind()):
+		field.Set(rv.Convert(field.Type()))
+	default:
+		return fmt.Errorf("cannot set %s from %T", field.Type(), val)
+	}
+	return nil
+}
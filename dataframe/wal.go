@@ -0,0 +1,145 @@
+package dataframe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// walOp is the kind of mutation a log record describes.
+type walOp uint8
+
+const (
+	walInsert walOp = iota + 1
+	walUpdate
+	walDelete
+)
+
+// walRecord is one logged mutation. Inserts and updates carry the full row.
+type walRecord struct {
+	Op  walOp
+	ID  int
+	Row map[string]interface{}
+}
+
+// wal is an append-only write-ahead log. Each batch of records is written as
+// its length, a CRC32 checksum and its gob encoding, so a batch torn by a crash
+// is detected and dropped as a whole on replay.
+type wal struct {
+	file   *os.File
+	path   string
+	failed error // Write error that left a torn batch in the log
+}
+
+// openWAL opens the log at path for appending, creating it if needed.
+func openWAL(path string) (*wal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log %s: %v", path, err)
+	}
+	return &wal{file: file, path: path}, nil
+}
+
+// append writes records to the log as one batch and syncs it. A batch that
+// fails to be written is truncated away, since replay stops at the first torn
+// batch and would drop every batch after it. If it cannot be, or if the sync
+// fails and the batch may be partly on disk, the log refuses further batches
+// until the next checkpoint empties it.
+func (w *wal) append(records ...walRecord) error {
+	if w.failed != nil {
+		return fmt.Errorf("log failed on an earlier write: %v", w.failed)
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	for _, record := range records {
+		for _, val := range record.Row {
+			registerValueType(val) // Named types must be registered with gob
+		}
+	}
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(records); err != nil {
+		return err
+	}
+	var header [8]byte
+	binary.LittleEndian.PutUint32(header[0:], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload.Bytes()))
+
+	var buf bytes.Buffer
+	buf.Write(header[:])
+	buf.Write(payload.Bytes())
+	if _, err := w.file.Write(buf.Bytes()); err != nil {
+		if w.file.Truncate(offset) != nil {
+			w.failed = err
+		}
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Truncate(offset)
+		w.failed = err
+		return err
+	}
+	return nil
+}
+
+// truncate empties the log once everything in it is in the chunk files.
+func (w *wal) truncate() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.failed = nil
+	return nil
+}
+
+func (w *wal) close() error {
+	return w.file.Close()
+}
+
+// readWAL returns the records of the log at path in the order they were written.
+// Reading stops at the first incomplete or corrupt batch, which can only be the
+// last one written before a crash.
+func readWAL(path string) ([]walRecord, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading log %s: %v", path, err)
+	}
+
+	var records []walRecord
+	reader := bytes.NewReader(data)
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return records, nil
+		}
+		size := int(binary.LittleEndian.Uint32(header[0:]))
+		if size > reader.Len() {
+			return records, nil
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return records, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+			return records, nil
+		}
+
+		var batch []walRecord
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&batch); err != nil {
+			return records, nil
+		}
+		records = append(records, batch...)
+	}
+}
@@ -0,0 +1,88 @@
+package dataframe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadWALTruncatedTail(t *testing.T) {
+	batches := [][]walRecord{
+		{{Op: walInsert, ID: 0, Row: map[string]interface{}{"Name": "pen"}}},
+		{{Op: walUpdate, ID: 0, Row: map[string]interface{}{"Name": "ink"}}, {Op: walDelete, ID: 1}},
+		{{Op: walInsert, ID: 2, Row: map[string]interface{}{"Name": "pad"}}},
+	}
+
+	path := filepath.Join(t.TempDir(), walFile)
+	w, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ends []int // Size of the log after every batch
+	for _, batch := range batches {
+		if err := w.append(batch...); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, int(info.Size()))
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		size    int // Bytes of the log kept
+		flip    int // Byte to corrupt, or -1
+		batches int // Batches expected back
+	}{
+		{"complete", ends[2], -1, 3},
+		{"empty", 0, -1, 0},
+		{"torn first header", 3, -1, 0},
+		{"torn first payload", ends[0] - 1, -1, 0},
+		{"torn last header", ends[1] + 4, -1, 2},
+		{"header without payload", ends[1] + 8, -1, 2},
+		{"torn last payload", ends[2] - 1, -1, 2},
+		{"corrupt last payload", ends[2], ends[2] - 1, 2},
+		{"corrupt middle payload", ends[2], ends[1] - 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := append([]byte(nil), data[:tt.size]...)
+			if tt.flip >= 0 {
+				log[tt.flip] ^= 0xff
+			}
+			path := filepath.Join(t.TempDir(), walFile)
+			if err := os.WriteFile(path, log, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := readWAL(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []walRecord
+			for _, batch := range batches[:tt.batches] {
+				want = append(want, batch...)
+			}
+			if !reflect.DeepEqual(records, want) {
+				t.Errorf("readWAL = %v, want %v", records, want)
+			}
+		})
+	}
+}
+
+func TestReadWALMissing(t *testing.T) {
+	records, err := readWAL(filepath.Join(t.TempDir(), walFile))
+	if err != nil || records != nil {
+		t.Errorf("readWAL = %v, %v, want no records", records, err)
+	}
+}
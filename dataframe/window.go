@@ -0,0 +1,428 @@
+package dataframe
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Kinds of windows.
+const (
+	windowRolling   = iota // The last rows, or the rows within a duration
+	windowExpanding        // All rows so far
+	windowEWM              // All rows so far, weighted exponentially
+)
+
+// windowConfig holds the settings of Rolling, Expanding and EWM.
+type windowConfig struct {
+	orderBy string
+	alpha   float64
+}
+
+// WindowOption configures Rolling, Expanding and EWM.
+type WindowOption func(*windowConfig)
+
+// WithOrderBy moves windows over the rows in the order of a column instead of
+// id order. Ties keep id order and nulls come last.
+func WithOrderBy(column string) WindowOption {
+	return func(c *windowConfig) {
+		c.orderBy = column
+	}
+}
+
+// WithAlpha sets the smoothing factor of EWM, in (0, 1].
+func WithAlpha(alpha float64) WindowOption {
+	return func(c *windowConfig) {
+		c.alpha = alpha
+	}
+}
+
+// WithSpan sets the smoothing factor of EWM from a span of at least 1, as
+// alpha = 2 / (span + 1).
+func WithSpan(span float64) WindowOption {
+	return func(c *windowConfig) {
+		c.alpha = 2 / (span + 1)
+	}
+}
+
+// Window computes statistics of the numeric columns over a window moving
+// through the rows. It is built by Rolling, Expanding or EWM, and its methods
+// return a DataFrame with the same ids holding the statistic of each row's
+// window, plus the order column if one was chosen.
+type Window struct {
+	df         *DataFrame
+	kind       int
+	size       int           // Rows in a rolling window
+	duration   time.Duration // Time span of a rolling window over a time column
+	minPeriods int
+	cfg        *windowConfig
+	err        error
+}
+
+// Rolling returns a window over the last rows up to and including each row.
+// The window is a number of rows, or a duration such as "5m" or a
+// time.Duration when the rows are ordered by a time.Time column, in which case
+// it holds the rows within that duration before each row. A row's statistic
+// is null unless its window holds at least minPeriods non-null values;
+// minPeriods <= 0 means the window size for row windows and 1 for time windows.
+func (df *DataFrame) Rolling(window interface{}, minPeriods int, opts ...WindowOption) *Window {
+	w := newWindow(df, windowRolling, minPeriods, opts)
+	switch v := window.(type) {
+	case int:
+		if v <= 0 {
+			w.err = fmt.Errorf("window size %d is not positive", v)
+		}
+		w.size = v
+		if w.minPeriods <= 0 {
+			w.minPeriods = v
+		}
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			w.err = fmt.Errorf("invalid window %q: %v", v, err)
+		}
+		w.duration = d
+	case time.Duration:
+		w.duration = v
+	default:
+		w.err = fmt.Errorf("window must be a number of rows or a duration, not %T", window)
+	}
+	if w.size == 0 && w.err == nil {
+		if w.minPeriods <= 0 {
+			w.minPeriods = 1
+		}
+		if w.duration <= 0 {
+			w.err = fmt.Errorf("window duration %v is not positive", w.duration)
+		} else if w.cfg.orderBy == "" {
+			w.err = fmt.Errorf("a time window needs a time.Time column to order by")
+		}
+	}
+	return w
+}
+
+// Expanding returns a window over all rows up to and including each row.
+func (df *DataFrame) Expanding(minPeriods int, opts ...WindowOption) *Window {
+	return newWindow(df, windowExpanding, minPeriods, opts)
+}
+
+// EWM returns an exponentially weighted window over all rows up to and
+// including each row, with the smoothing factor set by WithAlpha or WithSpan.
+// The weight of a row i rows back is (1-alpha)^i. Only Mean is supported.
+func (df *DataFrame) EWM(opts ...WindowOption) *Window {
+	w := newWindow(df, windowEWM, 1, opts)
+	if !(w.cfg.alpha > 0 && w.cfg.alpha <= 1) {
+		w.err = fmt.Errorf("EWM needs an alpha in (0, 1] or a span of at least 1")
+	}
+	return w
+}
+
+func newWindow(df *DataFrame, kind, minPeriods int, opts []WindowOption) *Window {
+	cfg := &windowConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if minPeriods <= 0 && kind != windowRolling {
+		minPeriods = 1
+	}
+	return &Window{df: df, kind: kind, minPeriods: minPeriods, cfg: cfg}
+}
+
+// Sum returns the sum of each window for the given columns, or for every
+// numeric column if none are given.
+func (w *Window) Sum(columns ...string) (*DataFrame, error) {
+	return w.compute("sum", nil, columns)
+}
+
+// Mean returns the mean of each window.
+func (w *Window) Mean(columns ...string) (*DataFrame, error) {
+	return w.compute("mean", nil, columns)
+}
+
+// Std returns the sample standard deviation of each window.
+func (w *Window) Std(columns ...string) (*DataFrame, error) {
+	return w.compute("std", nil, columns)
+}
+
+// Min returns the smallest value of each window.
+func (w *Window) Min(columns ...string) (*DataFrame, error) {
+	return w.compute("min", nil, columns)
+}
+
+// Max returns the largest value of each window.
+func (w *Window) Max(columns ...string) (*DataFrame, error) {
+	return w.compute("max", nil, columns)
+}
+
+// Apply returns the result of fn on the non-null values of each window, in order.
+func (w *Window) Apply(fn func(values []float64) float64, columns ...string) (*DataFrame, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("Apply needs a function")
+	}
+	return w.compute("apply", fn, columns)
+}
+
+// windowRow is a row as seen by a window: its id, order key and values.
+type windowRow struct {
+	id     int
+	key    interface{}
+	values []float64
+}
+
+func (w *Window) compute(stat string, fn func([]float64) float64, columns []string) (*DataFrame, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.kind == windowEWM && stat != "mean" {
+		return nil, fmt.Errorf("%s is not supported by exponentially weighted windows", stat)
+	}
+	df := w.df
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+
+	orderBy := w.cfg.orderBy
+	var orderField Field
+	if orderBy != "" {
+		picked := pickFields(df.fields, []string{orderBy})
+		if len(picked) == 0 {
+			return nil, fmt.Errorf("column %s not found", orderBy)
+		}
+		orderField = picked[0]
+	}
+	cols, err := windowColumns(df.fields, columns, orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []windowRow
+	nonNumeric := make([]int, len(cols))
+	err = df.scanRows(func(id int, row map[string]interface{}) error {
+		r := windowRow{id: id, values: make([]float64, len(cols))}
+		if orderBy != "" {
+			r.key, _ = statValue(row[orderBy])
+		}
+		numericRow(row, cols, r.values, nonNumeric)
+		rows = append(rows, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var keep []int
+	for i, col := range cols {
+		switch {
+		case describeKind(col.Type) == describeNumeric || nonNumeric[i] == 0:
+			keep = append(keep, i)
+		case len(columns) > 0:
+			return nil, fmt.Errorf("column %s is not numeric", col.Name)
+		}
+	}
+
+	if orderBy != "" {
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i].key, rows[j].key
+			if a == nil || b == nil {
+				return b == nil && a != nil
+			}
+			return lessValue(a, b)
+		})
+	}
+	starts, err := w.starts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []Field{}
+	if orderBy != "" {
+		fields = append(fields, orderField)
+	}
+	for _, i := range keep {
+		fields = append(fields, Field{Name: cols[i].Name, Type: reflect.TypeOf(float64(0))})
+	}
+	out := make([]Row, len(rows))
+	for t, r := range rows {
+		out[t] = make(Row, len(fields))
+		if orderBy != "" {
+			out[t][orderBy] = r.key
+		}
+	}
+	values := make([]float64, len(rows))
+	for _, i := range keep {
+		for t, r := range rows {
+			values[t] = r.values[i]
+		}
+		var results []interface{}
+		if w.kind == windowEWM {
+			results = ewmMean(values, w.cfg.alpha)
+		} else {
+			results = slidingStat(stat, fn, values, starts, w.minPeriods)
+		}
+		for t := range rows {
+			out[t][cols[i].Name] = results[t]
+		}
+	}
+
+	// The frame is keyed by id, so rows go back to id order
+	ids := make([]int, len(rows))
+	order := make([]int, len(rows))
+	for t := range order {
+		order[t] = t
+	}
+	sort.Slice(order, func(a, b int) bool { return rows[order[a]].id < rows[order[b]].id })
+	byID := make([]Row, len(rows))
+	for t, o := range order {
+		ids[t] = rows[o].id
+		byID[t] = out[o]
+	}
+	names := map[int]string{windowRolling: "_rolling", windowExpanding: "_expanding", windowEWM: "_ewm"}
+	return newDataFrameFromRows(df.Name+names[w.kind], fields, ids, byID)
+}
+
+// windowColumns returns the fields of the given columns, or the numeric fields
+// other than the order column if none are given.
+func windowColumns(fields []Field, columns []string, orderBy string) ([]Field, error) {
+	if len(columns) == 0 {
+		var cols []Field
+		for _, field := range numericFields(fields) {
+			if field.Name != orderBy {
+				cols = append(cols, field)
+			}
+		}
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("no numeric columns to compute windows over")
+		}
+		return cols, nil
+	}
+	cols := pickFields(fields, columns)
+	for _, col := range columns {
+		if !hasField(cols, col) {
+			return nil, fmt.Errorf("column %s not found", col)
+		}
+		if col == orderBy {
+			return nil, fmt.Errorf("column %s is the order column", col)
+		}
+	}
+	return cols, nil
+}
+
+// starts returns the index of the first row of each row's window, over rows in
+// window order. Starts never decrease.
+func (w *Window) starts(rows []windowRow) ([]int, error) {
+	starts := make([]int, len(rows))
+	switch {
+	case w.kind != windowRolling:
+		// Expanding windows start at the first row
+	case w.size > 0:
+		for t := range starts {
+			if t >= w.size {
+				starts[t] = t - w.size + 1
+			}
+		}
+	default:
+		// Rows within the duration before each row, and none for null times
+		s := 0
+		for t, r := range rows {
+			if r.key == nil {
+				starts[t] = t + 1
+				continue
+			}
+			end, ok := r.key.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("time window needs column %s to hold time.Time values, not %T", w.cfg.orderBy, r.key)
+			}
+			for s < t && !rows[s].key.(time.Time).After(end.Add(-w.duration)) {
+				s++
+			}
+			starts[t] = s
+		}
+	}
+	return starts, nil
+}
+
+// slidingStat computes a statistic over the window of each row: the values
+// from starts[t] to t. NaN values are nulls and are left out.
+func slidingStat(stat string, fn func([]float64) float64, values []float64, starts []int, minPeriods int) []interface{} {
+	results := make([]interface{}, len(values))
+	var count int
+	var sum, mean, m2 float64
+	var deque []int // Indexes of the window's candidates for min or max, in order
+	lo := 0
+	for t, x := range values {
+		if !math.IsNaN(x) {
+			count++
+			sum += x
+			delta := x - mean
+			mean += delta / float64(count)
+			m2 += delta * (x - mean)
+			if stat == "min" || stat == "max" {
+				for len(deque) > 0 && (stat == "min" && values[deque[len(deque)-1]] >= x || stat == "max" && values[deque[len(deque)-1]] <= x) {
+					deque = deque[:len(deque)-1]
+				}
+				deque = append(deque, t)
+			}
+		}
+		for ; lo < starts[t]; lo++ {
+			x := values[lo]
+			if math.IsNaN(x) {
+				continue
+			}
+			count--
+			sum -= x
+			if count == 0 {
+				sum, mean, m2 = 0, 0, 0
+				continue
+			}
+			delta := x - mean
+			mean -= delta / float64(count)
+			m2 -= delta * (x - mean)
+		}
+		for len(deque) > 0 && deque[0] < starts[t] {
+			deque = deque[1:]
+		}
+
+		if count < minPeriods || count == 0 {
+			continue
+		}
+		switch stat {
+		case "sum":
+			results[t] = sum
+		case "mean":
+			results[t] = mean
+		case "std":
+			if count > 1 {
+				results[t] = math.Sqrt(math.Max(m2, 0) / float64(count-1))
+			}
+		case "min", "max":
+			results[t] = values[deque[0]]
+		case "apply":
+			window := make([]float64, 0, count)
+			for _, x := range values[starts[t] : t+1] {
+				if !math.IsNaN(x) {
+					window = append(window, x)
+				}
+			}
+			results[t] = fn(window)
+		}
+	}
+	return results
+}
+
+// ewmMean computes the exponentially weighted mean of the values up to each
+// row. Nulls add nothing but still age the earlier values.
+func ewmMean(values []float64, alpha float64) []interface{} {
+	results := make([]interface{}, len(values))
+	var num, den float64
+	for t, x := range values {
+		num *= 1 - alpha
+		den *= 1 - alpha
+		if !math.IsNaN(x) {
+			num += x
+			den++
+		}
+		if den > 0 {
+			results[t] = num / den
+		}
+	}
+	return results
+}
@@ -0,0 +1,127 @@
+package dataframe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// windowValues returns the values of a column of a window result in id order.
+func windowValues(t *testing.T, out *DataFrame, column string) []interface{} {
+	t.Helper()
+	var values []interface{}
+	err := out.scanRows(func(id int, row map[string]interface{}) error {
+		values = append(values, row[column])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+// sameValues compares floats to within rounding and other values exactly.
+func sameValues(got, want []interface{}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		g, gok := got[i].(float64)
+		w, wok := want[i].(float64)
+		if gok && wok {
+			if math.Abs(g-w) > 1e-9 {
+				return false
+			}
+		} else if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWindows(t *testing.T) {
+	v := func(n int) *int { return &n }
+	minute := func(m int) time.Time { return time.Date(2024, 1, 1, 0, m, 0, 0, time.UTC) }
+	fields := []Field{
+		{Name: "V", Type: reflect.TypeOf((*int)(nil))},
+		{Name: "At", Type: reflect.TypeOf((*time.Time)(nil))},
+		{Name: "Seq", Type: reflect.TypeOf(0)},
+	}
+	at := func(m int) *time.Time { tm := minute(m); return &tm }
+	// Ordered by At, the rows are 1, 2, 3, 0, 5 and then 4, whose time is null
+	df, err := newDataFrameFromRows("items", fields, []int{0, 1, 2, 3, 4, 5}, []Row{
+		{"V": v(1), "At": at(10), "Seq": 0},
+		{"V": v(3), "At": at(0), "Seq": 1},
+		{"V": nil, "At": at(4), "Seq": 2},
+		{"V": v(8), "At": at(6), "Seq": 3},
+		{"V": v(2), "At": nil, "Seq": 4},
+		{"V": v(6), "At": at(13), "Seq": 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	last := func(values []float64) float64 { return values[len(values)-1] - values[0] }
+
+	tests := []struct {
+		name   string
+		window func() (*DataFrame, error)
+		want   []interface{}
+	}{
+		{"rolling sum", func() (*DataFrame, error) { return df.Rolling(3, 2).Sum("V") }, []interface{}{nil, 4.0, 4.0, 11.0, 10.0, 16.0}},
+		{"rolling full windows", func() (*DataFrame, error) { return df.Rolling(3, 0).Sum("V") }, []interface{}{nil, nil, nil, nil, nil, 16.0}},
+		{"rolling mean", func() (*DataFrame, error) { return df.Rolling(3, 2).Mean("V") }, []interface{}{nil, 2.0, 2.0, 5.5, 5.0, 16.0 / 3}},
+		{"rolling std", func() (*DataFrame, error) { return df.Rolling(3, 2).Std("V") }, []interface{}{nil, math.Sqrt(2), math.Sqrt(2), math.Sqrt(12.5), math.Sqrt(18), math.Sqrt(84.0 / 9)}},
+		{"rolling min", func() (*DataFrame, error) { return df.Rolling(3, 2).Min("V") }, []interface{}{nil, 1.0, 1.0, 3.0, 2.0, 2.0}},
+		{"rolling max", func() (*DataFrame, error) { return df.Rolling(3, 2).Max("V") }, []interface{}{nil, 3.0, 3.0, 8.0, 8.0, 8.0}},
+		{"rolling apply", func() (*DataFrame, error) { return df.Rolling(3, 1).Apply(last, "V") }, []interface{}{0.0, 2.0, 2.0, 5.0, -6.0, -2.0}},
+		{"expanding sum", func() (*DataFrame, error) { return df.Expanding(1).Sum("V") }, []interface{}{1.0, 4.0, 4.0, 12.0, 14.0, 20.0}},
+		{"expanding min periods", func() (*DataFrame, error) { return df.Expanding(4).Max("V") }, []interface{}{nil, nil, nil, nil, 8.0, 8.0}},
+		{"ewm alpha", func() (*DataFrame, error) { return df.EWM(WithAlpha(0.5)).Mean("V") }, []interface{}{1.0, 3.5 / 1.5, 3.5 / 1.5, 8.875 / 1.375, 6.4375 / 1.6875, 5.0}},
+		{"ewm span", func() (*DataFrame, error) { return df.EWM(WithSpan(3)).Mean("V") }, []interface{}{1.0, 3.5 / 1.5, 3.5 / 1.5, 8.875 / 1.375, 6.4375 / 1.6875, 5.0}},
+		{"ordered rows", func() (*DataFrame, error) { return df.Rolling(2, 1, WithOrderBy("At")).Sum("V") }, []interface{}{9.0, 3.0, 3.0, 8.0, 8.0, 7.0}},
+		// Windows end at each row's time and hold the rows less than 5m before it
+		{"time window", func() (*DataFrame, error) { return df.Rolling("5m", 0, WithOrderBy("At")).Sum("V") }, []interface{}{9.0, 3.0, 3.0, 8.0, nil, 7.0}},
+		{"time window duration", func() (*DataFrame, error) { return df.Rolling(4*time.Minute, 0, WithOrderBy("At")).Sum("V") }, []interface{}{1.0, 3.0, nil, 8.0, nil, 7.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.window()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if got := windowValues(t, out, "V"); !sameValues(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	out, err := df.Rolling("5m", 0, WithOrderBy("At")).Sum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if got := windowValues(t, out, "At"); !reflect.DeepEqual(got, []interface{}{minute(10), minute(0), minute(4), minute(6), nil, minute(13)}) {
+		t.Errorf("order column = %v", got)
+	}
+
+	for name, window := range map[string]*Window{
+		"zero size":          df.Rolling(0, 1),
+		"bad duration":       df.Rolling("5 minutes", 1, WithOrderBy("At")),
+		"time without order": df.Rolling("5m", 1),
+		"time over numbers":  df.Rolling("5m", 1, WithOrderBy("Seq")),
+		"ewm without alpha":  df.EWM(),
+		"ewm alpha too big":  df.EWM(WithAlpha(1.5)),
+		"missing order":      df.Rolling(2, 1, WithOrderBy("When")),
+	} {
+		if out, err := window.Sum("V"); err == nil {
+			out.Close()
+			t.Errorf("%s: window succeeded", name)
+		}
+	}
+	if _, err := df.EWM(WithAlpha(0.5)).Sum("V"); err == nil {
+		t.Error("exponentially weighted sum succeeded")
+	}
+}
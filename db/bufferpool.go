@@ -0,0 +1,165 @@
+package db
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// frame is a page held in memory by the buffer pool.
+type frame struct {
+	id    PageID
+	data  []byte
+	dirty bool
+	pins  int
+	elem  *list.Element // Position in the LRU list while unpinned
+}
+
+// BufferPool caches a bounded number of pages of a Pager in memory. Pages are
+// pinned while in use, unpinned pages are evicted least recently used first,
+// and dirty pages are written back when evicted or flushed.
+type BufferPool struct {
+	pager    *Pager
+	capacity int
+	mutex    sync.Mutex
+	frames   map[PageID]*frame
+	lru      *list.List // Unpinned frames, most recently used at the front
+}
+
+// NewBufferPool creates a buffer pool holding at most capacity pages.
+func NewBufferPool(pager *Pager, capacity int) *BufferPool {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &BufferPool{
+		pager:    pager,
+		capacity: capacity,
+		frames:   make(map[PageID]*frame),
+		lru:      list.New(),
+	}
+}
+
+// Fetch pins a page and returns its contents. Changes to the returned slice
+// must be reported through Unpin.
+func (bp *BufferPool) Fetch(id PageID) ([]byte, error) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+
+	if f, ok := bp.frames[id]; ok {
+		bp.pin(f)
+		return f.data, nil
+	}
+
+	f, err := bp.newFrame(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := bp.pager.ReadPage(id, f.data); err != nil {
+		delete(bp.frames, id)
+		return nil, err
+	}
+	return f.data, nil
+}
+
+// NewPage allocates a zeroed page and pins it. Room is made in the pool first,
+// so that no page is allocated when every frame is pinned.
+func (bp *BufferPool) NewPage() (PageID, []byte, error) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+
+	if err := bp.makeRoom(); err != nil {
+		return 0, nil, err
+	}
+	id, err := bp.pager.Allocate()
+	if err != nil {
+		return 0, nil, err
+	}
+	f := bp.addFrame(id)
+	f.dirty = true
+	return id, f.data, nil
+}
+
+// Unpin releases a page pinned by Fetch or NewPage, marking it dirty if it was modified.
+func (bp *BufferPool) Unpin(id PageID, dirty bool) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+
+	f, ok := bp.frames[id]
+	if !ok || f.pins == 0 {
+		return
+	}
+	f.dirty = f.dirty || dirty
+	f.pins--
+	if f.pins == 0 {
+		f.elem = bp.lru.PushFront(f)
+	}
+}
+
+// Flush writes every dirty page back to the page file and syncs it, then
+// writes the header if the root changed.
+func (bp *BufferPool) Flush() error {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+
+	for _, f := range bp.frames {
+		if f.dirty {
+			if err := bp.pager.WritePage(f.id, f.data); err != nil {
+				return err
+			}
+			f.dirty = false
+		}
+	}
+	if err := bp.pager.Sync(); err != nil {
+		return err
+	}
+	return bp.pager.commitHeader()
+}
+
+// pin marks a frame as in use, taking it off the LRU list.
+func (bp *BufferPool) pin(f *frame) {
+	if f.pins == 0 && f.elem != nil {
+		bp.lru.Remove(f.elem)
+		f.elem = nil
+	}
+	f.pins++
+}
+
+// newFrame makes room for and registers a pinned frame for a page.
+func (bp *BufferPool) newFrame(id PageID) (*frame, error) {
+	if err := bp.makeRoom(); err != nil {
+		return nil, err
+	}
+	return bp.addFrame(id), nil
+}
+
+// makeRoom evicts a page if the pool is full.
+func (bp *BufferPool) makeRoom() error {
+	if len(bp.frames) >= bp.capacity {
+		return bp.evict()
+	}
+	return nil
+}
+
+// addFrame registers a pinned frame for a page. There must be room for it.
+func (bp *BufferPool) addFrame(id PageID) *frame {
+	f := &frame{id: id, data: make([]byte, PageSize), pins: 1}
+	bp.frames[id] = f
+	return f
+}
+
+// evict writes back and drops the least recently used unpinned page.
+func (bp *BufferPool) evict() error {
+	elem := bp.lru.Back()
+	if elem == nil {
+		return fmt.Errorf("buffer pool exhausted: all %d pages are pinned", bp.capacity)
+	}
+	f := elem.Value.(*frame)
+	if f.dirty {
+		if err := bp.pager.WritePage(f.id, f.data); err != nil {
+			return err
+		}
+	}
+	bp.lru.Remove(elem)
+	delete(bp.frames, f.id)
+	return nil
+}
@@ -3,6 +3,9 @@ package db
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
+	"math"
+	"sort"
 	"sync"
 )
 
@@ -12,12 +15,14 @@ type BPlusTreeNode struct {
 	IsLeaf   bool
 	Next     *BPlusTreeNode
 	Mutex    sync.RWMutex
+	count    int // Number of keys in the subtree, for selecting keys by rank
 }
 
 type BPlusTree struct {
 	Root  *BPlusTreeNode
 	Order int
 	Mutex sync.RWMutex
+	size  int // Number of keys
 }
 
 // NewBPlusTree creates a new B+Tree with a dynamically set order.
@@ -31,14 +36,13 @@ func NewBPlusTree(size int) *BPlusTree {
 	return &BPlusTree{Root: root, Order: order}
 }
 
+// keyPointerSize is the size of a key and a child pointer, 8 bytes each.
+const keyPointerSize = 16
+
 // calculateOrder determines the order of the B+Tree based on the size.
 func calculateOrder(size int) int {
-	// Assuming each key-pointer pair is 16 bytes and the block size is 4 KB
-	const blockSize = 4096
-	const keyPointerSize = 16
-
-	// Calculate the maximum number of key-pointer pairs that fit in a block
-	maxOrder := blockSize / keyPointerSize
+	// Calculate the maximum number of key-pointer pairs that fit in a 4 KB block
+	maxOrder := PageSize / keyPointerSize
 
 	// Ensure the order is within the range of 32 to 256
 	if maxOrder > 256 {
@@ -61,15 +65,18 @@ func (tree *BPlusTree) Insert(key int) {
 	tree.Mutex.Lock()
 	defer tree.Mutex.Unlock()
 
+	tree.size++
 	root := tree.Root
-	if len(root.Keys) == 0 {
+	if len(root.Keys) == 0 && root.IsLeaf {
 		root.Keys = append(root.Keys, key)
+		root.count++
 		return
 	}
 
 	if len(root.Keys) == tree.Order {
 		newRoot := &BPlusTreeNode{
 			Children: []*BPlusTreeNode{root},
+			count:    root.count,
 		}
 		tree.splitChild(newRoot, 0)
 		tree.Root = newRoot
@@ -81,6 +88,7 @@ func (tree *BPlusTree) insertNonFull(node *BPlusTreeNode, key int) {
 	node.Mutex.Lock()
 	defer node.Mutex.Unlock()
 
+	node.count++
 	if node.IsLeaf {
 		i := 0
 		for i < len(node.Keys) && node.Keys[i] < key {
@@ -88,16 +96,13 @@ func (tree *BPlusTree) insertNonFull(node *BPlusTreeNode, key int) {
 		}
 		node.Keys = append(node.Keys[:i], append([]int{key}, node.Keys[i:]...)...)
 	} else {
-		i := 0
-		for i < len(node.Keys) && node.Keys[i] < key {
-			i++
-		}
+		i := childIndex(node, key)
 		child := node.Children[i]
 		child.Mutex.Lock()
 		if len(child.Keys) == tree.Order {
 			child.Mutex.Unlock()
 			tree.splitChild(node, i)
-			if key > node.Keys[i] {
+			if key >= node.Keys[i] {
 				i++
 			}
 		} else {
@@ -107,34 +112,49 @@ func (tree *BPlusTree) insertNonFull(node *BPlusTreeNode, key int) {
 	}
 }
 
+// childIndex returns the child of an internal node whose subtree covers key.
+// Separator i is the smallest key of child i+1, so equal keys go right.
+func childIndex(node *BPlusTreeNode, key int) int {
+	i := 0
+	for i < len(node.Keys) && key >= node.Keys[i] {
+		i++
+	}
+	return i
+}
+
 func (tree *BPlusTree) splitChild(parent *BPlusTreeNode, index int) {
 	child := parent.Children[index]
 	mid := len(child.Keys) / 2
 	midKey := child.Keys[mid]
 
 	newChild := &BPlusTreeNode{
-		Keys:   append([]int(nil), child.Keys[mid+1:]...),
 		IsLeaf: child.IsLeaf,
 	}
 
-	if len(child.Children) > 0 {
+	if child.IsLeaf {
+		// Leaves keep every key, the separator is copied up
+		newChild.Keys = append([]int(nil), child.Keys[mid:]...)
+		newChild.Next = child.Next
+		child.Next = newChild
+	} else {
+		// Internal nodes move the separator up
+		newChild.Keys = append([]int(nil), child.Keys[mid+1:]...)
 		newChild.Children = append([]*BPlusTreeNode(nil), child.Children[mid+1:]...)
 		child.Children = child.Children[:mid+1]
 	}
 
 	child.Keys = child.Keys[:mid]
-
-	if len(parent.Keys) == 0 {
-		parent.Keys = append(parent.Keys, midKey)
+	if child.IsLeaf {
+		newChild.count = len(newChild.Keys)
 	} else {
-		parent.Keys = append(parent.Keys[:index], append([]int{midKey}, parent.Keys[index:]...)...)
+		for _, grandchild := range newChild.Children {
+			newChild.count += grandchild.count
+		}
 	}
-	parent.Children = append(parent.Children[:index+1], append([]*BPlusTreeNode{newChild}, parent.Children[index+1:]...)...)
+	child.count -= newChild.count
 
-	if child.IsLeaf {
-		newChild.Next = child.Next
-		child.Next = newChild
-	}
+	parent.Keys = append(parent.Keys[:index], append([]int{midKey}, parent.Keys[index:]...)...)
+	parent.Children = append(parent.Children[:index+1], append([]*BPlusTreeNode{newChild}, parent.Children[index+1:]...)...)
 }
 
 func (tree *BPlusTree) Search(key int) bool {
@@ -147,122 +167,222 @@ func (tree *BPlusTree) search(node *BPlusTreeNode, key int) bool {
 	node.Mutex.RLock()
 	defer node.Mutex.RUnlock()
 
-	i := 0
-	for i < len(node.Keys) && key > node.Keys[i] {
-		i++
-	}
-	if i < len(node.Keys) && key == node.Keys[i] {
-		return true
+	if !node.IsLeaf {
+		return tree.search(node.Children[childIndex(node, key)], key)
 	}
-	if node.IsLeaf {
-		return false
+	i := sort.SearchInts(node.Keys, key)
+	return i < len(node.Keys) && node.Keys[i] == key
+}
+
+// findLeaf returns the leaf whose key range covers key.
+func (tree *BPlusTree) findLeaf(key int) *BPlusTreeNode {
+	node := tree.Root
+	for !node.IsLeaf {
+		node.Mutex.RLock()
+		next := node.Children[childIndex(node, key)]
+		node.Mutex.RUnlock()
+		node = next
 	}
-	return tree.search(node.Children[i], key)
+	return node
 }
 
-func (tree *BPlusTree) Delete(key int) {
-	tree.Mutex.Lock()
-	defer tree.Mutex.Unlock()
-	tree.delete(tree.Root, key)
+// Empty reports whether the root is a leaf without keys. A tree emptied by
+// Delete keeps its internal nodes and is not reported as empty.
+func (tree *BPlusTree) Empty() bool {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+	return tree.Root.IsLeaf && len(tree.Root.Keys) == 0
 }
 
-func (tree *BPlusTree) delete(node *BPlusTreeNode, key int) {
-	node.Mutex.Lock()
-	defer node.Mutex.Unlock()
+// Keys returns all keys stored in the tree in ascending order.
+func (tree *BPlusTree) Keys() []int {
+	return tree.Range(math.MinInt, math.MaxInt)
+}
 
-	i := 0
-	for i < len(node.Keys) && key > node.Keys[i] {
-		i++
-	}
+// Range returns the keys between min and max inclusive in ascending order,
+// walking the linked leaves from the leaf that covers min.
+func (tree *BPlusTree) Range(min, max int) []int {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
 
-	if node.IsLeaf {
-		if i < len(node.Keys) && node.Keys[i] == key {
-			node.Keys = append(node.Keys[:i], node.Keys[i+1:]...)
-		}
-	} else {
-		if i < len(node.Keys) && node.Keys[i] == key {
-			if len(node.Children[i].Keys) > tree.Order/2 {
-				node.Keys[i] = tree.getPredecessor(node.Children[i])
-				tree.delete(node.Children[i], node.Keys[i])
-			} else if len(node.Children[i+1].Keys) > tree.Order/2 {
-				node.Keys[i] = tree.getSuccessor(node.Children[i+1])
-				tree.delete(node.Children[i+1], node.Keys[i])
-			} else {
-				tree.mergeChildren(node, i)
-				tree.delete(node.Children[i], key)
-			}
-		} else {
-			if len(node.Children[i].Keys) == tree.Order/2 {
-				tree.fixChild(node, i)
+	keys := make([]int, 0)
+	for leaf := tree.findLeaf(min); leaf != nil; leaf = leaf.Next {
+		leaf.Mutex.RLock()
+		for _, key := range leaf.Keys[sort.SearchInts(leaf.Keys, min):] {
+			if key > max {
+				leaf.Mutex.RUnlock()
+				return keys
 			}
-			tree.delete(node.Children[i], key)
+			keys = append(keys, key)
 		}
+		leaf.Mutex.RUnlock()
 	}
+	return keys
 }
 
-func (tree *BPlusTree) getPredecessor(node *BPlusTreeNode) int {
-	for !node.IsLeaf {
-		node = node.Children[len(node.Children)-1]
+// Ceiling returns the smallest key at or above key, reporting false if there
+// is none. Leaves emptied by Delete are skipped.
+func (tree *BPlusTree) Ceiling(key int) (int, bool) {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+
+	for leaf := tree.findLeaf(key); leaf != nil; leaf = leaf.Next {
+		leaf.Mutex.RLock()
+		i := sort.SearchInts(leaf.Keys, key)
+		if i < len(leaf.Keys) {
+			found := leaf.Keys[i]
+			leaf.Mutex.RUnlock()
+			return found, true
+		}
+		leaf.Mutex.RUnlock()
 	}
-	return node.Keys[len(node.Keys)-1]
+	return 0, false
 }
 
-func (tree *BPlusTree) getSuccessor(node *BPlusTreeNode) int {
-	for !node.IsLeaf {
-		node = node.Children[0]
+// Delete removes key from its leaf. Separators in internal nodes are left in
+// place since they still route searches correctly, and underfull leaves are
+// not merged; a tree that shrinks a lot should be rebuilt with BulkLoad.
+func (tree *BPlusTree) Delete(key int) {
+	tree.Mutex.Lock()
+	defer tree.Mutex.Unlock()
+
+	path := []*BPlusTreeNode{tree.Root} // Nodes whose subtree holds key
+	for node := tree.Root; !node.IsLeaf; {
+		node = node.Children[childIndex(node, key)]
+		path = append(path, node)
+	}
+	leaf := path[len(path)-1]
+	leaf.Mutex.Lock()
+	defer leaf.Mutex.Unlock()
+
+	i := sort.SearchInts(leaf.Keys, key)
+	if i < len(leaf.Keys) && leaf.Keys[i] == key {
+		leaf.Keys = append(leaf.Keys[:i], leaf.Keys[i+1:]...)
+		tree.size--
+		for _, node := range path {
+			node.count--
+		}
 	}
-	return node.Keys[0]
 }
 
-func (tree *BPlusTree) fixChild(parent *BPlusTreeNode, index int) {
-	child := parent.Children[index]
-	if index > 0 && len(parent.Children[index-1].Keys) > tree.Order/2 {
-		leftSibling := parent.Children[index-1]
-		child.Keys = append([]int{parent.Keys[index-1]}, child.Keys...)
-		parent.Keys[index-1] = leftSibling.Keys[len(leftSibling.Keys)-1]
-		leftSibling.Keys = leftSibling.Keys[:len(leftSibling.Keys)-1]
-		if len(leftSibling.Children) > 0 {
-			child.Children = append([]*BPlusTreeNode{leftSibling.Children[len(leftSibling.Children)-1]}, child.Children...)
-			leftSibling.Children = leftSibling.Children[:len(leftSibling.Children)-1]
-		}
-	} else if index < len(parent.Children)-1 && len(parent.Children[index+1].Keys) > tree.Order/2 {
-		rightSibling := parent.Children[index+1]
-		child.Keys = append(child.Keys, parent.Keys[index])
-		parent.Keys[index] = rightSibling.Keys[0]
-		rightSibling.Keys = rightSibling.Keys[1:]
-		if len(rightSibling.Children) > 0 {
-			child.Children = append(child.Children, rightSibling.Children[0])
-			rightSibling.Children = rightSibling.Children[1:]
-		}
-	} else {
-		if index > 0 {
-			tree.mergeChildren(parent, index-1)
-		} else {
-			tree.mergeChildren(parent, index)
+// Len returns the number of keys in the tree.
+func (tree *BPlusTree) Len() int {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+	return tree.size
+}
+
+// Select returns the keys at the given ranks, a key's rank being its position
+// in ascending order from 0. Ranks must be sorted and below Len, and may repeat.
+// Each rank is found by descending from the root, skipping the subtrees before
+// it by their key counts, in O(log N) nodes.
+func (tree *BPlusTree) Select(ranks []int) []int {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+
+	keys := make([]int, 0, len(ranks))
+	for _, rank := range ranks {
+		node := tree.Root
+		for !node.IsLeaf {
+			node.Mutex.RLock()
+			i := 0
+			for i < len(node.Children)-1 && rank >= node.Children[i].count {
+				rank -= node.Children[i].count
+				i++
+			}
+			next := node.Children[i]
+			node.Mutex.RUnlock()
+			node = next
 		}
+		node.Mutex.RLock()
+		keys = append(keys, node.Keys[rank])
+		node.Mutex.RUnlock()
 	}
+	return keys
 }
 
-func (tree *BPlusTree) mergeChildren(parent *BPlusTreeNode, index int) {
-	leftChild := parent.Children[index]
-	rightChild := parent.Children[index+1]
+// BulkLoad builds a tree bottom-up from keys sorted in ascending order. Leaves
+// are filled to fillFactor of the tree order and linked left to right, then
+// each level of internal nodes is built over the one below. This is O(N),
+// against O(N log N) and repeated splits when inserting the keys one by one.
+func BulkLoad(sortedKeys []int, fillFactor float64) (*BPlusTree, error) {
+	if fillFactor <= 0 || fillFactor > 1 {
+		return nil, fmt.Errorf("fill factor must be in (0, 1], got %v", fillFactor)
+	}
+	for i := 1; i < len(sortedKeys); i++ {
+		if sortedKeys[i] <= sortedKeys[i-1] {
+			return nil, fmt.Errorf("keys must be sorted and unique, got %d after %d", sortedKeys[i], sortedKeys[i-1])
+		}
+	}
 
-	leftChild.Keys = append(leftChild.Keys, parent.Keys[index])
-	leftChild.Keys = append(leftChild.Keys, rightChild.Keys...)
-	leftChild.Children = append(leftChild.Children, rightChild.Children...)
+	tree := NewBPlusTree(len(sortedKeys))
+	tree.size = len(sortedKeys)
+	if len(sortedKeys) == 0 {
+		return tree, nil
+	}
 
-	parent.Keys = append(parent.Keys[:index], parent.Keys[index+1:]...)
-	parent.Children = append(parent.Children[:index+1], parent.Children[index+2:]...)
+	perNode := int(float64(tree.Order) * fillFactor)
+	if perNode < 2 {
+		perNode = 2
+	}
 
-	if leftChild.IsLeaf {
-		leftChild.Next = rightChild.Next
+	// Build the leaf level
+	var level []*BPlusTreeNode
+	var minKeys []int
+	for _, size := range spread(len(sortedKeys), perNode) {
+		leaf := &BPlusTreeNode{
+			Keys:   append(make([]int, 0, tree.Order), sortedKeys[:size]...),
+			IsLeaf: true,
+			count:  size,
+		}
+		if len(level) > 0 {
+			level[len(level)-1].Next = leaf
+		}
+		level = append(level, leaf)
+		minKeys = append(minKeys, sortedKeys[0])
+		sortedKeys = sortedKeys[size:]
 	}
 
-	if parent == tree.Root && len(parent.Keys) == 0 {
-		tree.Root = leftChild
+	// Build internal levels until a single root remains
+	for len(level) > 1 {
+		var parents []*BPlusTreeNode
+		var parentMins []int
+		for _, size := range spread(len(level), perNode+1) {
+			parent := &BPlusTreeNode{
+				Keys:     append(make([]int, 0, tree.Order), minKeys[1:size]...),
+				Children: append(make([]*BPlusTreeNode, 0, tree.Order+1), level[:size]...),
+			}
+			for _, child := range parent.Children {
+				parent.count += child.count
+			}
+			parents = append(parents, parent)
+			parentMins = append(parentMins, minKeys[0])
+			level, minKeys = level[size:], minKeys[size:]
+		}
+		level, minKeys = parents, parentMins
+	}
+
+	tree.Root = level[0]
+	return tree, nil
+}
+
+// spread splits n items into the fewest groups of at most max items, with
+// group sizes as even as possible so that no group is left nearly empty.
+func spread(n, max int) []int {
+	groups := (n + max - 1) / max
+	sizes := make([]int, groups)
+	for i := range sizes {
+		sizes[i] = n / groups
+		if i < n%groups {
+			sizes[i]++
+		}
 	}
+	return sizes
 }
 
+// MarshalBinary encodes a node and its subtree. Next pointers are not encoded,
+// since following them would encode every leaf to the right again; they are
+// restored by BPlusTree.UnmarshalBinary.
 func (node *BPlusTreeNode) MarshalBinary() ([]byte, error) {
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
@@ -275,18 +395,6 @@ func (node *BPlusTreeNode) MarshalBinary() ([]byte, error) {
 	if err := enc.Encode(node.IsLeaf); err != nil {
 		return nil, err
 	}
-	if node.Next != nil {
-		if err := enc.Encode(true); err != nil {
-			return nil, err
-		}
-		if err := enc.Encode(node.Next); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := enc.Encode(false); err != nil {
-			return nil, err
-		}
-	}
 	return buf.Bytes(), nil
 }
 
@@ -302,18 +410,7 @@ func (node *BPlusTreeNode) UnmarshalBinary(data []byte) error {
 	if err := dec.Decode(&node.IsLeaf); err != nil {
 		return err
 	}
-	var hasNext bool
-	if err := dec.Decode(&hasNext); err != nil {
-		return err
-	}
-	if hasNext {
-		node.Next = &BPlusTreeNode{}
-		if err := dec.Decode(node.Next); err != nil {
-			return err
-		}
-	} else {
-		node.Next = nil
-	}
+	node.Next = nil
 	return nil
 }
 
@@ -323,6 +420,9 @@ func (tree *BPlusTree) MarshalBinary() ([]byte, error) {
 	if err := enc.Encode(tree.Root); err != nil {
 		return nil, err
 	}
+	if err := enc.Encode(tree.Order); err != nil {
+		return nil, err
+	}
 	return buf.Bytes(), nil
 }
 
@@ -332,9 +432,36 @@ func (tree *BPlusTree) UnmarshalBinary(data []byte) error {
 	if err := dec.Decode(&tree.Root); err != nil {
 		return err
 	}
+	if err := dec.Decode(&tree.Order); err != nil {
+		return err
+	}
+	tree.size = linkLeaves(tree.Root)
 	return nil
 }
 
+// linkLeaves restores the Next pointers between the leaves under root and the
+// key counts of the nodes, and returns the number of keys under root.
+func linkLeaves(root *BPlusTreeNode) int {
+	var prev *BPlusTreeNode
+	var walk func(node *BPlusTreeNode) int
+	walk = func(node *BPlusTreeNode) int {
+		if node.IsLeaf {
+			if prev != nil {
+				prev.Next = node
+			}
+			prev = node
+			node.count = len(node.Keys)
+			return node.count
+		}
+		node.count = 0
+		for _, child := range node.Children {
+			node.count += walk(child)
+		}
+		return node.count
+	}
+	return walk(root)
+}
+
 func init() {
 	gob.Register(&BPlusTree{})
 	gob.Register(&BPlusTreeNode{})
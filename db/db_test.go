@@ -0,0 +1,281 @@
+package db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// keyTree is the interface shared by BPlusTree and PagedBPlusTree in tests.
+type keyTree interface {
+	Insert(key int)
+	Delete(key int)
+	Search(key int) bool
+	Range(min, max int) []int
+	Len() int
+	Select(ranks []int) []int
+	Ceiling(key int) (int, bool)
+}
+
+// pagedKeyTree adapts a PagedBPlusTree to keyTree, failing the test on errors.
+type pagedKeyTree struct {
+	t    *testing.T
+	tree *PagedBPlusTree
+}
+
+func (p pagedKeyTree) Insert(key int) {
+	if err := p.tree.Insert(key); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+func (p pagedKeyTree) Delete(key int) {
+	if err := p.tree.Delete(key); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+func (p pagedKeyTree) Search(key int) bool {
+	found, err := p.tree.Search(key)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return found
+}
+
+func (p pagedKeyTree) Range(min, max int) []int {
+	keys, err := p.tree.Range(min, max)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return keys
+}
+
+func (p pagedKeyTree) Len() int { return p.tree.Len() }
+
+func (p pagedKeyTree) Select(ranks []int) []int {
+	keys, err := p.tree.Select(ranks)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return keys
+}
+
+func (p pagedKeyTree) Ceiling(key int) (int, bool) {
+	next, ok, err := p.tree.Ceiling(key)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return next, ok
+}
+
+func openPagedKeyTree(t *testing.T, path string) pagedKeyTree {
+	tree, err := OpenPagedBPlusTree(path, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tree.Close() })
+	return pagedKeyTree{t, tree}
+}
+
+// sortedKeys returns the keys of a reference set in ascending order.
+func sortedKeys(ref map[int]bool) []int {
+	keys := make([]int, 0, len(ref))
+	for key := range ref {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// checkTree compares a tree with a reference set of keys.
+func checkTree(t *testing.T, tree keyTree, ref map[int]bool, maxKey int) {
+	t.Helper()
+	keys := sortedKeys(ref)
+	if got := tree.Range(-1, maxKey); !reflect.DeepEqual(got, keys) {
+		t.Fatalf("tree holds %d keys, want %d", len(got), len(keys))
+	}
+	if tree.Len() != len(keys) {
+		t.Fatalf("Len = %d, want %d", tree.Len(), len(keys))
+	}
+	ranks := make([]int, len(keys))
+	for i := range ranks {
+		ranks[i] = i
+	}
+	if got := tree.Select(ranks); len(keys) > 0 && !reflect.DeepEqual(got, keys) {
+		t.Fatalf("Select of every rank = %v, want %v", got, keys)
+	}
+	for key := -1; key <= maxKey; key++ {
+		if tree.Search(key) != ref[key] {
+			t.Fatalf("Search(%d) = %v, want %v", key, !ref[key], ref[key])
+		}
+		i := sort.SearchInts(keys, key)
+		next, ok := tree.Ceiling(key)
+		if ok != (i < len(keys)) || ok && next != keys[i] {
+			t.Fatalf("Ceiling(%d) = %d, %v", key, next, ok)
+		}
+	}
+	for _, r := range [][2]int{{10, 20}, {maxKey / 2, maxKey / 2}, {maxKey, -1}} {
+		var want []int
+		for _, key := range keys {
+			if key >= r[0] && key <= r[1] {
+				want = append(want, key)
+			}
+		}
+		got := tree.Range(r[0], r[1])
+		if len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+			t.Fatalf("Range(%d, %d) = %v, want %v", r[0], r[1], got, want)
+		}
+	}
+}
+
+func TestBPlusTreeInsertDelete(t *testing.T) {
+	const maxKey = 5000
+	bulk := func(t *testing.T, ref map[int]bool) keyTree {
+		for key := 0; key < maxKey; key += 3 {
+			ref[key] = true
+		}
+		tree, err := BulkLoad(sortedKeys(ref), 0.7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree
+	}
+
+	tests := []struct {
+		name    string
+		newTree func(t *testing.T, ref map[int]bool) keyTree
+		ops     int
+		deletes int // Percentage of operations that delete
+	}{
+		{"small order", func(*testing.T, map[int]bool) keyTree { return NewBPlusTree(0) }, 20000, 30},
+		{"large order", func(*testing.T, map[int]bool) keyTree { return NewBPlusTree(100000) }, 20000, 30},
+		{"delete heavy", func(*testing.T, map[int]bool) keyTree { return NewBPlusTree(0) }, 20000, 70},
+		{"bulk loaded", bulk, 10000, 50},
+		{"paged", func(t *testing.T, _ map[int]bool) keyTree {
+			return openPagedKeyTree(t, filepath.Join(t.TempDir(), "tree.pages"))
+		}, 20000, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			ref := make(map[int]bool)
+			tree := tt.newTree(t, ref)
+			for i := 0; i < tt.ops; i++ {
+				key := rng.Intn(maxKey)
+				if rng.Intn(100) < tt.deletes {
+					tree.Delete(key)
+					delete(ref, key)
+				} else if !ref[key] {
+					tree.Insert(key)
+					ref[key] = true
+				}
+				if i%5000 == 0 {
+					checkTree(t, tree, ref, maxKey)
+				}
+			}
+			checkTree(t, tree, ref, maxKey)
+
+			for key := range ref {
+				tree.Delete(key)
+			}
+			checkTree(t, tree, map[int]bool{}, maxKey)
+		})
+	}
+}
+
+func TestBPlusTreeGobRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	tree := NewBPlusTree(0)
+	ref := make(map[int]bool)
+	for i := 0; i < 3000; i++ {
+		key := rng.Intn(10000)
+		if !ref[key] {
+			tree.Insert(key)
+			ref[key] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(tree); err != nil {
+		t.Fatal(err)
+	}
+	var decoded *BPlusTree
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	checkTree(t, decoded, ref, 10000)
+}
+
+func TestPagedBPlusTreeReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.pages")
+	rng := rand.New(rand.NewSource(3))
+	ref := make(map[int]bool)
+
+	tree, err := OpenPagedBPlusTree(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10000; i++ {
+		key := rng.Intn(8000)
+		if rng.Intn(4) == 0 {
+			err = tree.Delete(key)
+			delete(ref, key)
+		} else {
+			err = tree.Insert(key)
+			ref[key] = true
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tree.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTree(t, openPagedKeyTree(t, path), ref, 8000)
+}
+
+func TestSelect(t *testing.T) {
+	keys := make([]int, 2000)
+	for i := range keys {
+		keys[i] = i * 7
+	}
+	tree, err := BulkLoad(keys, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paged := openPagedKeyTree(t, filepath.Join(t.TempDir(), "tree.pages"))
+	for _, key := range keys {
+		paged.Insert(key)
+	}
+
+	tests := []struct {
+		name  string
+		ranks []int
+	}{
+		{"none", []int{}},
+		{"first and last", []int{0, 1999}},
+		{"repeated", []int{5, 5, 5, 600}},
+		{"spread", []int{1, 64, 65, 300, 1024, 1500, 1998}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make([]int, len(tt.ranks))
+			for i, rank := range tt.ranks {
+				want[i] = keys[rank]
+			}
+			if got := tree.Select(tt.ranks); !reflect.DeepEqual(got, want) {
+				t.Errorf("Select = %v, want %v", got, want)
+			}
+			got, err := paged.tree.Select(tt.ranks)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("paged Select = %v, %v, want %v", got, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	pageHeaderSize = 16 // Leaf flag, key count and next leaf page
+
+	// Leaves hold only keys, internal nodes hold keys and one more child page than keys
+	maxLeafKeys     = (PageSize - pageHeaderSize) / 8
+	maxInternalKeys = (PageSize - pageHeaderSize - 8) / keyPointerSize
+)
+
+// PagedBPlusTree is a B+Tree whose nodes live in 4KB pages of a page file and
+// reference each other by page id. Nodes are read through a bounded buffer
+// pool, so the tree can grow larger than the memory it is given.
+type PagedBPlusTree struct {
+	pager *Pager
+	pool  *BufferPool
+	size  int // Number of keys, counted from the leaves when the tree is opened
+	Mutex sync.RWMutex
+}
+
+// pagedNode is the decoded form of a node page.
+type pagedNode struct {
+	id       PageID
+	isLeaf   bool
+	keys     []int
+	children []PageID
+	next     PageID // Next leaf, 0 for the last one
+}
+
+// OpenPagedBPlusTree opens the tree stored in the page file at path, creating
+// it if needed. At most poolPages pages are cached in memory.
+func OpenPagedBPlusTree(path string, poolPages int) (*PagedBPlusTree, error) {
+	pager, err := OpenPager(path)
+	if err != nil {
+		return nil, err
+	}
+	tree := &PagedBPlusTree{pager: pager, pool: NewBufferPool(pager, poolPages)}
+
+	if pager.Root() == 0 {
+		root, err := tree.newNode(true)
+		if err != nil {
+			pager.Close()
+			return nil, err
+		}
+		pager.SetRoot(root.id)
+		return tree, nil
+	}
+
+	leaf, err := tree.findLeaf(math.MinInt)
+	for err == nil {
+		tree.size += len(leaf.keys)
+		if leaf.next == 0 {
+			return tree, nil
+		}
+		leaf, err = tree.readNode(leaf.next)
+	}
+	pager.Close()
+	return nil, err
+}
+
+func (n *pagedNode) full() bool {
+	if n.isLeaf {
+		return len(n.keys) >= maxLeafKeys
+	}
+	return len(n.keys) >= maxInternalKeys
+}
+
+func (n *pagedNode) encode(buf []byte) {
+	for i := range buf {
+		buf[i] = 0
+	}
+	if n.isLeaf {
+		buf[0] = 1
+	}
+	binary.LittleEndian.PutUint16(buf[2:], uint16(len(n.keys)))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(n.next))
+	offset := pageHeaderSize
+	for _, key := range n.keys {
+		binary.LittleEndian.PutUint64(buf[offset:], uint64(key))
+		offset += 8
+	}
+	for _, child := range n.children {
+		binary.LittleEndian.PutUint64(buf[offset:], uint64(child))
+		offset += 8
+	}
+}
+
+func decodeNode(id PageID, buf []byte) *pagedNode {
+	n := &pagedNode{
+		id:     id,
+		isLeaf: buf[0] == 1,
+		next:   PageID(binary.LittleEndian.Uint64(buf[8:])),
+	}
+	numKeys := int(binary.LittleEndian.Uint16(buf[2:]))
+	offset := pageHeaderSize
+	n.keys = make([]int, numKeys)
+	for i := range n.keys {
+		n.keys[i] = int(binary.LittleEndian.Uint64(buf[offset:]))
+		offset += 8
+	}
+	if !n.isLeaf {
+		n.children = make([]PageID, numKeys+1)
+		for i := range n.children {
+			n.children[i] = PageID(binary.LittleEndian.Uint64(buf[offset:]))
+			offset += 8
+		}
+	}
+	return n
+}
+
+func (tree *PagedBPlusTree) readNode(id PageID) (*pagedNode, error) {
+	buf, err := tree.pool.Fetch(id)
+	if err != nil {
+		return nil, fmt.Errorf("error reading page %d: %v", id, err)
+	}
+	n := decodeNode(id, buf)
+	tree.pool.Unpin(id, false)
+	return n, nil
+}
+
+func (tree *PagedBPlusTree) writeNode(n *pagedNode) error {
+	buf, err := tree.pool.Fetch(n.id)
+	if err != nil {
+		return fmt.Errorf("error writing page %d: %v", n.id, err)
+	}
+	n.encode(buf)
+	tree.pool.Unpin(n.id, true)
+	return nil
+}
+
+func (tree *PagedBPlusTree) newNode(isLeaf bool) (*pagedNode, error) {
+	id, buf, err := tree.pool.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	n := &pagedNode{id: id, isLeaf: isLeaf}
+	n.encode(buf)
+	tree.pool.Unpin(id, true)
+	return n, nil
+}
+
+// pagedChildIndex returns the child of an internal node whose subtree covers key.
+func pagedChildIndex(n *pagedNode, key int) int {
+	return sort.Search(len(n.keys), func(i int) bool { return n.keys[i] > key })
+}
+
+// Insert adds key to the tree. Keys already present are ignored.
+func (tree *PagedBPlusTree) Insert(key int) error {
+	tree.Mutex.Lock()
+	defer tree.Mutex.Unlock()
+
+	root, err := tree.readNode(tree.pager.Root())
+	if err != nil {
+		return err
+	}
+	if root.full() {
+		newRoot, err := tree.newNode(false)
+		if err != nil {
+			return err
+		}
+		newRoot.children = []PageID{root.id}
+		if err := tree.splitChild(newRoot, 0, root); err != nil {
+			return err
+		}
+		tree.pager.SetRoot(newRoot.id)
+		root = newRoot
+	}
+	inserted, err := tree.insertNonFull(root, key)
+	if inserted {
+		tree.size++
+	}
+	return err
+}
+
+// insertNonFull adds key under n, reporting whether it was not already there.
+func (tree *PagedBPlusTree) insertNonFull(n *pagedNode, key int) (bool, error) {
+	for !n.isLeaf {
+		i := pagedChildIndex(n, key)
+		child, err := tree.readNode(n.children[i])
+		if err != nil {
+			return false, err
+		}
+		if child.full() {
+			if err := tree.splitChild(n, i, child); err != nil {
+				return false, err
+			}
+			if key >= n.keys[i] {
+				if child, err = tree.readNode(n.children[i+1]); err != nil {
+					return false, err
+				}
+			}
+		}
+		n = child
+	}
+
+	i := sort.SearchInts(n.keys, key)
+	if i < len(n.keys) && n.keys[i] == key {
+		return false, nil
+	}
+	n.keys = append(n.keys[:i], append([]int{key}, n.keys[i:]...)...)
+	return true, tree.writeNode(n)
+}
+
+// splitChild splits the full child at index of parent into two pages.
+func (tree *PagedBPlusTree) splitChild(parent *pagedNode, index int, child *pagedNode) error {
+	right, err := tree.newNode(child.isLeaf)
+	if err != nil {
+		return err
+	}
+	mid := len(child.keys) / 2
+	separator := child.keys[mid]
+
+	if child.isLeaf {
+		// Leaves keep every key, the separator is copied up
+		right.keys = append([]int(nil), child.keys[mid:]...)
+		right.next = child.next
+		child.next = right.id
+	} else {
+		// Internal nodes move the separator up
+		right.keys = append([]int(nil), child.keys[mid+1:]...)
+		right.children = append([]PageID(nil), child.children[mid+1:]...)
+		child.children = append([]PageID(nil), child.children[:mid+1]...)
+	}
+	child.keys = append([]int(nil), child.keys[:mid]...)
+
+	parent.keys = append(parent.keys[:index], append([]int{separator}, parent.keys[index:]...)...)
+	parent.children = append(parent.children[:index+1], append([]PageID{right.id}, parent.children[index+1:]...)...)
+
+	for _, n := range []*pagedNode{child, right, parent} {
+		if err := tree.writeNode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findLeaf returns the leaf whose key range covers key.
+func (tree *PagedBPlusTree) findLeaf(key int) (*pagedNode, error) {
+	n, err := tree.readNode(tree.pager.Root())
+	for err == nil && !n.isLeaf {
+		n, err = tree.readNode(n.children[pagedChildIndex(n, key)])
+	}
+	return n, err
+}
+
+// Search reports whether key is in the tree.
+func (tree *PagedBPlusTree) Search(key int) (bool, error) {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+
+	leaf, err := tree.findLeaf(key)
+	if err != nil {
+		return false, err
+	}
+	i := sort.SearchInts(leaf.keys, key)
+	return i < len(leaf.keys) && leaf.keys[i] == key, nil
+}
+
+// Range returns the keys between min and max inclusive in ascending order.
+func (tree *PagedBPlusTree) Range(min, max int) ([]int, error) {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+
+	keys := make([]int, 0)
+	leaf, err := tree.findLeaf(min)
+	for err == nil {
+		for _, key := range leaf.keys[sort.SearchInts(leaf.keys, min):] {
+			if key > max {
+				return keys, nil
+			}
+			keys = append(keys, key)
+		}
+		if leaf.next == 0 {
+			return keys, nil
+		}
+		leaf, err = tree.readNode(leaf.next)
+	}
+	return nil, err
+}
+
+// Ceiling returns the smallest key at or above key, reporting false if there
+// is none.
+func (tree *PagedBPlusTree) Ceiling(key int) (int, bool, error) {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+
+	leaf, err := tree.findLeaf(key)
+	for err == nil {
+		if i := sort.SearchInts(leaf.keys, key); i < len(leaf.keys) {
+			return leaf.keys[i], true, nil
+		}
+		if leaf.next == 0 {
+			return 0, false, nil
+		}
+		leaf, err = tree.readNode(leaf.next)
+	}
+	return 0, false, err
+}
+
+// Keys returns all keys in ascending order.
+func (tree *PagedBPlusTree) Keys() ([]int, error) {
+	return tree.Range(math.MinInt, math.MaxInt)
+}
+
+// Delete removes key from its leaf. As with BPlusTree, separators stay in
+// place and underfull leaves are not merged.
+func (tree *PagedBPlusTree) Delete(key int) error {
+	tree.Mutex.Lock()
+	defer tree.Mutex.Unlock()
+
+	leaf, err := tree.findLeaf(key)
+	if err != nil {
+		return err
+	}
+	i := sort.SearchInts(leaf.keys, key)
+	if i == len(leaf.keys) || leaf.keys[i] != key {
+		return nil
+	}
+	leaf.keys = append(leaf.keys[:i], leaf.keys[i+1:]...)
+	tree.size--
+	return tree.writeNode(leaf)
+}
+
+// Len returns the number of keys in the tree.
+func (tree *PagedBPlusTree) Len() int {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+	return tree.size
+}
+
+// Select returns the keys at the given ranks, sorted and below Len, as
+// BPlusTree.Select does. Pages do not record the key counts of their subtrees,
+// so every leaf page up to the one holding the highest rank is read, in
+// O(N / maxLeafKeys) page reads; their keys are not copied.
+func (tree *PagedBPlusTree) Select(ranks []int) ([]int, error) {
+	tree.Mutex.RLock()
+	defer tree.Mutex.RUnlock()
+
+	keys := make([]int, 0, len(ranks))
+	first := 0 // Rank of the first key of the leaf
+	leaf, err := tree.findLeaf(math.MinInt)
+	for err == nil {
+		for len(keys) < len(ranks) && ranks[len(keys)] < first+len(leaf.keys) {
+			keys = append(keys, leaf.keys[ranks[len(keys)]-first])
+		}
+		first += len(leaf.keys)
+		if len(keys) == len(ranks) || leaf.next == 0 {
+			return keys, nil
+		}
+		leaf, err = tree.readNode(leaf.next)
+	}
+	return nil, err
+}
+
+// Flush writes all dirty pages back to the page file.
+func (tree *PagedBPlusTree) Flush() error {
+	tree.Mutex.Lock()
+	defer tree.Mutex.Unlock()
+	return tree.pool.Flush()
+}
+
+// Close flushes the tree and closes its page file.
+func (tree *PagedBPlusTree) Close() error {
+	if err := tree.Flush(); err != nil {
+		return err
+	}
+	return tree.pager.Close()
+}
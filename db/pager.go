@@ -0,0 +1,161 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// PageSize is the size of a page on disk. It matches the block size that
+// calculateOrder sizes in-memory nodes for.
+const PageSize = 4096
+
+// PageID identifies a page by its position in the page file. Page 0 holds the
+// file header, so 0 also serves as the nil page.
+type PageID uint64
+
+const (
+	pageMagic   = "BJPG"
+	pageVersion = 1
+)
+
+// Pager reads and writes fixed-size pages of a page file.
+type Pager struct {
+	file     *os.File
+	mutex    sync.Mutex
+	numPages PageID
+	root     PageID // Root page, recorded in the header by commitHeader
+	dirty    bool   // The root changed since the header was written
+}
+
+// OpenPager opens the page file at path, creating it with an empty header if needed.
+func OpenPager(path string) (*Pager, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size()%PageSize != 0 {
+		file.Close()
+		return nil, fmt.Errorf("page file %s has a partial page", path)
+	}
+
+	p := &Pager{file: file, numPages: PageID(info.Size() / PageSize)}
+	if p.numPages == 0 {
+		p.numPages = 1
+		if err := p.writeHeader(); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return p, nil
+	}
+
+	header := make([]byte, PageSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if string(header[:4]) != pageMagic {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a page file", path)
+	}
+	if version := binary.LittleEndian.Uint16(header[4:]); version != pageVersion {
+		file.Close()
+		return nil, fmt.Errorf("unsupported page file version %d", version)
+	}
+	p.root = PageID(binary.LittleEndian.Uint64(header[8:]))
+	return p, nil
+}
+
+func (p *Pager) writeHeader() error {
+	header := make([]byte, PageSize)
+	copy(header, pageMagic)
+	binary.LittleEndian.PutUint16(header[4:], pageVersion)
+	binary.LittleEndian.PutUint64(header[8:], uint64(p.root))
+	_, err := p.file.WriteAt(header, 0)
+	return err
+}
+
+// Root returns the root page recorded in the header.
+func (p *Pager) Root() PageID {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.root
+}
+
+// SetRoot sets the root page. The header is only written by the next
+// BufferPool.Flush, once the pages the new root refers to are on disk, so that
+// a crash never leaves it pointing at a page that was not written.
+func (p *Pager) SetRoot(id PageID) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.root = id
+	p.dirty = true
+}
+
+// commitHeader writes the header if the root changed and syncs it. Pages must
+// have been written and synced first.
+func (p *Pager) commitHeader() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	p.dirty = false
+	return p.file.Sync()
+}
+
+// Allocate reserves a new page at the end of the file.
+func (p *Pager) Allocate() (PageID, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	id := p.numPages
+	if _, err := p.file.WriteAt(make([]byte, PageSize), int64(id)*PageSize); err != nil {
+		return 0, err
+	}
+	p.numPages++
+	return id, nil
+}
+
+// ReadPage reads a page into buf, which must be PageSize long.
+func (p *Pager) ReadPage(id PageID, buf []byte) error {
+	if id == 0 || id >= p.NumPages() {
+		return fmt.Errorf("page %d out of range", id)
+	}
+	_, err := p.file.ReadAt(buf[:PageSize], int64(id)*PageSize)
+	return err
+}
+
+// WritePage writes buf, which must be PageSize long, to a page.
+func (p *Pager) WritePage(id PageID, buf []byte) error {
+	if id == 0 || id >= p.NumPages() {
+		return fmt.Errorf("page %d out of range", id)
+	}
+	_, err := p.file.WriteAt(buf[:PageSize], int64(id)*PageSize)
+	return err
+}
+
+// NumPages returns the number of pages in the file, including the header.
+func (p *Pager) NumPages() PageID {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.numPages
+}
+
+// Sync flushes the page file to stable storage.
+func (p *Pager) Sync() error {
+	return p.file.Sync()
+}
+
+// Close closes the page file.
+func (p *Pager) Close() error {
+	return p.file.Close()
+}
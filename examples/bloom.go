@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Person struct {
+		Name string
+		City string
+	}
+
+	var people []Person
+	for i := 0; i < 10000; i++ {
+		people = append(people, Person{fmt.Sprintf("person-%d", i), fmt.Sprintf("city-%d", i%50)})
+	}
+
+	dir := "people_frame"
+	defer os.RemoveAll(dir)
+
+	// Every chunk file gets a Bloom filter over the names and cities it holds
+	df, err := dataframe.CreateDataFrame(dir, people, dataframe.WithBloomFilters("Name", "City"))
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Only chunks whose filters may hold the name are read
+	found, err := df.Lazy().Filter(dataframe.Col("Name").Eq("person-4242")).Collect()
+	if err != nil {
+		log.Fatalf("Error filtering DataFrame: %v", err)
+	}
+	defer found.Close()
+	found.WriteMarkdown(os.Stdout)
+
+	// Inner joins probe the filters with the keys of the right side before reading a chunk
+	type Mayor struct {
+		City  string
+		Mayor string
+	}
+	mayors, err := dataframe.NewDataFrame([]Mayor{{"city-7", "Ada"}, {"atlantis", "Nemo"}})
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer mayors.Close()
+
+	joined, err := df.Lazy().Filter(dataframe.Col("Name").Eq("person-107")).Join(mayors.Lazy(), "inner", "City").Collect()
+	if err != nil {
+		log.Fatalf("Error joining DataFrames: %v", err)
+	}
+	defer joined.Close()
+	joined.WriteMarkdown(os.Stdout)
+}
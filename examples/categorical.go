@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Sale struct {
+		Store  string
+		Region string
+		Amount float64
+	}
+	type Manager struct {
+		Region  string
+		Manager string
+	}
+
+	// Many sales spread over a handful of stores and regions
+	stores := []string{"Downtown", "Airport", "Harbor", "Mall"}
+	regions := []string{"East", "West"}
+	var sales []Sale
+	for i := 0; i < 10000; i++ {
+		sales = append(sales, Sale{stores[i%len(stores)], regions[i%len(regions)], float64(i%50) + 0.99})
+	}
+
+	// Store and Region keep each distinct value once and are written to the
+	// chunk files as small integer codes
+	df, err := dataframe.NewDataFrame(sales, dataframe.WithCategorical("Store", "Region"))
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	categories, err := df.Categories("Store")
+	if err != nil {
+		log.Fatalf("Error listing categories: %v", err)
+	}
+	fmt.Println("Stores:", categories)
+
+	// Filters on values no row holds skip every chunk
+	none, err := df.Lazy().Filter(dataframe.Col("Store").Eq("Station")).Collect()
+	if err != nil {
+		log.Fatalf("Error filtering: %v", err)
+	}
+	defer none.Close()
+	fmt.Println("Sales at Station:")
+	none.WriteMarkdown(os.Stdout)
+
+	// Categorical columns group and join like any string column
+	managers, err := dataframe.NewDataFrame([]Manager{{"East", "Ada"}, {"West", "Grace"}})
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer managers.Close()
+	totals, err := df.Lazy().
+		Filter(dataframe.Col("Store").In("Downtown", "Harbor")).
+		Join(managers.Lazy(), "inner", "Region").
+		GroupBy("Store", "Manager").
+		Agg(dataframe.Count("Amount"), dataframe.Sum("Amount")).
+		Collect()
+	if err != nil {
+		log.Fatalf("Error aggregating: %v", err)
+	}
+	defer totals.Close()
+	totals.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2))
+}
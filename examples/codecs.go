@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Reading struct {
+		Sensor string
+		Value  float64
+	}
+
+	var readings []Reading
+	for i := 0; i < 5000; i++ {
+		readings = append(readings, Reading{fmt.Sprintf("sensor-%d", i%10), float64(i) / 10})
+	}
+
+	dir := "readings_frame"
+	defer os.RemoveAll(dir)
+
+	// Write chunk files with the compact binary codec, compressed with zstd
+	df, err := dataframe.CreateDataFrame(dir, readings, dataframe.WithChunkCodec(dataframe.BinaryCodec{}, dataframe.Zstd))
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	df.Close()
+
+	// The codec is recorded in each chunk header, so no option is needed to read them back
+	df, err = dataframe.OpenDataFrame(dir)
+	if err != nil {
+		log.Fatalf("Error opening DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	row, err := df.ReadRow(4321)
+	if err != nil {
+		log.Fatalf("Error reading row: %v", err)
+	}
+	fmt.Println("Row 4321:", row)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Employee struct {
+		ID         int
+		FirstName  string
+		LastName   string
+		Department string
+		Salary     float64
+	}
+
+	employees := []Employee{
+		{1, "Ada", "Lovelace", "Research", 120000},
+		{2, "Alan", "Turing", "Research", 115000},
+		{3, "Grace", "Hopper", "Engineering", 130000},
+	}
+
+	df, err := dataframe.NewDataFrame(employees)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Keep only the columns needed for a report
+	names, err := df.Select("LastName", "FirstName")
+	if err != nil {
+		log.Fatalf("Error selecting columns: %v", err)
+	}
+	defer names.Close()
+	names.WriteMarkdown(os.Stdout)
+
+	// Drop the internal id and give the remaining columns friendlier names
+	public, err := df.Drop("ID")
+	if err != nil {
+		log.Fatalf("Error dropping columns: %v", err)
+	}
+	defer public.Close()
+
+	renamed, err := public.Rename(map[string]string{"FirstName": "First", "LastName": "Last", "Department": "Team"})
+	if err != nil {
+		log.Fatalf("Error renaming columns: %v", err)
+	}
+	defer renamed.Close()
+
+	// Put the team first
+	report, err := renamed.Reorder("Team")
+	if err != nil {
+		log.Fatalf("Error reordering columns: %v", err)
+	}
+	defer report.Close()
+	report.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(0))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Order struct {
+		Customer string
+		Price    float64
+		Quantity int
+		Placed   time.Time
+	}
+
+	placed := time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC)
+	orders := []Order{
+		{" alice ", 12.5, 4, placed},
+		{"Bob", 3.2, 10, placed.Add(26 * time.Hour)},
+		{"carol", 99.9, 1, placed.Add(50 * time.Hour)},
+		{"dave ", 7.25, 12, placed.Add(75 * time.Hour)},
+	}
+
+	df, err := dataframe.NewDataFrame(orders)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Derive columns with the same expressions used for filtering
+	withTotal, err := df.WithColumn("Total", dataframe.Col("Price").Mul(dataframe.Col("Quantity")))
+	if err != nil {
+		log.Fatalf("Error computing Total: %v", err)
+	}
+	defer withTotal.Close()
+
+	withSize, err := withTotal.WithColumn("Size", dataframe.If(dataframe.Col("Total").Ge(50), "large", "small"))
+	if err != nil {
+		log.Fatalf("Error computing Size: %v", err)
+	}
+	defer withSize.Close()
+
+	withDay, err := withSize.WithColumn("Weekday", dataframe.Col("Placed").Weekday())
+	if err != nil {
+		log.Fatalf("Error computing Weekday: %v", err)
+	}
+	defer withDay.Close()
+
+	// Trim the names with an expression, then capitalize them with MapColumn
+	trimmed, err := withDay.WithColumn("Customer", dataframe.Col("Customer").Trim())
+	if err != nil {
+		log.Fatalf("Error trimming Customer: %v", err)
+	}
+	defer trimmed.Close()
+
+	cleaned, err := trimmed.MapColumn("Customer", func(v interface{}) interface{} {
+		name := v.(string)
+		return strings.ToUpper(name[:1]) + name[1:]
+	})
+	if err != nil {
+		log.Fatalf("Error cleaning Customer: %v", err)
+	}
+	defer cleaned.Close()
+
+	// Compute a column from whole rows with Apply
+	rounded, err := cleaned.Apply("Rounded", func(row dataframe.Row) interface{} {
+		return math.Round(row["Total"].(float64))
+	})
+	if err != nil {
+		log.Fatalf("Error computing Rounded: %v", err)
+	}
+	defer rounded.Close()
+
+	rounded.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2), dataframe.WithTimeLayout("2006-01-02"))
+}
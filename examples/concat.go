@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Monday struct {
+		Product string
+		Units   int
+	}
+	type Tuesday struct {
+		Product string
+		Units   float64
+		Promo   bool
+	}
+	type Prices struct {
+		Price float64
+	}
+
+	monday, err := dataframe.NewDataFrame([]Monday{{"pen", 12}, {"ink", 3}})
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer monday.Close()
+	tuesday, err := dataframe.NewDataFrame([]Tuesday{{"pen", 9.5, true}, {"pad", 4, false}})
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer tuesday.Close()
+
+	// Stack the days. Monday keeps ids 0 and 1 and the ids of Tuesday move to
+	// the next chunk. Units is promoted to float64 and Monday has no Promo
+	// values.
+	week, err := dataframe.Concat([]*dataframe.DataFrame{monday, tuesday})
+	if err != nil {
+		log.Fatalf("Error concatenating: %v", err)
+	}
+	defer week.Close()
+	week.WriteMarkdown(os.Stdout)
+
+	// Keep only the columns both days have
+	common, err := dataframe.Concat([]*dataframe.DataFrame{monday, tuesday}, dataframe.WithSchemaIntersection())
+	if err != nil {
+		log.Fatalf("Error concatenating: %v", err)
+	}
+	defer common.Close()
+	common.WriteMarkdown(os.Stdout)
+
+	// Add a price to the rows of Monday, matching rows by id
+	prices, err := dataframe.NewDataFrame([]Prices{{1.5}, {7}})
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer prices.Close()
+	priced, err := dataframe.HConcat([]*dataframe.DataFrame{monday, prices})
+	if err != nil {
+		log.Fatalf("Error concatenating: %v", err)
+	}
+	defer priced.Close()
+	priced.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2))
+}
@@ -3,51 +3,75 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+
 	"github.com/aggnr/bluejay/dataframe"
 	"github.com/aggnr/bluejay/viz"
 )
 
-// Define the struct that matches the CSV data structure
+// Define the struct that holds the sample data
 type SampleData struct {
-	Age        int     `json:"Age"`
-	Salary     float64 `json:"Salary"`
-	Experience int     `json:"Experience"`
-	Height     float64 `json:"Height"`
-	Weight     float64 `json:"Weight"`
-	Score      float64 `json:"Score"`
+	Age        int
+	Salary     float64
+	Experience int
+	Height     float64
+	Weight     float64
+	Score      float64
 }
 
 func main() {
 
-	// CSV data as a string with mixed correlations
-	csvString := `Age,Salary,Experience,Height,Weight,Score
-25,50000,2,170,70,85
-30,60000,5,175,75,88
-35,70000,8,180,80,90
-40,80000,12,185,85,92
-45,90000,10,190,90,94
-50,100000,20,195,95,96
-55,110000,25,200,100,98
-60,120000,30,205,105,100
-65,130000,26,210,110,102
-70,140000,29,215,115,104
-75,135000,28,220,120,106
-80,130000,27,225,125,108
-85,125000,26,230,130,110
-90,120000,25,235,135,112
-95,115000,24,240,140,114`
+	// Sample data with mixed correlations
+	data := []SampleData{
+		{25, 50000, 2, 170, 70, 85},
+		{30, 60000, 5, 175, 75, 88},
+		{35, 70000, 8, 180, 80, 90},
+		{40, 80000, 12, 185, 85, 92},
+		{45, 90000, 10, 190, 90, 94},
+		{50, 100000, 20, 195, 95, 96},
+		{55, 110000, 25, 200, 100, 98},
+		{60, 120000, 30, 205, 105, 100},
+		{65, 130000, 26, 210, 110, 102},
+		{70, 140000, 29, 215, 115, 104},
+		{75, 135000, 28, 220, 120, 106},
+		{80, 130000, 27, 225, 125, 108},
+		{85, 125000, 26, 230, 130, 110},
+		{90, 120000, 25, 235, 135, 112},
+		{95, 115000, 24, 240, 140, 114},
+	}
 
-	// Read the CSV data into a DataFrame
-	df, err := dataframe.ReadCSVFromString(csvString, &SampleData{})
+	df, err := dataframe.NewDataFrame(data)
 	if err != nil {
-		log.Fatalf("Failed to read CSV data: %v", err)
+		log.Fatalf("Failed to create DataFrame: %v", err)
 	}
+	defer df.Close()
 
-	// Calculate the correlation matrix
-	corrDF, err := df.Corr()
+	// Calculate the Pearson and Spearman correlation matrices
+	corrDF, err := df.Corr("pearson")
 	if err != nil {
 		log.Fatalf("Failed to calculate correlation matrix: %v", err)
 	}
+	defer corrDF.Close()
+
+	rankDF, err := df.Corr("spearman")
+	if err != nil {
+		log.Fatalf("Failed to calculate rank correlation matrix: %v", err)
+	}
+	defer rankDF.Close()
+
+	// Calculate the covariance matrix, requiring at least 10 rows per pair
+	covDF, err := df.Cov(dataframe.WithMinPeriods(10))
+	if err != nil {
+		log.Fatalf("Failed to calculate covariance matrix: %v", err)
+	}
+	defer covDF.Close()
+
+	fmt.Println("Pearson correlation:")
+	corrDF.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(3))
+	fmt.Println("Spearman correlation:")
+	rankDF.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(3))
+	fmt.Println("Covariance:")
+	covDF.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(1))
 
 	// Convert the correlation DataFrame to a 2D slice of float64 and get column names
 	corrMatrix, columns, err := corrDF.ToMatrix()
@@ -57,8 +81,4 @@ func main() {
 
 	// Plot the correlation matrix
 	viz.PlotCorrMat(corrMatrix, columns)
-
-	// Display the correlation DataFrame
-	fmt.Println("Correlation DataFrame:")
-	corrDF.Display()
-}
\ No newline at end of file
+}
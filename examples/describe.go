@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Person struct {
+		Name   string
+		City   string
+		Age    int
+		Salary float64
+	}
+
+	people := []Person{
+		{"Alice", "Paris", 30, 72000},
+		{"Bob", "London", 25, 54000},
+		{"Charlie", "Paris", 35, 91000},
+		{"Diana", "Berlin", 28, 63000},
+		{"Eve", "Paris", 22, 48000},
+		{"Frank", "London", 40, 105000},
+	}
+
+	df, err := dataframe.NewDataFrame(people)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Summarize every column, with the 10th and 90th percentiles besides the median
+	summary, err := df.Describe(0.1, 0.5, 0.9)
+	if err != nil {
+		log.Fatalf("Error describing DataFrame: %v", err)
+	}
+	defer summary.Close()
+
+	summary.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2))
+}
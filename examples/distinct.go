@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Event struct {
+		User   string
+		Action string
+		Day    int
+	}
+
+	// Overlapping exports repeat some events
+	events := []Event{
+		{"ann", "login", 1},
+		{"bob", "login", 1},
+		{"ann", "login", 1},
+		{"ann", "upload", 2},
+		{"bob", "login", 1},
+		{"cid", "login", 3},
+	}
+
+	df, err := dataframe.NewDataFrame(events)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Rows repeating an earlier row
+	duplicated, err := df.Duplicated(nil, "first")
+	if err != nil {
+		log.Fatalf("Error finding duplicates: %v", err)
+	}
+	fmt.Println("Duplicated:", duplicated)
+
+	// Keep the last event of every user
+	latest, err := df.DropDuplicates([]string{"User"}, "last")
+	if err != nil {
+		log.Fatalf("Error dropping duplicates: %v", err)
+	}
+	defer latest.Close()
+	latest.WriteMarkdown(os.Stdout)
+
+	users, err := df.Unique("User")
+	if err != nil {
+		log.Fatalf("Error listing users: %v", err)
+	}
+	actions, err := df.NUnique("Action")
+	if err != nil {
+		log.Fatalf("Error counting actions: %v", err)
+	}
+	fmt.Println("Users:", users, "distinct actions:", actions)
+
+	counts, err := df.ValueCounts("Action", true)
+	if err != nil {
+		log.Fatalf("Error counting values: %v", err)
+	}
+	defer counts.Close()
+	counts.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2))
+}
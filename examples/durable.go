@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Person struct {
+		Name string
+		Age  int
+		City string
+	}
+
+	people := []Person{
+		{"John", 30, "New York"},
+		{"Jane", 25, "Boston"},
+	}
+
+	dir := "people_frame"
+	defer os.RemoveAll(dir)
+
+	// Create a DataFrame whose files are kept in dir
+	df, err := dataframe.CreateDataFrame(dir, people)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+
+	// Mutations are written to the write-ahead log before they are applied
+	df.InsertRow(2, Person{"Alice", 28, "Seattle"})
+	if err := df.UpdateRow(1, map[string]interface{}{"Age": 26}); err != nil {
+		log.Fatalf("Error updating row: %v", err)
+	}
+	if err := df.DeleteRow(0); err != nil {
+		log.Fatalf("Error deleting row: %v", err)
+	}
+	df.Close()
+
+	// Reopen the DataFrame; after a crash, logged mutations would be replayed here
+	df, err = dataframe.OpenDataFrame(dir)
+	if err != nil {
+		log.Fatalf("Error opening DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	for id := 0; id < 3; id++ {
+		row, err := df.ReadRow(id)
+		if err != nil {
+			fmt.Println("Error reading row:", err)
+			continue
+		}
+		fmt.Printf("Row %d: %v\n", id, row)
+	}
+}
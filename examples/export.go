@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Person struct {
+		Name      string
+		Age       int
+		Salary    float64
+		IsMarried bool
+	}
+
+	people := []Person{
+		{"John", 30, 50000.50, true},
+		{"Jane", 25, 60000.75, false},
+	}
+
+	df, err := dataframe.NewDataFrame(people)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Write the DataFrame to stdout in each supported format
+	if err := df.WriteCSV(os.Stdout, dataframe.WithFloatPrecision(2)); err != nil {
+		log.Fatalf("Error writing CSV: %v", err)
+	}
+	if err := df.WriteJSON(os.Stdout, dataframe.WithIndex("id")); err != nil {
+		log.Fatalf("Error writing JSON: %v", err)
+	}
+	if err := df.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2)); err != nil {
+		log.Fatalf("Error writing Markdown: %v", err)
+	}
+	if err := df.WriteHTML(os.Stdout, dataframe.WithNullValue("NA")); err != nil {
+		log.Fatalf("Error writing HTML: %v", err)
+	}
+}
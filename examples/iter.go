@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+type Reading struct {
+	Sensor string
+	Value  float64
+}
+
+func main() {
+	var readings []Reading
+	for i := 0; i < 2500; i++ {
+		readings = append(readings, Reading{fmt.Sprintf("s%d", i%3), float64(i) / 10})
+	}
+
+	df, err := dataframe.NewDataFrame(readings)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Range over rows in id order, stopping early
+	for id, row := range df.All() {
+		if id == 3 {
+			break
+		}
+		fmt.Println(id, row["Sensor"], row["Value"])
+	}
+
+	// Range over the values of a single column
+	total := 0.0
+	for v := range df.Column("Value") {
+		total += v.(float64)
+	}
+	fmt.Printf("Total: %.1f\n", total)
+
+	// Range over whole chunks, one in memory at a time
+	for chunk := range df.Chunks() {
+		fmt.Printf("Chunk %d holds %d rows\n", chunk.ID, len(chunk.Rows))
+	}
+
+	// Handle a chunk that cannot be read rather than having it printed
+	for chunk, err := range df.ChunksErr() {
+		if err != nil {
+			log.Fatalf("Error reading chunk %d: %v", chunk.ID, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Person struct {
+		Name string
+		Age  int
+		City string
+	}
+
+	type Job struct {
+		Name   string
+		Title  string
+		Salary float64
+	}
+
+	people := []Person{
+		{"John", 30, "New York"},
+		{"Jane", 25, "Chicago"},
+		{"Doe", 40, "New York"},
+	}
+
+	jobs := []Job{
+		{"John", "Engineer", 70000},
+		{"Doe", "Doctor", 80000},
+	}
+
+	dfPeople, err := dataframe.NewDataFrame(people)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame for people: %v", err)
+	}
+	defer dfPeople.Close()
+
+	dfJobs, err := dataframe.NewDataFrame(jobs)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame for jobs: %v", err)
+	}
+	defer dfJobs.Close()
+
+	// Build the query lazily, nothing is read until Collect is called
+	query := dfPeople.Lazy().
+		Join(dfJobs.Lazy(), "inner", "Name").
+		Filter(dataframe.Col("Age").Gt(28).And(dataframe.Col("Salary").Ge(75000))).
+		Select("Name", "City", "Title")
+
+	// The filters are pushed below the join and unused columns are pruned
+	fmt.Print(query.Explain())
+
+	result, err := query.Collect()
+	if err != nil {
+		log.Fatalf("Error collecting query: %v", err)
+	}
+	defer result.Close()
+
+	if err := result.WriteMarkdown(os.Stdout); err != nil {
+		log.Fatalf("Error writing result: %v", err)
+	}
+}
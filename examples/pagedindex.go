@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+type Event struct {
+	User  string
+	Value int
+}
+
+func main() {
+	var events []Event
+	for i := 0; i < 200000; i++ {
+		events = append(events, Event{fmt.Sprintf("user-%d", i%500), i})
+	}
+
+	dir := "events_frame"
+	defer os.RemoveAll(dir)
+
+	// Keep the row index in page files, with at most 16 pages of each shard in memory
+	df, err := dataframe.CreateDataFrame(dir, events, dataframe.WithPagedIndex(16))
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	df.InsertRow(200000, Event{"user-new", -1})
+	if err := df.DeleteRow(42); err != nil {
+		log.Fatalf("Error deleting row: %v", err)
+	}
+	df.Close()
+
+	// The option is remembered, and the page files are rebuilt on open
+	df, err = dataframe.OpenDataFrame(dir)
+	if err != nil {
+		log.Fatalf("Error opening DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	for _, id := range []int{41, 42, 200000} {
+		row, err := df.ReadRow(id)
+		if err != nil {
+			fmt.Println("Error reading row:", err)
+			continue
+		}
+		fmt.Printf("Row %d: %v\n", id, row)
+	}
+}
@@ -404,6 +404,36 @@ func main() {
 
 	df.Close()
 
+	// Measure the same reads once the rows are in chunk files, which are memory-mapped
+	// and decoded a row at a time
+	dir, err := os.MkdirTemp("", "perf_frame")
+	if err != nil {
+		fmt.Println("Error creating directory:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	diskDF, err := dataframe.CreateDataFrame(dir, data)
+	if err != nil {
+		fmt.Println("Error creating DataFrame:", err)
+		return
+	}
+	defer diskDF.Close()
+	for i := 0; i < 100; i++ {
+		randomID := rand.Intn(scale)
+		start := time.Now()
+		_, err = diskDF.ReadRow(randomID)
+		if err != nil {
+			fmt.Println("Error reading row:", err)
+			return
+		}
+		searchTimes[i] = time.Since(start)
+	}
+	totalSearchTime = 0
+	for _, t := range searchTimes {
+		totalSearchTime += t
+	}
+	fmt.Printf("Average time to read a random row from disk: %.6f ms\n", float64((totalSearchTime / time.Duration(len(searchTimes))).Microseconds())/1000)
+
 	// Profile memory usage after the operations
 	//profileMemory("memprofile_after.prof")
 }
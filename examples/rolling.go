@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Reading struct {
+		Time  time.Time
+		Value float64
+	}
+
+	// Sensor readings taken at irregular intervals
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	offsets := []int{0, 1, 2, 4, 5, 9, 10, 11, 15, 16}
+	values := []float64{0.1, 0.5, 0.9, 0.4, 0.7, 1.0, 0.8, 0.6, 0.3, 0.2}
+	var readings []Reading
+	for i, minutes := range offsets {
+		readings = append(readings, Reading{start.Add(time.Duration(minutes) * time.Minute), values[i]})
+	}
+
+	df, err := dataframe.NewDataFrame(readings)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Moving average over the last 3 readings, once at least 2 are available
+	avg, err := df.Rolling(3, 2).Mean("Value")
+	if err != nil {
+		log.Fatalf("Error computing rolling mean: %v", err)
+	}
+	defer avg.Close()
+	fmt.Println("Rolling mean over 3 readings:")
+	avg.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(3))
+
+	// Largest value within the 5 minutes up to each reading
+	peak, err := df.Rolling("5m", 1, dataframe.WithOrderBy("Time")).Max("Value")
+	if err != nil {
+		log.Fatalf("Error computing rolling max: %v", err)
+	}
+	defer peak.Close()
+	fmt.Println("Rolling max over 5 minutes:")
+	peak.WriteMarkdown(os.Stdout, dataframe.WithTimeLayout("15:04"))
+
+	// Exponentially weighted mean with a span of 4 readings
+	ewm, err := df.EWM(dataframe.WithSpan(4)).Mean("Value")
+	if err != nil {
+		log.Fatalf("Error computing exponentially weighted mean: %v", err)
+	}
+	defer ewm.Close()
+	fmt.Println("Exponentially weighted mean:")
+	ewm.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(3))
+}
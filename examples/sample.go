@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Patient struct {
+		Age       int
+		Treatment string
+		Recovered bool
+	}
+
+	// One patient in five received the new treatment
+	var patients []Patient
+	for i := 0; i < 1000; i++ {
+		treatment := "standard"
+		if i%5 == 0 {
+			treatment = "new"
+		}
+		patients = append(patients, Patient{20 + i%60, treatment, i%3 != 0})
+	}
+
+	df, err := dataframe.NewDataFrame(patients)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// The same seed always draws the same rows
+	sample, err := df.Sample(5, dataframe.WithSeed(42))
+	if err != nil {
+		log.Fatalf("Error sampling: %v", err)
+	}
+	defer sample.Close()
+	sample.WriteMarkdown(os.Stdout)
+
+	// Keep the share of each treatment in a 10% sample
+	stratified, err := df.SampleFrac(0.1, dataframe.WithStratify("Treatment"), dataframe.WithSeed(42))
+	if err != nil {
+		log.Fatalf("Error sampling: %v", err)
+	}
+	defer stratified.Close()
+	counts, err := stratified.ValueCounts("Treatment", false)
+	if err != nil {
+		log.Fatalf("Error counting values: %v", err)
+	}
+	defer counts.Close()
+	counts.WriteMarkdown(os.Stdout)
+
+	// Hold out 20% of the patients for testing
+	train, test, err := df.TrainTestSplit(0.2, dataframe.WithStratify("Treatment"), dataframe.WithSeed(7))
+	if err != nil {
+		log.Fatalf("Error splitting: %v", err)
+	}
+	defer train.Close()
+	defer test.Close()
+	testCounts, err := test.ValueCounts("Treatment", false)
+	if err != nil {
+		log.Fatalf("Error counting values: %v", err)
+	}
+	defer testCounts.Close()
+	fmt.Println("Test patients by treatment:")
+	testCounts.WriteMarkdown(os.Stdout)
+}
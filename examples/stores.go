@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Order struct {
+		Customer string
+		Amount   float64
+	}
+
+	orders := []Order{
+		{"John", 120.50},
+		{"Jane", 75.25},
+	}
+
+	// Keep the chunks in a MinIO bucket when one is configured, in memory otherwise, e.g.
+	// MINIO_ENDPOINT=http://localhost:9000 MINIO_ACCESS_KEY=minioadmin MINIO_SECRET_KEY=minioadmin
+	var store dataframe.ChunkStore = dataframe.NewMemoryStore()
+	if endpoint := os.Getenv("MINIO_ENDPOINT"); endpoint != "" {
+		s3, err := dataframe.NewS3Store(dataframe.S3Config{
+			Endpoint:  endpoint,
+			Bucket:    "bluejay",
+			Prefix:    "frames/orders/",
+			AccessKey: os.Getenv("MINIO_ACCESS_KEY"),
+			SecretKey: os.Getenv("MINIO_SECRET_KEY"),
+		})
+		if err != nil {
+			log.Fatalf("Error configuring S3 store: %v", err)
+		}
+		store = s3
+	}
+
+	// The write-ahead log stays in the local directory
+	dir := "orders_frame"
+	defer os.RemoveAll(dir)
+
+	df, err := dataframe.CreateDataFrame(dir, orders, dataframe.WithChunkStore(store))
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	df.Close()
+
+	names, err := store.List()
+	if err != nil {
+		log.Fatalf("Error listing store: %v", err)
+	}
+	fmt.Println("Stored blobs:", names)
+
+	df, err = dataframe.OpenDataFrame(dir, dataframe.WithChunkStore(store))
+	if err != nil {
+		log.Fatalf("Error opening DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	row, err := df.ReadRow(1)
+	if err != nil {
+		log.Fatalf("Error reading row: %v", err)
+	}
+	fmt.Println("Row 1:", row)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+type Address struct {
+	City string `bluejay:"city,categorical"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type Audit struct {
+	CreatedBy string
+}
+
+type Customer struct {
+	Audit            // Embedded fields are promoted
+	ID       int     `bluejay:"id"`
+	Email    string  `bluejay:"email,index"`
+	Address  Address `bluejay:"address"` // Flattened into address.city and address.zip
+	Password string  `bluejay:"-"`
+	notes    string  // Unexported fields are skipped
+}
+
+func main() {
+	customers := []Customer{
+		{Audit{"admin"}, 1, "ann@example.com", Address{"Paris", "75001"}, "secret", ""},
+		{Audit{"admin"}, 2, "ben@example.com", Address{"Lyon", ""}, "secret", ""},
+		{Audit{"import"}, 3, "cat@example.com", Address{"Paris", "75002"}, "secret", ""},
+	}
+
+	df, err := dataframe.NewDataFrame(customers)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	df.WriteMarkdown(os.Stdout)
+
+	// Flattened columns filter like any other
+	paris, err := df.Lazy().Filter(dataframe.Col("address.city").Eq("Paris")).Collect()
+	if err != nil {
+		log.Fatalf("Error filtering: %v", err)
+	}
+	defer paris.Close()
+	fmt.Println("Customers in Paris:")
+	paris.WriteMarkdown(os.Stdout)
+
+	// Rows read back through the same mapping
+	ben, err := dataframe.Get[Customer](df, 1)
+	if err != nil {
+		log.Fatalf("Error reading row: %v", err)
+	}
+	fmt.Printf("%s lives in %s\n", ben.Email, ben.Address.City)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Reading struct {
+		Time   time.Time
+		Sensor string
+		Value  float64
+	}
+	type Calibration struct {
+		Time   time.Time
+		Sensor string
+		Offset float64
+	}
+
+	// A reading from each of two sensors every 20 minutes
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	var readings []Reading
+	for i := 0; i < 12; i++ {
+		at := start.Add(time.Duration(i/2*20) * time.Minute)
+		readings = append(readings, Reading{at, []string{"north", "south"}[i%2], float64(10 + i)})
+	}
+	calibrations := []Calibration{
+		{start, "north", 0.5},
+		{start, "south", -0.2},
+		{start.Add(50 * time.Minute), "north", 0.7},
+	}
+
+	df, err := dataframe.NewDataFrame(readings)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+	if err := df.SetTimeIndex("Time"); err != nil {
+		log.Fatalf("Error setting time index: %v", err)
+	}
+
+	// Hourly count and mean of the readings
+	hourly, err := df.Resample("1h").Agg(dataframe.Count("Value"), dataframe.Mean("Value"))
+	if err != nil {
+		log.Fatalf("Error resampling: %v", err)
+	}
+	defer hourly.Close()
+	fmt.Println("Hourly readings:")
+	hourly.WriteMarkdown(os.Stdout, dataframe.WithFloatPrecision(2))
+
+	// Readings between 08:30 and 09:30
+	slice, err := df.Between(start.Add(30*time.Minute), start.Add(90*time.Minute))
+	if err != nil {
+		log.Fatalf("Error slicing: %v", err)
+	}
+	defer slice.Close()
+
+	// Attach the calibration in effect at each reading of the same sensor
+	cal, err := dataframe.NewDataFrame(calibrations)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer cal.Close()
+	if err := cal.SetTimeIndex("Time"); err != nil {
+		log.Fatalf("Error setting time index: %v", err)
+	}
+	joined, err := slice.AsOfJoin(cal, 0, "Sensor")
+	if err != nil {
+		log.Fatalf("Error joining: %v", err)
+	}
+	defer joined.Close()
+
+	// Show the times in New York
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		log.Fatalf("Error loading location: %v", err)
+	}
+	local, err := joined.ConvertTimezone(ny)
+	if err != nil {
+		log.Fatalf("Error converting timezone: %v", err)
+	}
+	defer local.Close()
+	fmt.Println("Calibrated readings from 08:30 to 09:30 UTC:")
+	local.WriteMarkdown(os.Stdout, dataframe.WithTimeLayout("2006-01-02 15:04 MST"))
+}
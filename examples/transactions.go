@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Account struct {
+		Owner   string
+		Balance float64
+	}
+
+	accounts := []Account{
+		{"John", 100},
+		{"Jane", 50},
+	}
+
+	df, err := dataframe.NewDataFrame(accounts)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Take a point-in-time view before the transfer
+	snapshot := df.Snapshot()
+	defer snapshot.Release()
+
+	// Move 30 from John to Jane in one atomic batch
+	tx := df.Begin()
+	if err := tx.UpdateRow(0, map[string]interface{}{"Balance": 70.0}); err != nil {
+		log.Fatalf("Error updating row: %v", err)
+	}
+	if err := tx.UpdateRow(1, map[string]interface{}{"Balance": 80.0}); err != nil {
+		log.Fatalf("Error updating row: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error committing transaction: %v", err)
+	}
+
+	// The snapshot still sees the balances from before the transfer
+	fmt.Println("Snapshot:")
+	snapshot.Scan(func(id int, row dataframe.Row) error {
+		fmt.Printf("  %d: %v\n", id, row)
+		return nil
+	})
+
+	fmt.Println("Current:")
+	for id := 0; id < 2; id++ {
+		row, _ := df.ReadRow(id)
+		fmt.Printf("  %d: %v\n", id, row)
+	}
+}
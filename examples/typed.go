@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+type Person struct {
+	Name   string
+	Age    int
+	Salary float64
+}
+
+func main() {
+	people := []Person{
+		{"Alice", 30, 85000},
+		{"Bob", 25, 62000},
+		{"Carol", 41, 99000},
+	}
+
+	df, err := dataframe.NewDataFrame(people)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Read a row back into the struct it came from
+	bob, err := dataframe.Get[Person](df, 1)
+	if err != nil {
+		log.Fatalf("Error reading row: %v", err)
+	}
+	fmt.Printf("%s is %d\n", bob.Name, bob.Age)
+
+	// Read every row
+	all, err := dataframe.ToStructs[Person](df)
+	if err != nil {
+		log.Fatalf("Error reading rows: %v", err)
+	}
+	fmt.Println(all)
+
+	// Read whole columns without type assertions
+	names, err := df.Strings("Name")
+	if err != nil {
+		log.Fatalf("Error reading Name: %v", err)
+	}
+	salaries, err := df.Float64s("Salary")
+	if err != nil {
+		log.Fatalf("Error reading Salary: %v", err)
+	}
+	for i, name := range names {
+		fmt.Printf("%s earns %.0f\n", name, salaries[i])
+	}
+}
@@ -34,17 +34,13 @@ func main() {
 	}
 
 	// Extract data for plotting
-	xData := []float64{}
-	yData := []float64{}
-	for _, row := range df.Data {
-		rowMap := row.(map[string]interface{})
-		xVal, ok1 := dataframe.ToFloat64(rowMap["Age"])
-		yVal, ok2 := dataframe.ToFloat64(rowMap["Salary"])
-		if !ok1 || !ok2 {
-			log.Fatalf("Non-numeric value encountered")
-		}
-		xData = append(xData, xVal)
-		yData = append(yData, yVal)
+	xData, err := df.Float64s("Age")
+	if err != nil {
+		log.Fatalf("Error reading Age: %v", err)
+	}
+	yData, err := df.Float64s("Salary")
+	if err != nil {
+		log.Fatalf("Error reading Salary: %v", err)
 	}
 
 	// Sort xData and rearrange yData accordingly
@@ -47,17 +47,13 @@ func main() {
 	}()
 
 	// Extract data for plotting
-	xData := []float64{}
-	yData := []float64{}
-	for _, row := range df.Data {
-		rowMap := row.(map[string]interface{})
-		xVal, ok1 := dataframe.ToFloat64(rowMap["Time"])
-		yVal, ok2 := dataframe.ToFloat64(rowMap["Value"])
-		if (!ok1 || !ok2) {
-			log.Fatalf("Non-numeric value encountered")
-		}
-		xData = append(xData, xVal)
-		yData = append(yData, yVal)
+	xData, err := df.Float64s("Time")
+	if err != nil {
+		log.Fatalf("Error reading Time: %v", err)
+	}
+	yData, err := df.Float64s("Value")
+	if err != nil {
+		log.Fatalf("Error reading Value: %v", err)
 	}
 
 	// Sort xData and rearrange yData accordingly
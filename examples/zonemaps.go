@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aggnr/bluejay/dataframe"
+)
+
+func main() {
+	type Person struct {
+		Name string
+		Age  int
+	}
+
+	// Rows are mostly ordered by age, so each chunk covers a narrow range of ages
+	var people []Person
+	for i := 0; i < 10000; i++ {
+		people = append(people, Person{fmt.Sprintf("person-%d", i), i / 100})
+	}
+
+	dir := "people_frame"
+	defer os.RemoveAll(dir)
+
+	df, err := dataframe.CreateDataFrame(dir, people)
+	if err != nil {
+		log.Fatalf("Error creating DataFrame: %v", err)
+	}
+	defer df.Close()
+
+	// Every chunk file records the min, max, null count and distinct values of its columns
+	for _, stats := range df.ChunkStats()[:3] {
+		fmt.Printf("Chunk %d: Age %v\n", stats.ID, stats.Columns["Age"])
+	}
+
+	// Chunks whose ages are all 90 or less are skipped without being read
+	old, err := df.Lazy().Filter(dataframe.Col("Age").Gt(90)).Collect()
+	if err != nil {
+		log.Fatalf("Error filtering DataFrame: %v", err)
+	}
+	defer old.Close()
+	snapshot := old.Snapshot()
+	defer snapshot.Release()
+	fmt.Println("People over 90:", len(snapshot.IDs()))
+}
@@ -3,8 +3,11 @@ module github.com/aggnr/bluejay
 go 1.23.0
 
 require (
+	github.com/golang/snappy v0.0.3
 	github.com/hajimehoshi/ebiten/v2 v2.7.10
+	github.com/klauspost/compress v1.13.1
 	github.com/mattn/go-sqlite3 v1.14.23
+	github.com/pierrec/lz4/v4 v4.1.8
 	golang.org/x/image v0.18.0
 )
 
@@ -30,17 +33,14 @@ require (
 	github.com/go-text/typesetting v0.1.1-0.20240325125605-c7936fe59984 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	github.com/golang/snappy v0.0.3 // indirect
 	github.com/google/flatbuffers v2.0.0+incompatible // indirect
 	github.com/gopherjs/gopherjs v1.17.2 // indirect
 	github.com/jeandeaual/go-locale v0.0.0-20240223122105-ce5225dcaa49 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	github.com/jsummers/gobmp v0.0.0-20151104160322-e2ba15ffa76e // indirect
-	github.com/klauspost/compress v1.13.1 // indirect
 	github.com/mattn/go-gtk v0.0.0-20240119050609-48574e312fac // indirect
 	github.com/mattn/go-pointer v0.0.1 // indirect
 	github.com/nicksnyder/go-i18n/v2 v2.4.0 // indirect
-	github.com/pierrec/lz4/v4 v4.1.8 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rymdport/portal v0.2.6 // indirect
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c // indirect